@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+	"wsl-notify-send/internal/ask"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/notify"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	askYes     string
+	askNo      string
+	askTimeout time.Duration
+	askDefault string
+)
+
+// openTerminal opens the terminal the question is asked on when no backend
+// can show it, even when stdin and stdout are redirected
+var openTerminal = func() (io.ReadWriteCloser, error) {
+	if runtime.GOOS == "windows" {
+		return nil, errors.New("no terminal device on windows")
+	}
+	return os.OpenFile("/dev/tty", os.O_RDWR, 0)
+}
+
+var askCmd = &cobra.Command{
+	Use:   "ask [flags] <question> [message]",
+	Short: "Ask a yes/no question with a notification",
+	Long: `Ask a yes/no question with notification buttons and wait for the answer. The
+answer is printed on stdout and wsl-notify-send exits with 0 for yes and 1 for
+no, so scripts can gate steps on a desktop confirmation.
+
+A question that times out exits with 6 and one that is dismissed with 7,
+unless --default gives the answer for both. When the backend can't report
+which button was clicked, the question is asked on the terminal instead.
+
+The notification uses the icon, app name and other options from the config
+files, profile and environment.
+
+Examples:
+  wsl-notify-send ask "Deploy to prod?" && ./deploy.sh
+  wsl-notify-send ask "Deploy to prod?" --yes Deploy --no Cancel --timeout 60s --default no`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return exitcode.Mark(config.ErrInvalidArgs, errors.New("requires a question"))
+		}
+		if len(args) > 2 {
+			return exitcode.Mark(config.ErrInvalidArgs, errors.New("too many arguments, expected: <question> [message]"))
+		}
+		return nil
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		answer, err := ask.ParseAnswer(askDefault)
+		if err != nil {
+			return exitcode.Mark(config.ErrInvalidArgs, fmt.Errorf("invalid --default value: %w", err))
+		}
+		if askYes == "" || askNo == "" {
+			return exitcode.Mark(config.ErrInvalidArgs, errors.New("--yes and --no labels cannot be empty"))
+		}
+		if askTimeout < 0 {
+			return exitcode.Mark(config.ErrInvalidArgs, errors.New("--timeout must be 0 or greater"))
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", optionSources.Annotate(err))
+		}
+		opts, err := cfg.NotifyOptions()
+		if err != nil {
+			return exitcode.Mark(config.ErrInvalidConfig, fmt.Errorf("invalid configuration: %w", err))
+		}
+
+		q := ask.Question{Title: args[0], YesLabel: askYes, NoLabel: askNo, Default: answer}
+		if len(args) > 1 {
+			q.Message = args[1]
+		}
+
+		ctx := cmd.Context()
+		if askTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, askTimeout)
+			defer cancel()
+		}
+
+		if notify.CanWait() {
+			opts.Actions = q.Actions()
+			if askTimeout > 0 {
+				opts.ExpireTimeout = int(askTimeout.Milliseconds())
+			}
+			var action string
+			_, action, err = notify.NotifyAndWait(ctx, q.Title, q.Message, cfg.Icon, cfg.AppName, opts, cfg.AlertMode)
+			answer, err = q.Resolve(action, err)
+		} else {
+			answer, err = promptTerminal(ctx, cmd, q)
+		}
+
+		// Like a no, a question left unanswered is reported by the exit code
+		if errors.Is(err, notify.ErrDismissed) || errors.Is(err, notify.ErrTimeout) {
+			return exitcode.Status(exitcode.Of(err))
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), answer)
+		if answer == ask.No {
+			return exitcode.Status(exitcode.General)
+		}
+		return nil
+	},
+}
+
+// promptTerminal asks the question on the terminal, or on stdin and stderr
+// when there is none
+func promptTerminal(ctx context.Context, cmd *cobra.Command, q ask.Question) (string, error) {
+	tty, err := openTerminal()
+	if err != nil {
+		return ask.Prompt(ctx, cmd.InOrStdin(), cmd.ErrOrStderr(), q)
+	}
+	defer tty.Close()
+
+	return ask.Prompt(ctx, tty, tty, q)
+}
+
+func init() {
+	askCmd.Flags().StringVar(&askYes, "yes", "Yes", "Label of the yes button")
+	askCmd.Flags().StringVar(&askNo, "no", "No", "Label of the no button")
+	askCmd.Flags().DurationVar(&askTimeout, "timeout", 0, "How long to wait for an answer (0 to wait until answered)")
+	askCmd.Flags().StringVar(&askDefault, "default", "", "Answer when the question times out or is dismissed: yes or no")
+
+	rootCmd.AddCommand(askCmd)
+}
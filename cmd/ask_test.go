@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/notify"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noTerminal makes the terminal fallback use stdin and stderr
+func noTerminal(t *testing.T) {
+	original := openTerminal
+	openTerminal = func() (io.ReadWriteCloser, error) { return nil, errors.New("no terminal") }
+	t.Cleanup(func() { openTerminal = original })
+}
+
+func TestAskCommand_Notification(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		flags    []string
+		output   string
+		code     int
+	}{
+		{"yes", "action:yes", nil, "yes\n", exitcode.Success},
+		{"no", "action:no", nil, "no\n", exitcode.General},
+		{"clicked", "action:default", nil, "", exitcode.Dismissed},
+		{"dismissed", "dismiss", nil, "", exitcode.Dismissed},
+		{"timeout", "timeout", nil, "", exitcode.Timeout},
+		{"timeout with default", "timeout", []string{"--default", "no"}, "no\n", exitcode.General},
+		{"dismissed with default", "dismiss", []string{"--default", "yes"}, "yes\n", exitcode.Success},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupMockBeeper(t)
+			t.Setenv(notify.FakeResponseEnv, tt.response)
+
+			args := append([]string{"ask", "--backend", "fake", "--yes", "Deploy", "--no", "Cancel"}, tt.flags...)
+			output, err := executeCommand(append(args, "Deploy to prod?", "api v2"))
+
+			assert.Equal(t, tt.code, exitcode.Of(err))
+			assert.Equal(t, tt.output, output)
+
+			fake, ok := notify.GetBeeper().(*notify.FakeBeeper)
+			require.True(t, ok)
+			sent := fake.Sent()
+			require.Len(t, sent, 1)
+			assert.Equal(t, "Deploy to prod?", sent[0].Title)
+			assert.Equal(t, "api v2", sent[0].Message)
+			assert.Equal(t, []string{"yes", "no"}, sent[0].Actions)
+		})
+	}
+}
+
+func TestAskCommand_TerminalFallback(t *testing.T) {
+	setupMockBeeper(t)
+	noTerminal(t)
+
+	output, err := executeCommandWithStdin([]string{"ask", "--default", "no", "Deploy to prod?"}, "maybe\ny\n")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Deploy to prod? [Yes/No, default No] Please answer Yes or No.\n"+
+		"Deploy to prod? [Yes/No, default No] yes\n", output)
+}
+
+func TestAskCommand_TerminalFallbackNoAnswer(t *testing.T) {
+	setupMockBeeper(t)
+	noTerminal(t)
+
+	_, err := executeCommandWithStdin([]string{"ask", "Deploy to prod?"}, "")
+
+	assert.Equal(t, exitcode.Dismissed, exitcode.Of(err))
+}
+
+func TestAskCommand_InvalidArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		errMsg string
+	}{
+		{"no question", []string{"ask"}, "requires a question"},
+		{"too many arguments", []string{"ask", "a", "b", "c"}, "too many arguments"},
+		{"invalid default", []string{"ask", "--default", "maybe", "Deploy?"}, "invalid --default value"},
+		{"empty label", []string{"ask", "--yes", "", "Deploy?"}, "labels cannot be empty"},
+		{"negative timeout", []string{"ask", "--timeout", "-1s", "Deploy?"}, "--timeout must be 0 or greater"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupMockBeeper(t)
+
+			_, err := executeCommand(tt.args)
+
+			assert.ErrorContains(t, err, tt.errMsg)
+			assert.Equal(t, exitcode.InvalidArgs, exitcode.Of(err))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"wsl-notify-send/internal/notify"
+
+	"github.com/spf13/cobra"
+)
+
+var backendsCmd = &cobra.Command{
+	Use:   "backends",
+	Short: "List the notification backends and whether they can be used",
+	Long: `List the registered notification backends, whether each one can be used on
+this machine and why. Pick one with --backend NAME.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSTATUS\tDESCRIPTION\tDETAILS")
+
+		for _, b := range notify.Backends() {
+			status := "available"
+			ok, reason := b.Available()
+			if !ok {
+				status = "unavailable"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", b.Name, status, b.Description, reason)
+		}
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backendsCmd)
+}
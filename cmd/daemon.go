@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"wsl-notify-send/internal/daemon"
+	"wsl-notify-send/internal/notify"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonDBus   bool
+	daemonSocket bool
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run as a long-lived notification server",
+	Long: `Run wsl-notify-send as a long-lived notification server.
+
+With --dbus it owns org.freedesktop.Notifications on the session bus, so every
+Linux application that uses libnotify has its notifications forwarded to
+Windows without being wrapped.
+
+With --socket it listens on $XDG_RUNTIME_DIR/wsl-notify-send.sock, and every
+wsl-notify-send command hands its notifications over instead of starting the
+backend itself. This makes frequent notifications, such as those of hooks,
+much cheaper. Commands that select another backend, or wait for the
+notification to be closed, still deliver it themselves.
+
+Examples:
+  wsl-notify-send daemon --dbus &
+  notify-send "Hello" "From libnotify"
+  wsl-notify-send daemon --socket &`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !daemonDBus && !daemonSocket {
+			return errors.New("no daemon mode selected, use --dbus or --socket")
+		}
+
+		beeper := notify.GetBeeper()
+
+		if daemonDBus {
+			// Forwarding to D-Bus would send every notification back to us
+			if notify.UsesDBus(beeper) {
+				return errors.New("cannot serve D-Bus notifications through the D-Bus backend, pick another with --backend")
+			}
+
+			conn, err := dbus.ConnectSessionBus()
+			if err != nil {
+				return fmt.Errorf("cannot connect to session bus: %w", err)
+			}
+			defer conn.Close()
+
+			server := daemon.NewDBusServer(conn, beeper, Version)
+			server.ErrorLog = log.New(cmd.ErrOrStderr(), "", log.LstdFlags)
+			if err := server.Start(); err != nil {
+				return fmt.Errorf("cannot start D-Bus server: %w", err)
+			}
+			defer server.Stop()
+		}
+
+		errc := make(chan error, 1)
+		if daemonSocket {
+			path := daemon.SocketPath()
+			listener, err := daemon.ListenSocket(path)
+			if err != nil {
+				return fmt.Errorf("cannot listen on %s: %w", path, err)
+			}
+			defer listener.Close()
+
+			go func() {
+				errc <- daemon.NewSocketServer(beeper, cfg.Backend).Serve(listener)
+			}()
+			cmd.PrintErrf("Listening on %s\n", path)
+		}
+
+		// Serve until interrupted
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sig)
+
+		select {
+		case err := <-errc:
+			return err
+		case <-sig:
+			return nil
+		}
+	},
+}
+
+func init() {
+	daemonCmd.Flags().BoolVar(&daemonDBus, "dbus", false, "Serve org.freedesktop.Notifications on the session bus")
+	daemonCmd.Flags().BoolVar(&daemonSocket, "socket", false, "Take over the notifications of other wsl-notify-send commands on a Unix socket")
+
+	rootCmd.AddCommand(daemonCmd)
+}
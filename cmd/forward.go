@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/daemon"
+	"wsl-notify-send/internal/notify"
+)
+
+// deliver sends a notification through the running daemon, or directly when
+// there is none. c must already be validated and title and message rendered.
+func deliver(c config.Config, title, message string, opts notify.Options) (uint32, error) {
+	id, err := forward(c, title, message)
+	if !errors.Is(err, daemon.ErrNoDaemon) {
+		return id, err
+	}
+
+	if c.AlertMode {
+		return notify.AlertWithOptions(title, message, c.Icon, c.AppName, opts)
+	}
+	return notify.NotifyWithOptions(title, message, c.Icon, c.AppName, opts)
+}
+
+// beep beeps through the running daemon, or directly when there is none
+func beep(c config.Config) error {
+	_, err := forward(c, "", "")
+	if !errors.Is(err, daemon.ErrNoDaemon) {
+		return err
+	}
+	return notify.Beep(c.Frequency, c.Duration)
+}
+
+// forward hands the notification over to the daemon, which resolves icon
+// paths from its own working directory
+func forward(c config.Config, title, message string) (uint32, error) {
+	c.Icon = absIcon(c.Icon)
+	return daemon.Forward(daemon.SocketPath(), daemon.SocketRequest{
+		Backend: c.Backend,
+		Config:  c,
+		Title:   title,
+		Message: message,
+	})
+}
+
+// absIcon makes an icon file path absolute, leaving stock icon names alone
+func absIcon(icon string) string {
+	if icon == "" || filepath.IsAbs(icon) {
+		return icon
+	}
+	if filepath.Dir(icon) == "." {
+		if _, err := os.Stat(icon); err != nil {
+			return icon
+		}
+	}
+	if abs, err := filepath.Abs(icon); err == nil {
+		return abs
+	}
+	return icon
+}
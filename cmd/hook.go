@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/hook"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	hookTemplates hook.Templates
+	hookAppName   string
+)
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Send notifications from coding agent hooks",
+	Long: `Send notifications from coding agent hooks, built from the event payload the
+agent passes on stdin.
+
+Hook commands never exit with code 2, which agents treat as a request to
+block the action; every failure exits with code 1 instead.`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return hookError(cmd, loadConfiguration(cmd))
+	},
+}
+
+var hookClaudeCmd = &cobra.Command{
+	Use:   "claude",
+	Short: "Notify from a Claude Code hook event read from stdin",
+	Long: `Read a Claude Code hook payload from stdin and send a notification built from
+its event type, tool name, command and working directory. Notification events
+and failed tool calls are sent as alerts.
+
+The title, body, urgency and icon can be overridden with Go templates that see
+the payload fields (.Event, .ToolName, .Command, .FilePath, .Description,
+.Message, .Prompt, .Cwd, .Project, .SessionID, .Failed) and the default
+notification (.Default.Title, .Default.Body, ...).
+
+Example .claude/settings.json hook command:
+  wsl-notify-send hook claude
+  wsl-notify-send hook claude --title '{{.Project}}: {{.Default.Title}}'`,
+	Args:          cobra.NoArgs,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return hookError(cmd, sendClaudeHook(cmd))
+	},
+}
+
+func sendClaudeHook(cmd *cobra.Command) error {
+	event, err := hook.ParseClaude(cmd.InOrStdin())
+	if err != nil {
+		return err
+	}
+
+	n, err := hook.Render(event, hookTemplates)
+	if err != nil {
+		return err
+	}
+
+	// The hook decides the urgency, the rest comes from the config. An icon
+	// or app name set there is kept unless the hook's own flag is given.
+	hookCfg := cfg
+	hookCfg.Urgency = n.Urgency
+	hookCfg.AlertMode = n.Alert
+	hookCfg.BeepMode = false
+	if hookCfg.Icon == "" || cmd.Flags().Changed("icon") {
+		hookCfg.Icon = n.Icon
+	}
+	if _, configured := optionSources["app-name"]; !configured || cmd.Flags().Changed("app-name") {
+		hookCfg.AppName = hookAppName
+	}
+	if err := hookCfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", optionSources.Annotate(err))
+	}
+
+	opts, err := hookCfg.NotifyOptions()
+	if err != nil {
+		return err
+	}
+
+	_, err = deliver(hookCfg, n.Title, n.Body, opts)
+	return err
+}
+
+// hookError reports err itself and exits with code 1, as exit code 2 would
+// make the agent block the action that triggered the hook
+func hookError(cmd *cobra.Command, err error) error {
+	if err == nil {
+		return nil
+	}
+	if !cfg.Quiet {
+		cmd.PrintErrf("Error: %v\n", err)
+	}
+	return exitcode.Status(exitcode.General)
+}
+
+func init() {
+	hookClaudeCmd.Flags().StringVar(&hookTemplates.Title, "title", "", "Template for the title")
+	hookClaudeCmd.Flags().StringVar(&hookTemplates.Body, "body", "", "Template for the body")
+	hookClaudeCmd.Flags().StringVar(&hookTemplates.Urgency, "urgency", "", "Template for the urgency (low, normal, critical)")
+	hookClaudeCmd.Flags().StringVar(&hookTemplates.Icon, "icon", "", "Template for the icon")
+	hookClaudeCmd.Flags().StringVar(&hookAppName, "app-name", "Claude Code", "Application name, unless the config sets one")
+
+	hookCmd.AddCommand(hookClaudeCmd)
+	rootCmd.AddCommand(hookCmd)
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"testing"
+	"wsl-notify-send/internal/exitcode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHookClaudeCommand(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	payload := `{"hook_event_name": "PostToolUse", "tool_name": "Bash", "cwd": "/src/api",
+		"tool_input": {"command": "go test ./..."}, "tool_response": {"stdout": "ok"}}`
+
+	mockBeeper.On("SetAppName", "Claude Code").Once()
+	mockBeeper.On("Notify", "Bash finished", "go test ./...\nin api", "dialog-information").Return(nil).Once()
+
+	_, err := executeCommandWithStdin([]string{"hook", "claude"}, payload)
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestHookClaudeCommand_Alerts(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		title   string
+	}{
+		{
+			name:    "notification",
+			payload: `{"hook_event_name": "Notification", "message": "Waiting for input"}`,
+			title:   "Claude needs your attention",
+		},
+		{
+			name:    "failed bash",
+			payload: `{"hook_event_name": "PostToolUse", "tool_name": "Bash", "tool_input": {"command": "make"}, "tool_response": {"interrupted": true}}`,
+			title:   "Bash failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBeeper := setupMockBeeper(t)
+
+			mockBeeper.On("SetAppName", "Claude Code").Once()
+			mockBeeper.On("Alert", tt.title, mock.Anything, mock.Anything).Return(nil).Once()
+
+			_, err := executeCommandWithStdin([]string{"hook", "claude"}, tt.payload)
+
+			assert.NoError(t, err)
+			mockBeeper.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHookClaudeCommand_Templates(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	payload := `{"hook_event_name": "Stop", "cwd": "/src/api", "session_id": "abc123"}`
+
+	mockBeeper.On("SetAppName", "Claude").Once()
+	mockBeeper.On("Notify", "api: Claude finished", "session abc123", "info").Return(nil).Once()
+
+	_, err := executeCommandWithStdin([]string{"hook", "claude",
+		"--app-name", "Claude",
+		"--title", "{{.Project}}: {{.Default.Title}}",
+		"--body", "session {{.SessionID}}",
+		"--icon", "info",
+		"--urgency", "low",
+	}, payload)
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestHookClaudeCommand_Config(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	t.Setenv("WSL_NOTIFY_SEND_APP_NAME", "Agents")
+	t.Setenv("WSL_NOTIFY_SEND_ICON", "robot")
+
+	payload := `{"hook_event_name": "Stop", "cwd": "/src/api"}`
+
+	// The configured app name and icon win over the hook's defaults
+	mockBeeper.On("SetAppName", "Agents").Once()
+	mockBeeper.On("Notify", mock.Anything, mock.Anything, "robot").Return(nil).Once()
+
+	_, err := executeCommandWithStdin([]string{"hook", "claude"}, payload)
+	assert.NoError(t, err)
+
+	// The hook's own flags win over the config
+	mockBeeper.On("SetAppName", "Claude").Once()
+	mockBeeper.On("Notify", mock.Anything, mock.Anything, "info").Return(nil).Once()
+
+	_, err = executeCommandWithStdin([]string{"hook", "claude", "--app-name", "Claude", "--icon", "info"}, payload)
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestHookClaudeCommand_ErrorsNeverBlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		payload  string
+		errorMsg string
+	}{
+		{"bad payload", []string{"hook", "claude"}, "not json", "cannot parse hook payload"},
+		{"bad template", []string{"hook", "claude", "--title", "{{"}, `{"hook_event_name": "Stop"}`, "invalid title template"},
+		{"bad urgency", []string{"hook", "claude", "--urgency", "loud"}, `{"hook_event_name": "Stop"}`, "invalid urgency: loud"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBeeper := setupMockBeeper(t)
+
+			output, err := executeCommandWithStdin(tt.args, tt.payload)
+
+			// Exit code 2 would block the agent
+			assert.Equal(t, exitcode.General, exitcode.Of(err))
+			assert.Contains(t, output, tt.errorMsg)
+			mockBeeper.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHookClaudeCommand_ConfigErrorsNeverBlock(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	t.Setenv("WSL_NOTIFY_SEND_FREQ", "loud")
+
+	output, err := executeCommandWithStdin([]string{"hook", "claude"}, `{"hook_event_name": "Stop"}`)
+
+	assert.Equal(t, exitcode.General, exitcode.Of(err))
+	assert.Contains(t, output, "WSL_NOTIFY_SEND_FREQ")
+	mockBeeper.AssertExpectations(t)
+}
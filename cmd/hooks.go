@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/hook"
+	"wsl-notify-send/internal/shell"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	hooksProject bool
+	hooksUser    bool
+	hooksDryRun  bool
+	hooksCommand string
+)
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Install or remove the Claude Code notification hooks",
+	Long: `Install or remove the hooks that run "wsl-notify-send hook claude" in a Claude
+Code settings file: the project's .claude/settings.json (--project, the
+default) or ~/.claude/settings.json (--user).
+
+Other settings and hooks are left alone, and running install again only
+updates the notification hooks.`,
+}
+
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Add the notification hooks to the Claude Code settings",
+	Long: `Add hooks for Notification, Stop and Bash PostToolUse events to the Claude
+Code settings, or update the ones already there.
+
+Examples:
+  wsl-notify-send hooks install
+  wsl-notify-send hooks install --user --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := loadHookSettings()
+		if err != nil {
+			return err
+		}
+
+		command := hooksCommand
+		if command == "" {
+			command = defaultHookCommand()
+		}
+
+		if err := settings.Install(command, hook.DefaultEvents, hookMatcher(command)); err != nil {
+			return exitcode.Mark(config.ErrInvalidConfig, err)
+		}
+		return saveHookSettings(cmd, settings, "Installed notification hooks in", "Notification hooks already installed in")
+	},
+}
+
+var hooksUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the notification hooks from the Claude Code settings",
+	Long: `Remove the notification hooks from the Claude Code settings. Hooks installed
+with --command are only recognized when given the same --command.
+
+Examples:
+  wsl-notify-send hooks uninstall
+  wsl-notify-send hooks uninstall --command "~/bin/notify-wrapper"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := loadHookSettings()
+		if err != nil {
+			return err
+		}
+
+		if err := settings.Uninstall(hookMatcher(hooksCommand)); err != nil {
+			return exitcode.Mark(config.ErrInvalidConfig, err)
+		}
+		return saveHookSettings(cmd, settings, "Removed notification hooks from", "No notification hooks in")
+	},
+}
+
+// loadHookSettings loads the settings file picked by --project or --user
+func loadHookSettings() (*hook.Settings, error) {
+	if hooksProject && hooksUser {
+		return nil, exitcode.Mark(config.ErrInvalidArgs, errors.New("cannot use both --project and --user"))
+	}
+
+	var path string
+	if hooksUser {
+		p, err := hook.UserSettingsFile()
+		if err != nil {
+			return nil, err
+		}
+		path = p
+	} else {
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		path = hook.ProjectSettingsFile(dir)
+	}
+
+	settings, err := hook.LoadSettings(path)
+	if err != nil {
+		return nil, exitcode.Mark(config.ErrInvalidConfig, err)
+	}
+	return settings, nil
+}
+
+// saveHookSettings writes changed settings, or prints the diff with --dry-run
+func saveHookSettings(cmd *cobra.Command, settings *hook.Settings, changedMsg, unchangedMsg string) error {
+	changed, err := settings.Changed()
+	if err != nil {
+		return err
+	}
+	if !changed {
+		cmd.Printf("%s %s\n", unchangedMsg, settings.Path)
+		return nil
+	}
+
+	if hooksDryRun {
+		diff, err := settings.Diff()
+		if err != nil {
+			return err
+		}
+		cmd.Print(diff)
+		return nil
+	}
+
+	if err := settings.Save(); err != nil {
+		return err
+	}
+	cmd.Printf("%s %s\n", changedMsg, settings.Path)
+	return nil
+}
+
+// defaultHookCommand runs this binary by name when it is the one found on
+// the PATH, so project settings work on other machines, and by absolute
+// path otherwise
+func defaultHookCommand() string {
+	executable, err := os.Executable()
+	if err != nil {
+		return "wsl-notify-send hook claude"
+	}
+
+	name := strings.TrimSuffix(filepath.Base(executable), ".exe")
+	if found, err := exec.LookPath(name); err == nil && sameFile(found, executable) {
+		return name + " hook claude"
+	}
+	return shell.Quote(executable) + " hook claude"
+}
+
+func sameFile(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
+
+// hookMatcher recognizes the hooks running command, which may be a wrapper
+// script or a renamed binary, as well as the ones running this binary
+func hookMatcher(command string) func(string) bool {
+	command = strings.TrimSpace(command)
+	return func(c string) bool {
+		return (command != "" && strings.TrimSpace(c) == command) || isHookCommand(c)
+	}
+}
+
+// isHookCommand recognizes the hook commands installed by hooks install
+// without --command, wherever the binary lives
+func isHookCommand(command string) bool {
+	i := strings.Index(command, " hook claude")
+	if i < 0 {
+		return false
+	}
+	rest := command[i+len(" hook claude"):]
+	if rest != "" && !strings.HasPrefix(rest, " ") {
+		return false
+	}
+
+	program := strings.Trim(strings.TrimSpace(command[:i]), `'"`)
+	return strings.HasPrefix(filepath.Base(program), "wsl-notify-send")
+}
+
+func init() {
+	for _, c := range []*cobra.Command{hooksInstallCmd, hooksUninstallCmd} {
+		c.Flags().BoolVar(&hooksProject, "project", false, "Edit the project's .claude/settings.json (default)")
+		c.Flags().BoolVar(&hooksUser, "user", false, "Edit ~/.claude/settings.json")
+		c.Flags().BoolVar(&hooksDryRun, "dry-run", false, "Print the changes as a diff instead of writing them")
+	}
+	hooksInstallCmd.Flags().StringVar(&hooksCommand, "command", "", "Hook command to install (default: this binary with \"hook claude\")")
+	hooksUninstallCmd.Flags().StringVar(&hooksCommand, "command", "", "Also remove the hooks running this command, as given to install")
+
+	hooksCmd.AddCommand(hooksInstallCmd)
+	hooksCmd.AddCommand(hooksUninstallCmd)
+	rootCmd.AddCommand(hooksCmd)
+}
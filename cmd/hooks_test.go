@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"wsl-notify-send/internal/exitcode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooksInstallCommand(t *testing.T) {
+	setupMockBeeper(t)
+	project := t.TempDir()
+	t.Chdir(project)
+	path := filepath.Join(project, ".claude", "settings.json")
+
+	output, err := executeCommand([]string{"hooks", "install", "--command", "wsl-notify-send hook claude"})
+	require.NoError(t, err)
+	assert.Equal(t, "Installed notification hooks in "+path+"\n", output)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(data), "wsl-notify-send hook claude"))
+
+	output, err = executeCommand([]string{"hooks", "install", "--command", "wsl-notify-send hook claude"})
+	require.NoError(t, err)
+	assert.Equal(t, "Notification hooks already installed in "+path+"\n", output)
+
+	output, err = executeCommand([]string{"hooks", "uninstall"})
+	require.NoError(t, err)
+	assert.Equal(t, "Removed notification hooks from "+path+"\n", output)
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{}\n", string(data))
+
+	output, err = executeCommand([]string{"hooks", "uninstall"})
+	require.NoError(t, err)
+	assert.Equal(t, "No notification hooks in "+path+"\n", output)
+}
+
+func TestHooksInstallCommand_CustomCommand(t *testing.T) {
+	setupMockBeeper(t)
+	project := t.TempDir()
+	t.Chdir(project)
+	path := filepath.Join(project, ".claude", "settings.json")
+
+	for _, command := range []string{"~/bin/notify-wrapper", "/opt/notifier/bin/notifier hook claude"} {
+		t.Run(command, func(t *testing.T) {
+			output, err := executeCommand([]string{"hooks", "install", "--command", command})
+			require.NoError(t, err)
+			assert.Equal(t, "Installed notification hooks in "+path+"\n", output)
+
+			output, err = executeCommand([]string{"hooks", "install", "--command", command})
+			require.NoError(t, err)
+			assert.Equal(t, "Notification hooks already installed in "+path+"\n", output)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, 3, strings.Count(string(data), command))
+
+			output, err = executeCommand([]string{"hooks", "uninstall"})
+			require.NoError(t, err)
+			assert.Equal(t, "No notification hooks in "+path+"\n", output)
+
+			output, err = executeCommand([]string{"hooks", "uninstall", "--command", command})
+			require.NoError(t, err)
+			assert.Equal(t, "Removed notification hooks from "+path+"\n", output)
+
+			data, err = os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, "{}\n", string(data))
+		})
+	}
+}
+
+func TestHooksInstallCommand_DryRun(t *testing.T) {
+	setupMockBeeper(t)
+	t.Chdir(t.TempDir())
+
+	output, err := executeCommand([]string{"hooks", "install", "--dry-run", "--command", "wsl-notify-send hook claude"})
+	require.NoError(t, err)
+	assert.Contains(t, output, "+++ ")
+	assert.Contains(t, output, `+            "command": "wsl-notify-send hook claude"`)
+
+	_, err = os.Stat(filepath.Join(".claude", "settings.json"))
+	assert.True(t, os.IsNotExist(err), "dry run must not write the settings")
+}
+
+func TestHooksInstallCommand_User(t *testing.T) {
+	setupMockBeeper(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(t.TempDir())
+
+	_, err := executeCommand([]string{"hooks", "install", "--user", "--command", "wsl-notify-send hook claude"})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(home, ".claude", "settings.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "wsl-notify-send hook claude")
+}
+
+func TestHooksInstallCommand_Errors(t *testing.T) {
+	setupMockBeeper(t)
+	t.Chdir(t.TempDir())
+
+	_, err := executeCommand([]string{"hooks", "install", "--project", "--user"})
+	assert.ErrorContains(t, err, "cannot use both --project and --user")
+	assert.Equal(t, 2, exitcode.Of(err))
+
+	require.NoError(t, os.MkdirAll(".claude", 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(".claude", "settings.json"), []byte("{"), 0644))
+
+	_, err = executeCommand([]string{"hooks", "install"})
+	assert.ErrorContains(t, err, "cannot parse settings")
+	assert.Equal(t, 2, exitcode.Of(err))
+}
+
+func TestIsHookCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"wsl-notify-send hook claude", true},
+		{"/usr/local/bin/wsl-notify-send hook claude", true},
+		{"'/home/me/go/bin/wsl-notify-send' hook claude", true},
+		{"wsl-notify-send.exe hook claude --title '{{.Default.Title}}'", true},
+		{"wsl-notify-send hook claudette", false},
+		{"other-notifier hook claude", false},
+		{"wsl-notify-send Done", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			assert.Equal(t, tt.want, isHookCommand(tt.command))
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/exitcode"
+
+	"github.com/spf13/cobra"
+)
+
+// sendJSON sends the notifications read from r with --json or --jsonl
+func sendJSON(cmd *cobra.Command, r io.Reader) error {
+	// The options every notification starts from are checked once, up front
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", optionSources.Annotate(err))
+	}
+
+	if cfg.JSONL {
+		return sendJSONLines(cmd, r)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("cannot read notification: %w", err)
+	}
+	return sendRequest(cmd, data)
+}
+
+// sendJSONLines sends one notification per line of r. A line that fails is
+// reported and the stream goes on; the exit code is that of the first
+// failure.
+func sendJSONLines(cmd *cobra.Command, r io.Reader) error {
+	reader := bufio.NewReader(r)
+
+	var first error
+	for n := 1; ; n++ {
+		line, readErr := reader.ReadBytes('\n')
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			if err := sendRequest(cmd, line); err != nil {
+				if first == nil {
+					first = err
+				}
+				if !cfg.Quiet {
+					cmd.PrintErrf("Error: line %d: %v\n", n, err)
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("cannot read notifications: %w", readErr)
+		}
+	}
+
+	if first != nil {
+		// The failures have been reported already
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return exitcode.Status(exitcode.Of(first))
+	}
+	return nil
+}
+
+// sendRequest decodes one JSON notification and sends it
+func sendRequest(cmd *cobra.Command, data []byte) error {
+	// Templates may use fields of their own
+	templated := cfg.TitleTemplate != "" || cfg.Template != ""
+
+	req, err := config.DecodeRequest(data, templated)
+	if err != nil {
+		return exitcode.Mark(config.ErrInvalidArgs, err)
+	}
+
+	c := req.Apply(cfg)
+	if req.Title == "" && !c.BeepMode && c.TitleTemplate == "" {
+		return exitcode.Mark(config.ErrInvalidArgs, errors.New("notification has no title"))
+	}
+
+	return send(cmd, c, req.Title, req.Message, req.Fields)
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/exitcode"
+
+	"github.com/spf13/cobra"
+)
+
+var profilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "List and show the profiles defined in the config files",
+	Long: `Profiles are named sets of options kept in the profiles section of the user or
+project config file. Use one with --profile NAME.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listProfiles(cmd)
+	},
+}
+
+var profilesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the profiles and the files that define them",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listProfiles(cmd)
+	},
+}
+
+var profilesShowCmd = &cobra.Command{
+	Use:   "show NAME",
+	Short: "Show the options a profile sets",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		layers, err := loadConfigLayers()
+		if err != nil {
+			return err
+		}
+
+		profile, err := config.FindProfile(layers, args[0])
+		if err != nil {
+			return exitcode.Mark(config.ErrInvalidArgs, err)
+		}
+
+		cmd.Printf("# %s\n", profile.Source)
+
+		keys := make([]string, 0, len(profile.Values))
+		for key := range profile.Values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			cmd.Printf("%s: %s\n", key, strings.Join(profile.Values[key], ", "))
+		}
+		return nil
+	},
+}
+
+func listProfiles(cmd *cobra.Command) error {
+	layers, err := loadConfigLayers()
+	if err != nil {
+		return err
+	}
+
+	profiles := config.Profiles(layers)
+	if len(profiles) == 0 {
+		cmd.Println("No profiles defined")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSOURCE")
+	for _, p := range profiles {
+		fmt.Fprintf(w, "%s\t%s\n", p.Name, p.Source)
+	}
+	return w.Flush()
+}
+
+// loadConfigLayers loads the config files for the working directory
+func loadConfigLayers() ([]config.Layer, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := config.LoadLayers(dir)
+	if err != nil {
+		return nil, exitcode.Mark(config.ErrInvalidConfig, fmt.Errorf("invalid configuration: %w", err))
+	}
+	return layers, nil
+}
+
+func init() {
+	profilesCmd.AddCommand(profilesListCmd)
+	profilesCmd.AddCommand(profilesShowCmd)
+	rootCmd.AddCommand(profilesCmd)
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/notify"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	progressLabel    string
+	progressTag      string
+	progressGroup    string
+	progressInterval time.Duration
+)
+
+var progressCmd = &cobra.Command{
+	Use:   "progress [flags] <title> [message]",
+	Short: "Show progress read from stdin on one notification",
+	Long: `Read progress from stdin and show it on the progress bar of one notification,
+updating it until the input ends. Each line is a percentage, like "42" or
+"42%", or an amount done out of a total, like "3/7", which is also shown as the
+status. The output of "pv -n" can be piped in as it is.
+
+Updates are sent at most once per --interval, and the last value read is
+always sent when the input ends. Backends that can't update a notification in
+place only show the final progress.
+
+The notification uses the icon, app name and other options from the config
+files, profile and environment. An alert or critical urgency only plays a
+sound for the first notification, the updates are silent.
+
+Examples:
+  pv -n backup.tar 2>&1 >/mnt/d/backup.tar | wsl-notify-send progress "Backup"
+  ./sync.sh | wsl-notify-send progress --label "Syncing" --tag sync "Photos" "To the NAS"`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return exitcode.Mark(config.ErrInvalidArgs, errors.New("requires a title"))
+		}
+		if len(args) > 2 {
+			return exitcode.Mark(config.ErrInvalidArgs, errors.New("too many arguments, expected: <title> [message]"))
+		}
+		return nil
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if progressInterval < 0 {
+			return exitcode.Mark(config.ErrInvalidArgs, errors.New("--interval must be 0 or greater"))
+		}
+
+		c := cfg
+		c.BeepMode = false
+		c.Progress = "0"
+		if progressLabel != "" {
+			c.ProgressLabel = progressLabel
+		}
+		if progressTag != "" {
+			c.Tag = progressTag
+		}
+		if progressGroup != "" {
+			c.Group = progressGroup
+		}
+		// Every update needs the same identity to land on the same toast
+		if c.Tag == "" {
+			c.Tag = "progress-" + strconv.Itoa(os.Getpid())
+		}
+
+		// Check the notification settings before reading anything
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", optionSources.Annotate(err))
+		}
+
+		p := &progressNotification{cfg: c, title: args[0], live: notify.CanUpdate()}
+		if len(args) > 1 {
+			p.message = args[1]
+		}
+
+		scanner := bufio.NewScanner(cmd.InOrStdin())
+		for n := 1; scanner.Scan(); n++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+
+			percent, status, err := parseProgressLine(line)
+			if err != nil {
+				if !cfg.Quiet {
+					cmd.PrintErrf("Error: line %d: %v\n", n, err)
+				}
+				continue
+			}
+			if err := p.update(percent, status); err != nil {
+				return err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("cannot read progress: %w", err)
+		}
+
+		return p.finish()
+	},
+}
+
+// progressNotification keeps one notification showing the latest progress
+type progressNotification struct {
+	cfg     config.Config
+	title   string
+	message string
+
+	// live is set when the backend updates the notification in place, so
+	// progress can be shown before the input ends
+	live bool
+
+	latest *notify.Progress
+	shown  *notify.Progress
+	sentAt time.Time
+}
+
+// update records the latest progress and shows it, unless an update was
+// sent less than --interval ago
+func (p *progressNotification) update(percent int, status string) error {
+	if status == "" {
+		status = p.cfg.ProgressStatus
+	}
+	p.latest = &notify.Progress{Percent: percent, Label: p.cfg.ProgressLabel, Status: status}
+
+	if !p.live || time.Since(p.sentAt) < progressInterval {
+		return nil
+	}
+	return p.send()
+}
+
+// finish shows the last progress read
+func (p *progressNotification) finish() error {
+	if p.latest == nil {
+		return exitcode.Mark(config.ErrInvalidArgs, errors.New("no progress read from stdin"))
+	}
+	return p.send()
+}
+
+// send shows the latest progress, unless it is already showing. The ID the
+// backend gave the notification is reused, so D-Bus replaces it as well.
+// Only the first notification alerts, the updates are silent.
+func (p *progressNotification) send() error {
+	if p.shown != nil && *p.shown == *p.latest {
+		return nil
+	}
+
+	c := p.cfg
+	c.Progress = strconv.Itoa(p.latest.Percent)
+	c.ProgressStatus = p.latest.Status
+	opts, err := c.NotifyOptions()
+	if err != nil {
+		return exitcode.Mark(config.ErrInvalidConfig, fmt.Errorf("invalid configuration: %w", err))
+	}
+
+	id, err := deliver(c, p.title, p.message, opts)
+	if err != nil {
+		return err
+	}
+	if id != 0 {
+		p.cfg.ReplaceID = id
+	}
+
+	// Critical urgency plays a sound too, so keep only its staying on screen
+	p.cfg.AlertMode = false
+	if p.cfg.Urgency == notify.UrgencyCritical {
+		p.cfg.Urgency = notify.UrgencyNormal
+		p.cfg.ExpireTime = 0
+	}
+
+	p.shown = p.latest
+	p.sentAt = time.Now()
+	return nil
+}
+
+// parseProgressLine reads a percentage like "42" or "42%", or an amount done
+// out of a total like "3/7", which is also returned as the status. Progress
+// past the end, as from a total that was estimated too low, shows as 100.
+func parseProgressLine(line string) (int, string, error) {
+	if done, total, ok := strings.Cut(line, "/"); ok {
+		d, err := strconv.ParseFloat(strings.TrimSpace(done), 64)
+		if err != nil || !(d >= 0) {
+			return 0, "", fmt.Errorf("invalid progress: %s (expected a percentage or done/total)", line)
+		}
+		t, err := strconv.ParseFloat(strings.TrimSpace(total), 64)
+		if err != nil || !(t > 0) {
+			return 0, "", fmt.Errorf("invalid progress: %s (expected a percentage or done/total)", line)
+		}
+		return int(math.Min(d/t*100, 100)), line, nil
+	}
+
+	percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(line, "%")), 64)
+	if err != nil || !(percent >= 0) {
+		return 0, "", fmt.Errorf("invalid progress: %s (expected a percentage or done/total)", line)
+	}
+	return int(math.Min(percent, 100)), "", nil
+}
+
+func init() {
+	progressCmd.Flags().StringVar(&progressLabel, "label", "", "Label shown above the progress bar")
+	progressCmd.Flags().StringVar(&progressTag, "tag", "", "Identity of the notification (default \"progress-PID\")")
+	progressCmd.Flags().StringVar(&progressGroup, "group", "", "Group the tag belongs to")
+	progressCmd.Flags().DurationVar(&progressInterval, "interval", 500*time.Millisecond, "Shortest time between two updates")
+
+	rootCmd.AddCommand(progressCmd)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"testing"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/notify"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSent returns what the fake backend was sent
+func fakeSent(t *testing.T) []notify.FakeNotification {
+	t.Helper()
+
+	fake, ok := notify.GetBeeper().(*notify.FakeBeeper)
+	require.True(t, ok)
+	return fake.Sent()
+}
+
+func TestProgressCommand(t *testing.T) {
+	setupMockBeeper(t)
+
+	input := "10\n\n10%\nhalf\n50%\n3/4\n"
+	output, err := executeCommandWithStdin([]string{"progress", "--backend", "fake", "--interval", "0", "--label", "Copying", "Backup"}, input)
+
+	require.NoError(t, err)
+	assert.Contains(t, output, "Error: line 4: invalid progress: half (expected a percentage or done/total)")
+
+	// Unchanged progress isn't sent again, and every update replaces the
+	// first notification
+	sent := fakeSent(t)
+	require.Len(t, sent, 3)
+	for i, want := range []string{"Copying: 10%", "Copying: 50%", "Copying: 75% (3/4)"} {
+		assert.Equal(t, "Backup", sent[i].Title)
+		assert.Equal(t, want, sent[i].Message)
+		assert.Equal(t, uint32(1), sent[i].ID)
+	}
+}
+
+func TestProgressCommand_Interval(t *testing.T) {
+	setupMockBeeper(t)
+
+	_, err := executeCommandWithStdin([]string{"progress", "--backend", "fake", "--interval", "1h", "Backup", "Photos"}, "10\n20\n30\n")
+
+	// The first value is shown right away and the last one at the end
+	require.NoError(t, err)
+	sent := fakeSent(t)
+	require.Len(t, sent, 2)
+	assert.Equal(t, "Photos\n10%", sent[0].Message)
+	assert.Equal(t, "Photos\n30%", sent[1].Message)
+}
+
+func TestProgressCommand_SilentUpdates(t *testing.T) {
+	tests := []struct {
+		env, value string
+	}{
+		{"WSL_NOTIFY_SEND_ALERT", "true"},
+		{"WSL_NOTIFY_SEND_URGENCY", "critical"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			setupMockBeeper(t)
+			t.Setenv(tt.env, tt.value)
+
+			_, err := executeCommandWithStdin([]string{"progress", "--backend", "fake", "--interval", "0", "Backup"}, "10\n50\n100\n")
+			require.NoError(t, err)
+
+			// Only the first notification plays a sound
+			sent := fakeSent(t)
+			require.Len(t, sent, 3)
+			sounds := 0
+			for _, n := range sent {
+				if n.Sound {
+					sounds++
+				}
+			}
+			assert.Equal(t, 1, sounds)
+			assert.True(t, sent[0].Sound)
+		})
+	}
+}
+
+func TestProgressCommand_FinalOnly(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	// beeep stacks a new notification for every update, so only the end
+	// is shown
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Notify", "Backup", "100% (7/7)", "").Return(nil).Once()
+
+	_, err := executeCommandWithStdin([]string{"progress", "--interval", "0", "Backup"}, "1/7\n4/7\n7/7\n")
+
+	require.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestProgressCommand_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+		msg   string
+	}{
+		{"no title", []string{"progress"}, "", "requires a title"},
+		{"no progress", []string{"progress", "Backup"}, "\nhalf\n", "no progress read from stdin"},
+		{"negative interval", []string{"progress", "--interval", "-1s", "Backup"}, "10\n", "--interval must be 0 or greater"},
+		{"long tag", []string{"progress", "--tag", "a-tag-that-is-far-too-long-for-windows-to-accept-it-as-a-toast-tag", "Backup"}, "10\n", "tag must be at most 64 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBeeper := setupMockBeeper(t)
+
+			_, err := executeCommandWithStdin(tt.args, tt.input)
+
+			assert.ErrorContains(t, err, tt.msg)
+			assert.Equal(t, exitcode.InvalidArgs, exitcode.Of(err))
+			mockBeeper.AssertExpectations(t)
+		})
+	}
+}
+
+func TestParseProgressLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		percent int
+		status  string
+	}{
+		{"42", 42, ""},
+		{"42%", 42, ""},
+		{"42.9", 42, ""},
+		{"0", 0, ""},
+		{"130", 100, ""},
+		{"3/7", 42, "3/7"},
+		{"1048576 / 4194304", 25, "1048576 / 4194304"},
+		{"9/7", 100, "9/7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			percent, status, err := parseProgressLine(tt.line)
+			require.NoError(t, err)
+			assert.Equal(t, tt.percent, percent)
+			assert.Equal(t, tt.status, status)
+		})
+	}
+
+	for _, line := range []string{"half", "-5", "NaN", "3/0", "3/", "/7", "-1/7"} {
+		_, _, err := parseProgressLine(line)
+		assert.ErrorContains(t, err, "invalid progress: "+line, line)
+	}
+}
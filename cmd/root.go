@@ -1,11 +1,18 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"wsl-notify-send/internal/body"
 	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/exitcode"
 	"wsl-notify-send/internal/notify"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // Version can be set at build time with: go build -ldflags "-X wsl-notify-send/cmd.Version=x.y.z"
@@ -13,6 +20,10 @@ var Version = "0.1.0"
 
 var cfg config.Config
 
+// optionSources records which config file or environment variable set each
+// option, so validation errors can point at it
+var optionSources config.Sources
+
 var rootCmd = &cobra.Command{
 	Use:   "wsl-notify-send [flags] <title> [message]",
 	Short: "Send desktop notifications on Windows and WSL2",
@@ -25,26 +36,52 @@ Examples:
   wsl-notify-send --alert "Warning" "Something happened"
   wsl-notify-send --beep
   wsl-notify-send --icon icon.png "Info" "With custom icon"
-  wsl-notify-send --app-name "MyApp" "Custom" "From MyApp"`,
+  wsl-notify-send --app-name "MyApp" "Custom" "From MyApp"
+  wsl-notify-send -u critical -t 5000 --hint string:x-foo:bar "Build" "Failed"
+  wsl-notify-send --profile build-failed "Build" "Tests failed"
+  wsl-notify-send --backend dbus -A retry=Retry --wait "Deploy failed" "Retry?"
+  git log -3 | wsl-notify-send "Recent commits" -
+  wsl-notify-send --tag backup --progress 42 --progress-status "3/7" "Backup"
+  wsl-notify-send --title-template '{{.Env.WSL_DISTRO_NAME}}: {{.Title}}' "Build" "Done"
+  echo '{"title": "Build", "message": "Done", "urgency": "low"}' | wsl-notify-send --json
+
+Every flag can also be set with an environment variable named after it,
+e.g. WSL_NOTIFY_SEND_APP_NAME for --app-name.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfiguration(cmd)
+	},
 	Args: func(cmd *cobra.Command, args []string) error {
 		// If version mode, no args required
 		if cfg.Version {
 			return nil
 		}
 
+		// JSON input replaces the title and message arguments
+		if cfg.JSON || cfg.JSONL {
+			if len(args) > 0 {
+				return exitcode.Mark(config.ErrInvalidArgs, fmt.Errorf("cannot use title or message arguments with --json or --jsonl"))
+			}
+			return nil
+		}
+
 		// If beep mode, no args required
 		if cfg.BeepMode {
 			return nil
 		}
 
-		// Otherwise, need at least title
-		if len(args) < 1 {
-			return fmt.Errorf("requires at least a title argument")
+		// Otherwise, need at least title, unless a template makes one
+		if len(args) < 1 && cfg.TitleTemplate == "" {
+			return exitcode.Mark(config.ErrInvalidArgs, fmt.Errorf("requires at least a title argument"))
 		}
 
 		// Maximum 2 args (title and message)
 		if len(args) > 2 {
-			return fmt.Errorf("too many arguments, expected: <title> [message]")
+			return exitcode.Mark(config.ErrInvalidArgs, fmt.Errorf("too many arguments, expected: <title> [message]"))
+		}
+
+		// The message comes from either the argument or the body file
+		if len(args) > 1 && cfg.BodyFile != "" {
+			return exitcode.Mark(config.ErrInvalidArgs, fmt.Errorf("cannot use both a message argument and --body-file"))
 		}
 
 		return nil
@@ -56,29 +93,25 @@ Examples:
 			return nil
 		}
 
-		// Validate configuration
-		if err := cfg.Validate(); err != nil {
-			return fmt.Errorf("invalid configuration: %w", err)
+		if cfg.JSON || cfg.JSONL {
+			return sendJSON(cmd, cmd.InOrStdin())
 		}
 
-		// Handle beep mode
-		if cfg.BeepMode {
-			return notify.Beep(cfg.Frequency, cfg.Duration)
+		// Parse title and message, beep mode has none
+		title, message := "", ""
+		if len(args) > 0 {
+			title = args[0]
 		}
-
-		// Parse title and message
-		title := args[0]
-		message := ""
 		if len(args) > 1 {
 			message = args[1]
 		}
 
-		// Send notification
-		if cfg.AlertMode {
-			return notify.Alert(title, message, cfg.Icon, cfg.AppName)
+		message, err := readMessage(cmd, message)
+		if err != nil {
+			return err
 		}
 
-		return notify.Notify(title, message, cfg.Icon, cfg.AppName)
+		return send(cmd, cfg, title, message, nil)
 	},
 }
 
@@ -86,24 +119,211 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// readMessage reads the message from stdin when it is "-", or from the file
+// given with --body-file
+func readMessage(cmd *cobra.Command, message string) (string, error) {
+	if cfg.BeepMode {
+		return message, nil
+	}
+
+	switch {
+	case cfg.BodyFile == "-", cfg.BodyFile == "" && message == "-":
+		return body.Read(cmd.InOrStdin())
+	case cfg.BodyFile != "":
+		message, err := body.ReadFile(cfg.BodyFile)
+		return message, exitcode.Mark(config.ErrInvalidArgs, err)
+	default:
+		return message, nil
+	}
+}
+
+// send validates c and sends the notification, or beeps in beep mode. The
+// templates see the JSON input fields, if any.
+func send(cmd *cobra.Command, c config.Config, title, message string, input map[string]interface{}) error {
+	// Validate configuration
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", optionSources.Annotate(err))
+	}
+
+	// Handle beep mode
+	if c.BeepMode {
+		return beep(c)
+	}
+
+	title, message, err := c.RenderTemplates(title, message, input)
+	if err != nil {
+		return err
+	}
+
+	opts, err := c.NotifyOptions()
+	if err != nil {
+		return exitcode.Mark(config.ErrInvalidConfig, fmt.Errorf("invalid configuration: %w", err))
+	}
+
+	// Send notification, waiting for the response if asked to
+	var id uint32
+	var action string
+	switch {
+	case c.Wait:
+		id, action, err = notify.NotifyAndWait(cmd.Context(), title, message, c.Icon, c.AppName, opts, c.AlertMode)
+	default:
+		id, err = deliver(c, title, message, opts)
+	}
+	// A dismissal or timeout is an answer rather than a failure, so it is
+	// only reported through the exit code
+	closed := errors.Is(err, notify.ErrDismissed) || errors.Is(err, notify.ErrTimeout)
+	if err != nil && !closed {
+		return err
+	}
+
+	// Both go to stdout for scripts to capture, as with notify-send
+	if c.PrintID {
+		fmt.Fprintln(cmd.OutOrStdout(), id)
+	}
+	if closed {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return exitcode.Status(exitcode.Of(err))
+	}
+	if action != "" {
+		fmt.Fprintln(cmd.OutOrStdout(), action)
+	}
+
+	return nil
+}
+
+// loadConfiguration fills in the options not given on the command line and
+// switches to the selected backend
+func loadConfiguration(cmd *cobra.Command) error {
+	// Fill in everything not given on the command line from the
+	// environment and config files
+	sources, err := applyConfigFiles(cmd.Root())
+	if err != nil {
+		return exitcode.Mark(config.ErrInvalidConfig, fmt.Errorf("invalid configuration: %w", err))
+	}
+	optionSources = sources
+
+	// Switch backend only when one was asked for, keeping the default otherwise
+	if cfg.Backend == "" {
+		return nil
+	}
+	if err := notify.UseBackend(cfg.Backend); err != nil {
+		err = optionSources.Annotate(&config.OptionError{Option: "backend", Err: err})
+		return exitcode.Mark(config.ErrInvalidConfig, fmt.Errorf("invalid configuration: %w", err))
+	}
+	return nil
+}
+
+// applyConfigFiles sets every root flag that wasn't given on the command
+// line from the environment, then the selected profile, then the project
+// and user config files
+func applyConfigFiles(root *cobra.Command) (config.Sources, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := config.LoadLayers(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	flags.AddFlagSet(root.Flags())
+	flags.AddFlagSet(root.PersistentFlags())
+
+	env := config.EnvLayers(flags, os.Getenv)
+
+	layers := append([]config.Layer{}, files...)
+	if name := config.ProfileName(flags, append(files, env...)); name != "" {
+		profile, err := config.FindProfile(files, name)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, profile.Layer())
+	}
+	layers = append(layers, env...)
+
+	return config.ApplyLayers(flags, layers)
+}
+
 func IsQuietMode() bool {
 	return cfg.Quiet
 }
 
+// invokedAsNotifySend reports whether the binary was run under the name
+// notify-send, as through a symlink standing in for libnotify's
+func invokedAsNotifySend() bool {
+	return strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe") == "notify-send"
+}
+
 func init() {
+	// Unknown flags and bad flag values are invalid arguments
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return exitcode.Mark(config.ErrInvalidArgs, err)
+	})
+
+	defineRootFlags(invokedAsNotifySend())
+}
+
+// defineRootFlags adds the flags of the root command. Run as notify-send,
+// the short flags are notify-send's own: -a is --app-name, -h is --hint and
+// help is -?. Otherwise -a is --alert, -h is help and --hint has no short
+// form.
+func defineRootFlags(notifySend bool) {
+	alertShort, appNameShort, hintShort := "a", "", ""
+	if notifySend {
+		alertShort, appNameShort, hintShort = "", "a", "h"
+	}
+
 	// Notification mode flags
-	rootCmd.Flags().BoolVarP(&cfg.AlertMode, "alert", "a", false, "Send alert notification with sound")
+	rootCmd.Flags().BoolVarP(&cfg.AlertMode, "alert", alertShort, false, "Send alert notification with sound")
 	rootCmd.Flags().BoolVarP(&cfg.BeepMode, "beep", "b", false, "Just beep (no notification)")
 
 	// Content flags
 	rootCmd.Flags().StringVarP(&cfg.Icon, "icon", "i", "", "Icon file path or stock icon name")
-	rootCmd.Flags().StringVar(&cfg.AppName, "app-name", "wsl-notify-send", "Application name")
+	rootCmd.Flags().StringVarP(&cfg.AppName, "app-name", appNameShort, "wsl-notify-send", "Application name")
+	rootCmd.Flags().StringVar(&cfg.BodyFile, "body-file", "", "Read the message from a file (\"-\" for stdin)")
+	rootCmd.Flags().StringArrayVar(&cfg.Lines, "line", nil, "Extra text line below the message (repeatable)")
+	rootCmd.Flags().StringVar(&cfg.Attribution, "attribution", "", "Attribution line, e.g. the source of the notification")
+	rootCmd.Flags().StringVar(&cfg.Timestamp, "timestamp", "", "Time shown on the notification, in RFC 3339 format")
+	rootCmd.Flags().StringVar(&cfg.Progress, "progress", "", "Progress bar value from 0 to 100")
+	rootCmd.Flags().StringVar(&cfg.ProgressLabel, "progress-label", "", "Label shown above the progress bar")
+	rootCmd.Flags().StringVar(&cfg.ProgressStatus, "progress-status", "", "Status shown below the progress bar, e.g. \"3/7 files\"")
+	rootCmd.Flags().StringVar(&cfg.Tag, "tag", "", "Identity of the notification; one sent with the same tag updates it")
+	rootCmd.Flags().StringVar(&cfg.Group, "group", "", "Group the tag belongs to, narrowing its identity")
+	rootCmd.Flags().StringVar(&cfg.TitleTemplate, "title-template", "", "Go template that renders the title")
+	rootCmd.Flags().StringVar(&cfg.Template, "template", "", "Go template that renders the message")
 
 	// Beep customization flags
 	rootCmd.Flags().Float64Var(&cfg.Frequency, "freq", 587.0, "Beep frequency in Hz")
 	rootCmd.Flags().IntVar(&cfg.Duration, "duration", 500, "Beep duration in milliseconds")
 
+	// notify-send compatibility flags
+	rootCmd.Flags().StringVarP(&cfg.Urgency, "urgency", "u", "normal", "Urgency level (low, normal, critical)")
+	rootCmd.Flags().IntVarP(&cfg.ExpireTime, "expire-time", "t", -1, "Timeout in milliseconds (-1 for server default, 0 for never)")
+	rootCmd.Flags().StringVarP(&cfg.Category, "category", "c", "", "Notification category")
+	rootCmd.Flags().StringArrayVarP(&cfg.Hints, "hint", hintShort, nil, "Extra data as TYPE:NAME:VALUE (boolean, int, double, string, byte)")
+	rootCmd.Flags().Uint32VarP(&cfg.ReplaceID, "replace-id", "r", 0, "ID of the notification to replace")
+	rootCmd.Flags().BoolVarP(&cfg.PrintID, "print-id", "p", false, "Print the notification ID")
+	rootCmd.Flags().BoolVarP(&cfg.Transient, "transient", "e", false, "Show a transient notification")
+	rootCmd.Flags().StringArrayVarP(&cfg.Actions, "action", "A", nil, "Action as [NAME=]Label (repeatable)")
+	rootCmd.Flags().BoolVarP(&cfg.Wait, "wait", "w", false, "Wait for the notification to be clicked or closed, printing the chosen action")
+
+	// Input flags
+	rootCmd.Flags().BoolVar(&cfg.JSON, "json", false, "Read one notification as a JSON object from stdin")
+	rootCmd.Flags().BoolVar(&cfg.JSONL, "jsonl", false, "Read a stream of notifications from stdin, one JSON object per line")
+
+	// Backend flags
+	rootCmd.PersistentFlags().StringVar(&cfg.Backend, "backend", "", "Notification backend (see \"wsl-notify-send backends\")")
+
+	// Profile flags
+	rootCmd.PersistentFlags().StringVar(&cfg.Profile, "profile", "", "Use a named profile from the config files (see \"wsl-notify-send profiles\")")
+
 	// Utility flags
+	if notifySend {
+		rootCmd.Flags().BoolP("help", "?", false, "help for notify-send")
+	}
 	rootCmd.Flags().BoolVarP(&cfg.Quiet, "quiet", "q", false, "Suppress error output")
 	rootCmd.Flags().BoolVar(&cfg.Version, "version", false, "Show version information")
 
@@ -5,10 +5,16 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/daemon"
+	"wsl-notify-send/internal/exitcode"
 	"wsl-notify-send/internal/notify"
 
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -43,6 +49,10 @@ func setupMockBeeper(t *testing.T) *MockBeeper {
 	mockBeeper := new(MockBeeper)
 	notify.SetBeeper(mockBeeper)
 
+	// Keep the user's own config file and daemon out of the tests
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir(t))
+
 	// Initialize config with default values, resetting all fields
 	cfg = config.Config{
 		AlertMode: false,
@@ -74,6 +84,48 @@ func setupMockBeeper(t *testing.T) *MockBeeper {
 	return mockBeeper
 }
 
+// resetFlags restores every flag of the root command and its subcommands
+// to its default value
+func resetFlags() {
+	resetCommandFlags(rootCmd)
+}
+
+func resetCommandFlags(cmd *cobra.Command) {
+	for _, sub := range cmd.Commands() {
+		resetCommandFlags(sub)
+	}
+
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		flag.Changed = false
+
+		// Slice flags append on Set, so they have to be replaced instead
+		if sv, ok := flag.Value.(pflag.SliceValue); ok {
+			_ = sv.Replace(nil)
+			return
+		}
+		_ = flag.Value.Set(flag.DefValue)
+	})
+}
+
+// syncBuffer is a bytes.Buffer that can be written from several goroutines,
+// as a wrapped command's stdout and stderr are
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
 // Helper function to execute command and capture output
 func executeCommand(args []string) (string, error) {
 	// Save original args
@@ -84,7 +136,7 @@ func executeCommand(args []string) (string, error) {
 	os.Args = append([]string{"wsl-notify-send"}, args...)
 
 	// Capture output
-	var buf bytes.Buffer
+	var buf syncBuffer
 	rootCmd.SetOut(&buf)
 	rootCmd.SetErr(&buf)
 
@@ -101,11 +153,11 @@ func executeCommand(args []string) (string, error) {
 	rootCmd.SetArgs(nil)
 	rootCmd.SetOut(os.Stdout)
 	rootCmd.SetErr(os.Stderr)
+	rootCmd.SilenceErrors = false
+	rootCmd.SilenceUsage = false
 
 	// Reset command flags to defaults
-	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
-		_ = flag.Value.Set(flag.DefValue)
-	})
+	resetFlags()
 
 	// Also reset the global config to match the default flags
 	cfg.AlertMode = false
@@ -333,9 +385,7 @@ func TestRootCommand_QuietModeFlag(t *testing.T) {
 
 	// Reset for next test
 	rootCmd.SetArgs(nil)
-	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
-		_ = flag.Value.Set(flag.DefValue)
-	})
+	resetFlags()
 	cfg.AlertMode = false
 	cfg.BeepMode = false
 	cfg.Icon = ""
@@ -365,9 +415,7 @@ func TestRootCommand_ShortFlags(t *testing.T) {
 
 	// Reset for next test
 	rootCmd.SetArgs(nil)
-	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
-		_ = flag.Value.Set(flag.DefValue)
-	})
+	resetFlags()
 	cfg.AlertMode = false
 	cfg.BeepMode = false
 	cfg.Icon = ""
@@ -459,9 +507,7 @@ func TestRootCommand_AllFlagsTogethert(t *testing.T) {
 
 	// Reset for next test
 	rootCmd.SetArgs(nil)
-	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
-		_ = flag.Value.Set(flag.DefValue)
-	})
+	resetFlags()
 	cfg.AlertMode = false
 	cfg.BeepMode = false
 	cfg.Icon = ""
@@ -471,3 +517,1078 @@ func TestRootCommand_AllFlagsTogethert(t *testing.T) {
 	cfg.Quiet = false
 	cfg.Version = false
 }
+
+// MockOptionsBeeper is a MockBeeper that also understands notify-send options
+type MockOptionsBeeper struct {
+	MockBeeper
+}
+
+func (m *MockOptionsBeeper) NotifyWithOptions(title, message string, icon interface{}, opts notify.Options) (uint32, error) {
+	args := m.Called(title, message, icon, opts)
+	return args.Get(0).(uint32), args.Error(1)
+}
+
+func (m *MockOptionsBeeper) AlertWithOptions(title, message string, icon interface{}, opts notify.Options) (uint32, error) {
+	args := m.Called(title, message, icon, opts)
+	return args.Get(0).(uint32), args.Error(1)
+}
+
+// useNotifySendFlags parses the root flags as when the binary is run
+// through a notify-send symlink
+func useNotifySendFlags(t *testing.T) {
+	t.Helper()
+
+	rootCmd.ResetFlags()
+	defineRootFlags(true)
+	t.Cleanup(func() {
+		rootCmd.ResetFlags()
+		defineRootFlags(false)
+	})
+}
+
+func TestRootCommand_NotifySendFlags(t *testing.T) {
+	setupMockBeeper(t)
+	useNotifySendFlags(t)
+	mockBeeper := new(MockOptionsBeeper)
+	notify.SetBeeper(mockBeeper)
+
+	expected := notify.Options{
+		Urgency:       "normal",
+		ExpireTimeout: 5000,
+		Category:      "transfer.complete",
+		Transient:     true,
+		ReplaceID:     7,
+		Actions:       []notify.Action{{Key: "retry", Label: "Retry"}},
+		Hints: []notify.Hint{
+			{Name: "x-foo", Value: "bar"},
+			{Name: "value", Value: int32(42)},
+		},
+	}
+
+	mockBeeper.On("SetAppName", "MyApp").Once()
+	mockBeeper.On("NotifyWithOptions", "Title", "Message", "", expected).Return(uint32(7), nil).Once()
+
+	output, err := executeCommand([]string{
+		"-a", "MyApp",
+		"-u", "normal",
+		"-t", "5000",
+		"-c", "transfer.complete",
+		"-h", "string:x-foo:bar",
+		"-h", "int:value:42",
+		"-r", "7",
+		"-e",
+		"-A", "retry=Retry",
+		"-p",
+		"Title", "Message",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "7\n", output)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_NotifySendFlagsIgnoredByPlainBackend(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	useNotifySendFlags(t)
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Notify", "Title", "Message", "").Return(nil).Once()
+
+	output, err := executeCommand([]string{"-c", "im", "-h", "boolean:resident:true", "-p", "Title", "Message"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0\n", output)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_Wait(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		args     []string
+		output   string
+		code     int
+	}{
+		{"action", "action:retry", nil, "retry\n", exitcode.Success},
+		{"action with id", "action:retry", []string{"-p"}, "1\nretry\n", exitcode.Success},
+		{"dismissed", "dismiss", nil, "", exitcode.Dismissed},
+		{"dismissed with id", "dismiss", []string{"-p"}, "1\n", exitcode.Dismissed},
+		{"timeout", "timeout", nil, "", exitcode.Timeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupMockBeeper(t)
+			t.Setenv(notify.FakeResponseEnv, tt.response)
+
+			args := append([]string{"--backend", "fake", "-A", "retry=Retry", "--wait"}, tt.args...)
+			output, err := executeCommand(append(args, "Deploy failed", "Retry?"))
+
+			assert.Equal(t, tt.code, exitcode.Of(err))
+			assert.Equal(t, tt.output, output, "closing the notification is only reported by the exit code")
+		})
+	}
+}
+
+func TestRootCommand_WaitUnsupported(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	// Nothing is shown, so scripts can't take it for an answer
+	output, err := executeCommand([]string{"--wait", "-A", "retry=Retry", "Deploy failed"})
+
+	assert.ErrorContains(t, err, "cannot wait for a response")
+	assert.Equal(t, exitcode.BackendUnavailable, exitcode.Of(err))
+	assert.NotContains(t, output, "retry")
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_WaitWithJSONL(t *testing.T) {
+	setupMockBeeper(t)
+
+	_, err := executeCommandWithStdin([]string{"--jsonl", "--wait"}, `{"title": "Build"}`)
+
+	assert.ErrorContains(t, err, "cannot use --wait with --jsonl")
+}
+
+func TestRootCommand_InvalidHint(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	_, err := executeCommand([]string{"--hint", "int:value:notanumber", "Title"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid configuration")
+	assert.Contains(t, err.Error(), "invalid int value for hint value")
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_HelpShorthand(t *testing.T) {
+	setupMockBeeper(t)
+
+	output, err := executeCommand([]string{"-h"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Usage:")
+	assert.Contains(t, output, "-a, --alert")
+	assert.Contains(t, output, "    --hint")
+}
+
+func TestRootCommand_QuestionMarkHelp(t *testing.T) {
+	setupMockBeeper(t)
+	useNotifySendFlags(t)
+
+	output, err := executeCommand([]string{"-?"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Usage:")
+	assert.Contains(t, output, "-a, --app-name")
+	assert.Contains(t, output, "-h, --hint")
+}
+
+func TestInvokedAsNotifySend(t *testing.T) {
+	originalArgs := os.Args
+	t.Cleanup(func() { os.Args = originalArgs })
+
+	for name, expected := range map[string]bool{
+		"/usr/local/bin/notify-send": true,
+		"notify-send.exe":            true,
+		"wsl-notify-send":            false,
+		"/usr/bin/notify-sender":     false,
+	} {
+		os.Args = []string{name}
+		assert.Equal(t, expected, invokedAsNotifySend(), name)
+	}
+}
+
+func TestRootCommand_Urgency(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		mockSetup func(*MockBeeper)
+	}{
+		{
+			name: "low urgency is a silent notification",
+			args: []string{"-u", "low", "Title", "Message"},
+			mockSetup: func(m *MockBeeper) {
+				m.On("Notify", "Title", "Message", "").Return(nil).Once()
+			},
+		},
+		{
+			name: "normal urgency is a silent notification",
+			args: []string{"--urgency", "normal", "Title", "Message"},
+			mockSetup: func(m *MockBeeper) {
+				m.On("Notify", "Title", "Message", "").Return(nil).Once()
+			},
+		},
+		{
+			name: "critical urgency is an alert",
+			args: []string{"--urgency", "critical", "Title", "Message"},
+			mockSetup: func(m *MockBeeper) {
+				m.On("Alert", "Title", "Message", "").Return(nil).Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBeeper := setupMockBeeper(t)
+			mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+			tt.mockSetup(mockBeeper)
+
+			_, err := executeCommand(tt.args)
+
+			assert.NoError(t, err)
+			mockBeeper.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRootCommand_InvalidUrgency(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	_, err := executeCommand([]string{"--urgency", "urgent", "Title"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid urgency: urgent")
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestDaemonCommand_RequiresMode(t *testing.T) {
+	setupMockBeeper(t)
+
+	_, err := executeCommand([]string{"daemon"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no daemon mode selected")
+}
+
+// runtimeDir returns a runtime directory for the daemon socket. It doesn't
+// exist yet, so the daemon creates it private like a real one.
+func runtimeDir(tb testing.TB) string {
+	return filepath.Join(tb.TempDir(), "runtime")
+}
+
+// startDaemon serves the socket the command line hands notifications over
+// to, delivering them through b
+func startDaemon(tb testing.TB, b notify.Beeper, backend string) {
+	listener, err := daemon.ListenSocket(daemon.SocketPath())
+	require.NoError(tb, err)
+	tb.Cleanup(func() { listener.Close() })
+
+	go daemon.NewSocketServer(b, backend).Serve(listener)
+}
+
+func TestRootCommand_Daemon(t *testing.T) {
+	setupMockBeeper(t)
+	served := notify.NewFakeBeeper("")
+	startDaemon(t, served, "fake")
+
+	output, err := executeCommand([]string{"--backend", "fake", "--print-id", "--app-name", "CI", "Build", "Done"})
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", output)
+
+	sent := served.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "CI", sent[0].AppName)
+	assert.Equal(t, "Build", sent[0].Title)
+	assert.Equal(t, "Done", sent[0].Message)
+
+	// The command's own backend is left alone
+	direct, ok := notify.GetBeeper().(*notify.FakeBeeper)
+	require.True(t, ok)
+	assert.Empty(t, direct.Sent())
+}
+
+func TestRootCommand_DaemonFallback(t *testing.T) {
+	// The daemon delivers through another backend, so the command does it
+	// itself
+	mockBeeper := setupMockBeeper(t)
+	served := notify.NewFakeBeeper("")
+	startDaemon(t, served, "fake")
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Notify", "Build", "Done", "").Return(nil).Once()
+
+	_, err := executeCommand([]string{"Build", "Done"})
+
+	require.NoError(t, err)
+	assert.Empty(t, served.Sent())
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_DaemonError(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	served := new(MockBeeper)
+	startDaemon(t, served, "")
+
+	served.On("SetAppName", "wsl-notify-send").Once()
+	served.On("Alert", "Build", "Failed", "").Return(errors.New("toast failed")).Once()
+
+	_, err := executeCommand([]string{"--alert", "Build", "Failed"})
+
+	assert.ErrorContains(t, err, "toast failed")
+	assert.Equal(t, exitcode.NotificationFailed, exitcode.Of(err))
+	served.AssertExpectations(t)
+	mockBeeper.AssertExpectations(t)
+}
+
+// The benchmarks compare a whole command delivering directly with one
+// handing over to the daemon, through the fake backend. The direct path
+// also creates the backend each time, as every new process does.
+
+func BenchmarkRootCommand_Direct(b *testing.B) {
+	b.Setenv("XDG_CONFIG_HOME", b.TempDir())
+	b.Setenv("XDG_RUNTIME_DIR", runtimeDir(b))
+	b.Cleanup(func() { notify.SetBeeper(notify.NewDefaultBeeper()) })
+
+	for i := 0; i < b.N; i++ {
+		if _, err := executeCommand([]string{"--backend", "fake", "Build", "Done"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRootCommand_Daemon(b *testing.B) {
+	b.Setenv("XDG_CONFIG_HOME", b.TempDir())
+	b.Setenv("XDG_RUNTIME_DIR", runtimeDir(b))
+	b.Cleanup(func() { notify.SetBeeper(notify.NewDefaultBeeper()) })
+	startDaemon(b, notify.NewFakeBeeper(""), "fake")
+
+	for i := 0; i < b.N; i++ {
+		if _, err := executeCommand([]string{"--backend", "fake", "Build", "Done"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestServeCommand_Errors(t *testing.T) {
+	setupMockBeeper(t)
+
+	_, err := executeCommand([]string{"serve"})
+	assert.ErrorContains(t, err, "no server mode selected")
+	assert.Equal(t, exitcode.InvalidArgs, exitcode.Of(err))
+
+	_, err = executeCommand([]string{"serve", "--http", "localhost:not-a-port"})
+	assert.ErrorContains(t, err, "cannot listen on localhost:not-a-port")
+	assert.Equal(t, exitcode.InvalidArgs, exitcode.Of(err))
+}
+
+func TestServeCommand_Token(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(empty, []byte(" \n"), 0o600))
+
+	tests := []struct {
+		name string
+		args []string
+		msg  string
+	}{
+		{"all interfaces", []string{"serve", "--http", ":8765"}, ":8765 is not a loopback address, use --token-file"},
+		{"lan address", []string{"serve", "--http", "192.168.1.10:8765"}, "use --token-file"},
+		{"missing token file", []string{"serve", "--http", ":8765", "--token-file", filepath.Join(dir, "missing")}, "cannot read token"},
+		{"empty token file", []string{"serve", "--http", ":8765", "--token-file", empty}, "is empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupMockBeeper(t)
+
+			_, err := executeCommand(tt.args)
+
+			assert.ErrorContains(t, err, tt.msg)
+			assert.Equal(t, exitcode.InvalidArgs, exitcode.Of(err))
+		})
+	}
+
+	path := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(path, []byte("s3cret\n"), 0o600))
+	token, err := readToken(path)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", token)
+}
+
+func TestRootCommand_Backend(t *testing.T) {
+	setupMockBeeper(t)
+
+	backendBeeper := new(MockBeeper)
+	notify.RegisterBackend(notify.Backend{
+		Name:        "cmd-test",
+		Description: "Test backend",
+		Available:   func() (bool, string) { return true, "always" },
+		New:         func() (notify.Beeper, error) { return backendBeeper, nil },
+	})
+
+	backendBeeper.On("SetAppName", "wsl-notify-send").Once()
+	backendBeeper.On("Notify", "Title", "Message", "").Return(nil).Once()
+
+	_, err := executeCommand([]string{"--backend", "cmd-test", "Title", "Message"})
+
+	assert.NoError(t, err)
+	backendBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_PowerShellHostBackend(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake PowerShell needs sh")
+	}
+	setupMockBeeper(t)
+
+	// A stand-in that acknowledges every script without showing anything
+	shell := filepath.Join(t.TempDir(), "powershell.exe")
+	script := "#!/bin/sh\nwhile IFS= read -r line; do\n" +
+		"  seq=$(echo \"$line\" | sed -n \"s/^.ack = '@@wsl-notify-send \\([0-9]*\\) '.*/\\1/p\")\n" +
+		"  echo \"@@wsl-notify-send $seq ok\"\ndone\n"
+	require.NoError(t, os.WriteFile(shell, []byte(script), 0o755))
+	t.Setenv(notify.PowerShellEnv, shell)
+
+	_, err := executeCommand([]string{"--backend", "powershell-host", "Title", "Message"})
+
+	assert.NoError(t, err)
+}
+
+func TestRootCommand_UnknownBackend(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	_, err := executeCommand([]string{"--backend", "nope", "Title"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid configuration")
+	assert.Contains(t, err.Error(), "unknown backend: nope")
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestBackendsCommand(t *testing.T) {
+	setupMockBeeper(t)
+
+	output, err := executeCommand([]string{"backends"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "NAME")
+	assert.Contains(t, output, "auto")
+	assert.Contains(t, output, "beeep")
+	assert.Contains(t, output, "dbus")
+}
+
+func TestRootCommand_ErrorKinds(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		mockSetup func(*MockBeeper)
+		kind      error
+	}{
+		{
+			name: "missing title",
+			args: []string{},
+			kind: config.ErrInvalidArgs,
+		},
+		{
+			name: "unknown flag",
+			args: []string{"--no-such-flag", "Title"},
+			kind: config.ErrInvalidArgs,
+		},
+		{
+			name: "invalid configuration",
+			args: []string{"--alert", "--beep"},
+			kind: config.ErrInvalidConfig,
+		},
+		{
+			name: "missing icon file",
+			args: []string{"--icon", "/nonexistent/icon.png", "Title"},
+			kind: notify.ErrIconUnreadable,
+		},
+		{
+			name: "backend failure",
+			args: []string{"Title"},
+			mockSetup: func(m *MockBeeper) {
+				m.On("SetAppName", "wsl-notify-send").Once()
+				m.On("Notify", "Title", "", "").Return(errors.New("toast failed")).Once()
+			},
+			kind: notify.ErrBackendFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBeeper := setupMockBeeper(t)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockBeeper)
+			}
+
+			_, err := executeCommand(tt.args)
+
+			assert.ErrorIs(t, err, tt.kind)
+			mockBeeper.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRootCommand_ConfigFiles(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	// User config sets app name and icon
+	userDir := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "wsl-notify-send")
+	require.NoError(t, os.MkdirAll(userDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(userDir, "config.yaml"),
+		[]byte("app-name: User App\nicon: warning\nurgency: low\n"), 0644))
+
+	// Project config overrides the app name, found from a subdirectory
+	project := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(project, ".wsl-notify-send.yml"),
+		[]byte("app-name: Project App\n"), 0644))
+	sub := filepath.Join(project, "src", "pkg")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+	t.Chdir(sub)
+
+	// Command-line flags win over both
+	mockBeeper.On("SetAppName", "Project App").Once()
+	mockBeeper.On("Notify", "Title", "Message", "error").Return(nil).Once()
+
+	_, err := executeCommand([]string{"--icon", "error", "Title", "Message"})
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_ConfigFileValidation(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	project := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(project, ".wsl-notify-send.yaml"),
+		[]byte("freq: -5\nbeep: true\n"), 0644))
+	t.Chdir(project)
+
+	_, err := executeCommand([]string{"--beep"})
+
+	assert.ErrorIs(t, err, config.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), "frequency must be positive")
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_ConfigFileUnknownOption(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	project := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(project, ".wsl-notify-send.yaml"),
+		[]byte("colour: red\n"), 0644))
+	t.Chdir(project)
+
+	_, err := executeCommand([]string{"Title"})
+
+	assert.ErrorIs(t, err, config.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), `unknown option "colour"`)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_EnvironmentVariables(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	t.Setenv("WSL_NOTIFY_SEND_APP_NAME", "CI Runner")
+	t.Setenv("WSL_NOTIFY_SEND_ICON", "warning")
+
+	// Flags still win over the environment
+	mockBeeper.On("SetAppName", "CI Runner").Once()
+	mockBeeper.On("Notify", "Title", "Message", "error").Return(nil).Once()
+
+	_, err := executeCommand([]string{"-i", "error", "Title", "Message"})
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_EnvironmentOverridesConfigFile(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	project := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(project, ".wsl-notify-send.yaml"),
+		[]byte("app-name: Project App\nfreq: 900\n"), 0644))
+	t.Chdir(project)
+	t.Setenv("WSL_NOTIFY_SEND_FREQ", "1200")
+	t.Setenv("WSL_NOTIFY_SEND_DURATION", "250")
+
+	mockBeeper.On("Beep", 1200.0, 250).Return(nil).Once()
+
+	_, err := executeCommand([]string{"--beep"})
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_EnvironmentInvalidValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		variable string
+		value    string
+		errorMsg string
+	}{
+		{
+			name:     "unparsable frequency",
+			variable: "WSL_NOTIFY_SEND_FREQ",
+			value:    "loud",
+			errorMsg: "invalid value for freq in WSL_NOTIFY_SEND_FREQ",
+		},
+		{
+			name:     "negative duration",
+			variable: "WSL_NOTIFY_SEND_DURATION",
+			value:    "-10",
+			errorMsg: "duration must be positive (from WSL_NOTIFY_SEND_DURATION)",
+		},
+		{
+			name:     "unknown urgency",
+			variable: "WSL_NOTIFY_SEND_URGENCY",
+			value:    "urgent",
+			errorMsg: "invalid urgency: urgent (supported: low, normal, critical) (from WSL_NOTIFY_SEND_URGENCY)",
+		},
+		{
+			name:     "unknown backend",
+			variable: "WSL_NOTIFY_SEND_BACKEND",
+			value:    "carrier-pigeon",
+			errorMsg: "unknown backend: carrier-pigeon (from WSL_NOTIFY_SEND_BACKEND)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBeeper := setupMockBeeper(t)
+			t.Setenv(tt.variable, tt.value)
+
+			_, err := executeCommand([]string{"--beep"})
+
+			assert.ErrorIs(t, err, config.ErrInvalidConfig)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+			mockBeeper.AssertExpectations(t)
+		})
+	}
+}
+
+// writeProjectConfig writes a project config file to a new working directory
+func writeProjectConfig(t *testing.T, content string) {
+	project := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(project, ".wsl-notify-send.yaml"), []byte(content), 0644))
+	t.Chdir(project)
+}
+
+const profilesConfig = `
+app-name: Dev Box
+profiles:
+  build-failed:
+    mode: alert
+    icon: error
+    app-name: CI
+    urgency: critical
+  deploy-done:
+    icon: info
+`
+
+func TestRootCommand_Profile(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	writeProjectConfig(t, profilesConfig)
+
+	mockBeeper.On("SetAppName", "CI").Once()
+	mockBeeper.On("Alert", "Build", "Tests failed", "error").Return(nil).Once()
+
+	_, err := executeCommand([]string{"--profile", "build-failed", "Build", "Tests failed"})
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_ProfileFlagsWin(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	writeProjectConfig(t, profilesConfig)
+	t.Setenv("WSL_NOTIFY_SEND_PROFILE", "deploy-done")
+
+	// The profile comes from the environment, the icon from the command line
+	mockBeeper.On("SetAppName", "Dev Box").Once()
+	mockBeeper.On("Notify", "Deploy", "", "warning").Return(nil).Once()
+
+	_, err := executeCommand([]string{"-i", "warning", "Deploy"})
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_UnknownProfile(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	writeProjectConfig(t, profilesConfig)
+
+	_, err := executeCommand([]string{"--profile", "nope", "Title"})
+
+	assert.ErrorIs(t, err, config.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), "unknown profile: nope (available: build-failed, deploy-done)")
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestProfilesCommand(t *testing.T) {
+	setupMockBeeper(t)
+	writeProjectConfig(t, profilesConfig)
+
+	for _, args := range [][]string{{"profiles"}, {"profiles", "list"}} {
+		output, err := executeCommand(args)
+
+		assert.NoError(t, err)
+		assert.Contains(t, output, "NAME")
+		assert.Contains(t, output, "build-failed")
+		assert.Contains(t, output, "deploy-done")
+		assert.Contains(t, output, ".wsl-notify-send.yaml")
+	}
+}
+
+func TestProfilesCommand_NoProfiles(t *testing.T) {
+	setupMockBeeper(t)
+	t.Chdir(t.TempDir())
+
+	output, err := executeCommand([]string{"profiles", "list"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "No profiles defined")
+}
+
+func TestProfilesShowCommand(t *testing.T) {
+	setupMockBeeper(t)
+	writeProjectConfig(t, profilesConfig)
+
+	output, err := executeCommand([]string{"profiles", "show", "build-failed"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "alert: true")
+	assert.Contains(t, output, "app-name: CI")
+	assert.Contains(t, output, "icon: error")
+	assert.Contains(t, output, "urgency: critical")
+
+	_, err = executeCommand([]string{"profiles", "show", "nope"})
+	assert.ErrorIs(t, err, config.ErrInvalidArgs)
+	assert.Contains(t, err.Error(), "unknown profile: nope")
+}
+
+func TestShellInitCommand(t *testing.T) {
+	setupMockBeeper(t)
+
+	output, err := executeCommand([]string{"shell-init", "zsh", "--threshold", "45s", "--ignore", "k9s"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "add-zsh-hook precmd")
+	assert.Contains(t, output, "45s or more")
+	assert.Contains(t, output, "shell-done --ignore 'k9s'")
+}
+
+func TestShellInitCommand_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{"no shell", []string{"shell-init"}, "requires a shell: bash, zsh, fish"},
+		{"unsupported shell", []string{"shell-init", "tcsh"}, "unsupported shell: tcsh"},
+		{"bad threshold", []string{"shell-init", "bash", "--threshold", "0s"}, "--threshold must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupMockBeeper(t)
+
+			_, err := executeCommand(tt.args)
+
+			assert.ErrorIs(t, err, config.ErrInvalidArgs)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
+
+func TestShellDoneCommand(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Twice()
+	mockBeeper.On("Notify", "Command succeeded", "make build\nexit status 0 after 42s", "").Return(nil).Once()
+	mockBeeper.On("Alert", "Command failed", "go test ./...\nexit status 1 after 1m30s", "").Return(nil).Once()
+
+	_, err := executeCommand([]string{"shell-done", "--status", "0", "--duration", "42s", "--", "make build"})
+	assert.NoError(t, err)
+
+	_, err = executeCommand([]string{"shell-done", "--status", "1", "--duration", "90s", "--", "go test ./..."})
+	assert.NoError(t, err)
+
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestShellDoneCommand_Ignored(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	_, err := executeCommand([]string{"shell-done", "--status", "0", "--duration", "20m", "--", "vim main.go"})
+	assert.NoError(t, err)
+
+	_, err = executeCommand([]string{"shell-done", "--ignore", "k9s", "--duration", "20m", "--", "k9s"})
+	assert.NoError(t, err)
+
+	mockBeeper.AssertExpectations(t)
+}
+
+// executeCommandWithStdin runs the command with stdin read from input
+func executeCommandWithStdin(args []string, input string) (string, error) {
+	rootCmd.SetIn(strings.NewReader(input))
+	defer rootCmd.SetIn(nil)
+	return executeCommand(args)
+}
+
+func TestRootCommand_JSON(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	mockBeeper.On("SetAppName", "CI").Once()
+	mockBeeper.On("Alert", "Build \"api\"", "Tests failed:\n  parse_test.go", "").Return(nil).Once()
+
+	payload := `{"title": "Build \"api\"", "message": "Tests failed:\n  parse_test.go", "app_name": "CI", "alert": true}`
+	_, err := executeCommandWithStdin([]string{"--json"}, payload)
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_JSONErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		errorMsg string
+	}{
+		{"arguments", []string{"--json", "Title"}, `{"title": "x"}`, "cannot use title or message arguments"},
+		{"both modes", []string{"--json", "--jsonl"}, `{"title": "x"}`, "cannot use both --json and --jsonl"},
+		{"no title", []string{"--json"}, `{"message": "x"}`, "notification has no title"},
+		{"unknown field", []string{"--json"}, `{"title": "x", "body": "y"}`, `unknown field "body"`},
+		{"invalid value", []string{"--json"}, `{"title": "x", "urgency": "urgent"}`, "invalid urgency: urgent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupMockBeeper(t)
+
+			_, err := executeCommandWithStdin(tt.args, tt.input)
+
+			assert.ErrorContains(t, err, tt.errorMsg)
+			assert.Equal(t, 2, exitcode.Of(err))
+		})
+	}
+}
+
+func TestRootCommand_JSONL(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Twice()
+	mockBeeper.On("Notify", "First", "", "").Return(nil).Once()
+	mockBeeper.On("Notify", "Third", "three", "").Return(nil).Once()
+
+	input := `{"title": "First"}
+
+{"title": "Second", "urgency": "urgent"}
+{"title": "Third", "message": "three"}
+not json
+`
+	output, err := executeCommandWithStdin([]string{"--jsonl"}, input)
+
+	// Every line is tried, failures are reported with their line number and
+	// the exit code is that of the first failure
+	assert.Equal(t, 2, exitcode.Of(err))
+	assert.Contains(t, output, "Error: line 3: invalid configuration: invalid urgency: urgent")
+	assert.Contains(t, output, "Error: line 5: cannot parse notification")
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_JSONLQuiet(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	output, err := executeCommandWithStdin([]string{"--jsonl", "--quiet"}, "{}\n")
+
+	assert.Equal(t, 2, exitcode.Of(err))
+	assert.Empty(t, output)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_MessageFromStdin(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Notify", "Recent commits", "abc123 Fix parser\ndef456 Add tests", "").Return(nil).Once()
+
+	_, err := executeCommandWithStdin([]string{"Recent commits", "-"}, "\x1b[33mabc123\x1b[m Fix parser  \ndef456 Add tests\n\n")
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_BodyFile(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	path := filepath.Join(t.TempDir(), "body.txt")
+	require.NoError(t, os.WriteFile(path, []byte("line one\nline two\n"), 0644))
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Notify", "Report", "line one\nline two", "").Return(nil).Once()
+
+	_, err := executeCommand([]string{"--body-file", path, "Report"})
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_BodyFileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{"message argument", []string{"--body-file", "body.txt", "Title", "Message"}, "cannot use both a message argument and --body-file"},
+		{"missing file", []string{"--body-file", filepath.Join(t.TempDir(), "missing.txt"), "Title"}, "cannot read body file"},
+		{"json", []string{"--body-file", "body.txt", "--json"}, "cannot use --body-file with --json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBeeper := setupMockBeeper(t)
+
+			_, err := executeCommandWithStdin(tt.args, `{"title": "x"}`)
+
+			assert.ErrorContains(t, err, tt.errorMsg)
+			assert.Equal(t, 2, exitcode.Of(err))
+			mockBeeper.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRootCommand_Templates(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Notify", "Ubuntu: Build", "DONE in 1m30s", "").Return(nil).Once()
+
+	_, err := executeCommand([]string{
+		"--title-template", "{{.Env.WSL_DISTRO_NAME}}: {{.Title}}",
+		"--template", `{{upper .Message}} in {{duration "90s"}}`,
+		"Build", "done",
+	})
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_TitleTemplateWithoutTitle(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Notify", "Ubuntu", "", "").Return(nil).Once()
+
+	_, err := executeCommand([]string{"--title-template", "{{.Env.WSL_DISTRO_NAME}}"})
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_JSONTemplates(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Twice()
+	mockBeeper.On("Notify", "Deployed api", "3 replicas, region unknown", "").Return(nil).Once()
+	mockBeeper.On("Notify", "Deployed web", "1 replicas, region eu", "").Return(nil).Once()
+
+	input := `{"service": "api", "replicas": 3}
+{"service": "web", "replicas": 1, "region": "eu"}
+`
+	_, err := executeCommandWithStdin([]string{
+		"--jsonl",
+		"--title-template", "Deployed {{.Input.service}}",
+		"--template", `{{.Input.replicas}} replicas, region {{default "unknown" .Input.region}}`,
+	}, input)
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_TemplateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{"parse", []string{"--template", "{{.Message", "Title"}, "invalid message template"},
+		{"render", []string{"--title-template", "{{.Nope}}", "Title"}, "cannot render title template"},
+		{"function", []string{"--template", `{{duration "soon"}}`, "Title"}, "invalid duration: soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBeeper := setupMockBeeper(t)
+
+			_, err := executeCommand(tt.args)
+
+			assert.ErrorContains(t, err, tt.errorMsg)
+			assert.Equal(t, exitcode.TemplateError, exitcode.Of(err))
+			mockBeeper.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRootCommand_RichContentFolded(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	// The mock backend can't show rich content, so it ends up in the message
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Notify", "Build", "Done\n3 passed\n1 skipped\nvia CI", "").Return(nil).Once()
+
+	_, err := executeCommand([]string{
+		"--line", "3 passed", "--line", "1 skipped",
+		"--attribution", "via CI",
+		"--timestamp", "2026-10-16T09:30:00Z",
+		"Build", "Done",
+	})
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_InvalidTimestamp(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	_, err := executeCommand([]string{"--timestamp", "tomorrow", "Build"})
+
+	assert.ErrorContains(t, err, "invalid timestamp: tomorrow")
+	assert.ErrorIs(t, err, config.ErrInvalidConfig)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_Progress(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Notify", "Backup", "Photos\nCopying: 42% (3/7)", "").Return(nil).Once()
+
+	_, err := executeCommand([]string{
+		"--tag", "backup", "--group", "nightly",
+		"--progress", "42", "--progress-label", "Copying", "--progress-status", "3/7",
+		"Backup", "Photos",
+	})
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRootCommand_InvalidProgress(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	t.Setenv("WSL_NOTIFY_SEND_PROGRESS", "150")
+
+	_, err := executeCommand([]string{"Backup"})
+
+	assert.ErrorContains(t, err, "invalid progress: 150 (expected 0 to 100) (from WSL_NOTIFY_SEND_PROGRESS)")
+	assert.ErrorIs(t, err, config.ErrInvalidConfig)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestREADMEListsEnvVars(t *testing.T) {
+	readme, err := os.ReadFile(filepath.Join("..", "README.md"))
+	require.NoError(t, err)
+
+	flags := pflag.NewFlagSet("env", pflag.ContinueOnError)
+	flags.AddFlagSet(rootCmd.Flags())
+	flags.AddFlagSet(rootCmd.PersistentFlags())
+
+	for _, flag := range config.EnvFlags(flags) {
+		row := "| `" + config.EnvVar(flag.Name) + "` | `--" + flag.Name + "` |"
+		assert.Contains(t, string(readme), row)
+	}
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/humanize"
+	"wsl-notify-send/internal/runner"
+
+	"github.com/spf13/cobra"
+)
+
+// When run sends its notification
+const (
+	notifyAlways  = "always"
+	notifySuccess = "success"
+	notifyFailure = "failure"
+)
+
+// Streams the failure tail is taken from
+const (
+	tailStdout = "stdout"
+	tailStderr = "stderr"
+	tailBoth   = "both"
+)
+
+var (
+	runTitle    string
+	runOn       string
+	runTail     int
+	runTailFrom string
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [flags] -- command [args...]",
+	Short: "Run a command and notify when it finishes",
+	Long: `Run a command, then send a notification with its command line, exit status and
+elapsed time. Failures are sent as alerts that end with the last lines of the
+command's output. Stdio is passed through, signals are forwarded to the command
+and wsl-notify-send exits with the command's own exit code.
+
+The notification uses the icon, app name and other options from the config
+files, profile and environment.
+
+Examples:
+  wsl-notify-send run -- go test ./...
+  wsl-notify-send run --title "Nightly build" -- make release
+  wsl-notify-send run --on failure -- ./deploy.sh
+  wsl-notify-send run --tail 10 --tail-from stderr -- cargo build`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return exitcode.Mark(config.ErrInvalidArgs, errors.New("requires a command to run"))
+		}
+		return nil
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch runOn {
+		case notifyAlways, notifySuccess, notifyFailure:
+		default:
+			return exitcode.Mark(config.ErrInvalidArgs, fmt.Errorf("invalid --on value: %s (supported: always, success, failure)", runOn))
+		}
+
+		switch runTailFrom {
+		case tailStdout, tailStderr, tailBoth:
+		default:
+			return exitcode.Mark(config.ErrInvalidArgs, fmt.Errorf("invalid --tail-from value: %s (supported: stdout, stderr, both)", runTailFrom))
+		}
+
+		if runTail < 0 {
+			return exitcode.Mark(config.ErrInvalidArgs, errors.New("--tail must be 0 or greater"))
+		}
+
+		// Check the notification settings before running anything
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", optionSources.Annotate(err))
+		}
+		opts, err := cfg.NotifyOptions()
+		if err != nil {
+			return exitcode.Mark(config.ErrInvalidConfig, fmt.Errorf("invalid configuration: %w", err))
+		}
+
+		// Only capture output when a failure notification could show it
+		stdout, stderr := cmd.OutOrStdout(), cmd.ErrOrStderr()
+		var tail *runner.Tail
+		if runTail > 0 && runOn != notifySuccess {
+			tail = runner.NewTail(runTail)
+			if runTailFrom != tailStderr {
+				stdout = io.MultiWriter(stdout, tail.Writer())
+			}
+			if runTailFrom != tailStdout {
+				stderr = io.MultiWriter(stderr, tail.Writer())
+			}
+		}
+
+		result, err := runner.Run(args, cmd.InOrStdin(), stdout, stderr)
+		if err != nil {
+			return err
+		}
+
+		if shouldNotify(result) {
+			title, message := runNotification(result, tail)
+
+			runCfg := cfg
+			runCfg.AlertMode = !result.Success()
+			runCfg.BeepMode = false
+			_, err = deliver(runCfg, title, message, opts)
+
+			// The command's exit code matters more than the notification
+			if err != nil && !cfg.Quiet {
+				cmd.PrintErrf("Error: %v\n", err)
+			}
+		}
+
+		if !result.Success() {
+			return exitcode.Status(result.ExitCode)
+		}
+		return nil
+	},
+}
+
+func shouldNotify(result runner.Result) bool {
+	switch runOn {
+	case notifySuccess:
+		return result.Success()
+	case notifyFailure:
+		return !result.Success()
+	default:
+		return true
+	}
+}
+
+// runNotification returns the title and message reporting a finished
+// command. Failures end with the tail of the output, if one was kept.
+func runNotification(result runner.Result, tail *runner.Tail) (string, string) {
+	title, message := finishedNotification(runTitle, result.CommandLine(), result.ExitCode, result.Elapsed)
+
+	if !result.Success() && tail != nil {
+		if lines := tail.Lines(); len(lines) > 0 {
+			message += "\n\n" + strings.Join(lines, "\n")
+		}
+	}
+
+	return title, message
+}
+
+func init() {
+	// Everything after the command name belongs to the command
+	runCmd.Flags().SetInterspersed(false)
+
+	runCmd.Flags().StringVar(&runTitle, "title", "", "Notification title (default \"Command succeeded\" or \"Command failed\")")
+	runCmd.Flags().StringVar(&runOn, "on", notifyAlways, "When to notify: always, success or failure")
+	runCmd.Flags().IntVar(&runTail, "tail", 5, "Number of output lines to show when the command fails (0 to disable)")
+	runCmd.Flags().StringVar(&runTailFrom, "tail-from", tailBoth, "Output the tail is taken from: stdout, stderr or both")
+
+	rootCmd.AddCommand(runCmd)
+}
+
+// finishedNotification returns the title and message reporting that a
+// command line exited with exitCode after elapsed. An empty title is chosen
+// from the outcome.
+func finishedNotification(title, commandLine string, exitCode int, elapsed time.Duration) (string, string) {
+	if title == "" {
+		if exitCode == 0 {
+			title = "Command succeeded"
+		} else {
+			title = "Command failed"
+		}
+	}
+
+	message := fmt.Sprintf("%s\nexit status %d after %s", commandLine, exitCode, humanize.Duration(elapsed))
+	return title, message
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/runner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestHelperProcess isn't a real test, it's the command wrapped by the run
+// tests: it prints its arguments to stdout, a colored error to stderr and
+// exits with the last argument
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	args = args[1:]
+
+	fmt.Println(strings.Join(args, " "))
+	fmt.Fprintln(os.Stderr, "\x1b[31merror:\x1b[0m step "+args[0]+" failed")
+
+	code, _ := strconv.Atoi(args[len(args)-1])
+	os.Exit(code)
+}
+
+// runArgs returns the arguments of a run subcommand wrapping TestHelperProcess
+func runArgs(flags []string, exitCode string) ([]string, []string) {
+	command := []string{os.Args[0], "-test.run=TestHelperProcess", "--", "build", exitCode}
+	args := append([]string{"run"}, flags...)
+	args = append(args, "--")
+	return append(args, command...), command
+}
+
+func TestRunCommand_Success(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+
+	args, command := runArgs(nil, "0")
+	prefix := runner.CommandLine(command) + "\nexit status 0 after "
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Notify", "Command succeeded", mock.MatchedBy(func(message string) bool {
+		return strings.HasPrefix(message, prefix)
+	}), "").Return(nil).Once()
+
+	output, err := executeCommand(args)
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "build 0")
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRunCommand_Failure(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	t.Setenv("WSL_NOTIFY_SEND_ICON", "error")
+
+	args, _ := runArgs([]string{"--title", "Nightly"}, "3")
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Alert", "Nightly", mock.MatchedBy(func(message string) bool {
+		return strings.Contains(message, "exit status 3 after ")
+	}), "error").Return(nil).Once()
+
+	_, err := executeCommand(args)
+
+	assert.Equal(t, exitcode.Status(3), err)
+	assert.Equal(t, 3, exitcode.Of(err))
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRunCommand_On(t *testing.T) {
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+
+	tests := []struct {
+		on       string
+		exitCode string
+		notified bool
+	}{
+		{notifyFailure, "0", false},
+		{notifyFailure, "1", true},
+		{notifySuccess, "0", true},
+		{notifySuccess, "1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.on+" "+tt.exitCode, func(t *testing.T) {
+			mockBeeper := setupMockBeeper(t)
+			args, _ := runArgs([]string{"--on", tt.on}, tt.exitCode)
+
+			if tt.notified {
+				mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+				mockBeeper.On("Notify", mock.Anything, mock.Anything, "").Return(nil).Maybe()
+				mockBeeper.On("Alert", mock.Anything, mock.Anything, "").Return(nil).Maybe()
+			}
+
+			_, err := executeCommand(args)
+
+			code, _ := strconv.Atoi(tt.exitCode)
+			assert.Equal(t, code, exitcode.Of(err))
+			mockBeeper.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRunCommand_NotificationFailureKeepsExitCode(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+
+	args, _ := runArgs(nil, "0")
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Notify", "Command succeeded", mock.Anything, "").Return(assert.AnError).Once()
+
+	output, err := executeCommand(args)
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "failed to send notification")
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRunCommand_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"no command", []string{"run"}, exitcode.InvalidArgs},
+		{"invalid --on", []string{"run", "--on", "sometimes", "--", "true"}, exitcode.InvalidArgs},
+		{"invalid --tail-from", []string{"run", "--tail-from", "stdin", "--", "true"}, exitcode.InvalidArgs},
+		{"negative --tail", []string{"run", "--tail", "-1", "--", "true"}, exitcode.InvalidArgs},
+		{"command not found", []string{"run", "--", "wsl-notify-send-no-such-command"}, exitcode.CommandNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBeeper := setupMockBeeper(t)
+
+			_, err := executeCommand(tt.args)
+
+			assert.Error(t, err)
+			assert.Equal(t, tt.code, exitcode.Of(err))
+			mockBeeper.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRunCommand_FailureTail(t *testing.T) {
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+
+	tests := []struct {
+		name     string
+		flags    []string
+		included []string
+		excluded []string
+	}{
+		// The order of lines from different streams isn't deterministic
+		{"both", nil, []string{"\nbuild 2", "\nerror: step build failed"}, nil},
+		{"stdout", []string{"--tail-from", "stdout"}, []string{"\n\nbuild 2"}, []string{"error: step"}},
+		{"stderr", []string{"--tail-from", "stderr"}, []string{"\n\nerror: step build failed"}, []string{"\nbuild 2"}},
+		{"stdout one line", []string{"--tail", "1", "--tail-from", "stdout"}, []string{"\n\nbuild 2"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBeeper := setupMockBeeper(t)
+			args, _ := runArgs(tt.flags, "2")
+
+			var message string
+			mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+			mockBeeper.On("Alert", "Command failed", mock.Anything, "").Run(func(args mock.Arguments) {
+				message = args.String(1)
+			}).Return(nil).Once()
+
+			output, err := executeCommand(args)
+
+			assert.Equal(t, 2, exitcode.Of(err))
+			assert.Contains(t, output, "\x1b[31merror:", "output is passed through unchanged")
+			for _, text := range tt.included {
+				assert.Contains(t, message, text)
+			}
+			for _, text := range tt.excluded {
+				assert.NotContains(t, message, text)
+			}
+			mockBeeper.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRunCommand_NoTail(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+
+	args, _ := runArgs([]string{"--tail", "0"}, "2")
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Alert", "Command failed", mock.MatchedBy(func(message string) bool {
+		return !strings.Contains(message, "error: step")
+	}), "").Return(nil).Once()
+
+	_, err := executeCommand(args)
+
+	assert.Equal(t, 2, exitcode.Of(err))
+	mockBeeper.AssertExpectations(t)
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/daemon"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/notify"
+
+	"github.com/spf13/cobra"
+)
+
+// shutdownTimeout bounds how long requests in flight get to finish
+const shutdownTimeout = 5 * time.Second
+
+var (
+	serveHTTP      string
+	serveTokenFile string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve notifications over a local HTTP API",
+	Long: `Serve notifications over a local HTTP API, so containers, browser extensions
+and Windows-side tools can raise them through one long-lived process.
+
+POST /notify, /alert and /beep take a JSON object with the same fields as
+--json, with Content-Type: application/json. Fields that are left out keep the
+values from the config files, profile and environment. Failures respond with
+the HTTP status matching the exit code, and the error and exit code in the
+JSON body.
+
+Requests addressed to another host than localhost or a loopback address
+are refused, so web pages can't reach the API by pointing a name at it.
+With --token-file every request must instead send the token in the file as
+"Authorization: Bearer TOKEN", and may use any host name. Listening on an
+address other than a loopback one, for containers to reach, needs a token.
+
+Examples:
+  wsl-notify-send serve --http 127.0.0.1:8765 &
+  curl -H 'Content-Type: application/json' -d '{"title": "Build", "message": "Done"}' http://127.0.0.1:8765/notify
+  wsl-notify-send serve --http 0.0.0.0:8765 --token-file ~/.config/wsl-notify-send/token &`,
+	Args:          cobra.NoArgs,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if serveHTTP == "" {
+			return exitcode.Mark(config.ErrInvalidArgs, errors.New("no server mode selected, use --http"))
+		}
+
+		// Every request starts from this configuration, so check it once
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", optionSources.Annotate(err))
+		}
+
+		token, err := readToken(serveTokenFile)
+		if err != nil {
+			return err
+		}
+		if token == "" && !daemon.LoopbackHost(serveHTTP) {
+			return exitcode.Mark(config.ErrInvalidArgs, fmt.Errorf("%s is not a loopback address, use --token-file so only clients with the token are served", serveHTTP))
+		}
+
+		listener, err := net.Listen("tcp", serveHTTP)
+		if err != nil {
+			return exitcode.Mark(config.ErrInvalidArgs, fmt.Errorf("cannot listen on %s: %w", serveHTTP, err))
+		}
+
+		api := daemon.NewHTTPServer(notify.GetBeeper(), cfg)
+		api.Token = token
+		server := &http.Server{
+			Handler:           api.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		errc := make(chan error, 1)
+		go func() {
+			errc <- server.Serve(listener)
+		}()
+		cmd.PrintErrf("Listening on http://%s\n", listener.Addr())
+
+		// Serve until interrupted
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sig)
+
+		select {
+		case err := <-errc:
+			return err
+		case <-sig:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return server.Shutdown(ctx)
+	},
+}
+
+// readToken reads the bearer token from path, or returns "" without one
+func readToken(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", exitcode.Mark(config.ErrInvalidArgs, fmt.Errorf("cannot read token: %w", err))
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", exitcode.Mark(config.ErrInvalidArgs, fmt.Errorf("token file %s is empty", path))
+	}
+	return token, nil
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveHTTP, "http", "", "Serve the HTTP API on this address, e.g. 127.0.0.1:8765")
+	serveCmd.Flags().StringVar(&serveTokenFile, "token-file", "", "Require the bearer token in this file, needed off loopback")
+
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/shell"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	shellThreshold time.Duration
+	shellIgnore    []string
+
+	shellDoneStatus   int
+	shellDoneDuration time.Duration
+)
+
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init bash|zsh|fish",
+	Short: "Print a snippet that notifies when interactive commands are slow",
+	Long: `Print a shell snippet that times every interactive command and sends a
+notification, with the command, its duration and exit status, when one takes
+longer than the threshold. Editors, pagers, REPLs and other interactive
+programs are never reported, but interpreters, shells and ssh are when they
+run a script or command.
+
+Add it to your shell's startup file:
+  bash:  eval "$(wsl-notify-send shell-init bash)"      in ~/.bashrc
+  zsh:   eval "$(wsl-notify-send shell-init zsh)"       in ~/.zshrc
+  fish:  wsl-notify-send shell-init fish | source       in ~/.config/fish/config.fish`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return exitcode.Mark(config.ErrInvalidArgs, fmt.Errorf("requires a shell: %s", strings.Join(shell.Shells, ", ")))
+		}
+		return nil
+	},
+	ValidArgs: shell.Shells,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if shellThreshold <= 0 {
+			return exitcode.Mark(config.ErrInvalidArgs, errors.New("--threshold must be positive"))
+		}
+
+		executable, err := os.Executable()
+		if err != nil {
+			executable = "wsl-notify-send"
+		}
+
+		snippet, err := shell.Snippet(args[0], shell.Options{
+			Executable: executable,
+			Threshold:  shellThreshold,
+			Ignore:     shellIgnore,
+		})
+		if err != nil {
+			return exitcode.Mark(config.ErrInvalidArgs, err)
+		}
+
+		cmd.Print(snippet)
+		return nil
+	},
+}
+
+// shellDoneCmd is called back by the shell-init snippets
+var shellDoneCmd = &cobra.Command{
+	Use:    "shell-done [flags] -- command",
+	Short:  "Report a finished interactive command",
+	Hidden: true,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return exitcode.Mark(config.ErrInvalidArgs, errors.New("requires the command line"))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if shell.Ignored(args[0], shellIgnore) {
+			return nil
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", optionSources.Annotate(err))
+		}
+		opts, err := cfg.NotifyOptions()
+		if err != nil {
+			return exitcode.Mark(config.ErrInvalidConfig, fmt.Errorf("invalid configuration: %w", err))
+		}
+
+		title, message := finishedNotification("", args[0], shellDoneStatus, shellDoneDuration)
+		shellCfg := cfg
+		shellCfg.AlertMode = shellDoneStatus != 0
+		shellCfg.BeepMode = false
+		_, err = deliver(shellCfg, title, message, opts)
+		return err
+	},
+}
+
+func init() {
+	shellInitCmd.Flags().DurationVar(&shellThreshold, "threshold", 10*time.Second, "Report commands that run at least this long")
+	shellInitCmd.Flags().StringSliceVar(&shellIgnore, "ignore", nil, "Programs to never report, on top of editors, pagers and REPLs")
+
+	shellDoneCmd.Flags().IntVar(&shellDoneStatus, "status", 0, "Exit status of the command")
+	shellDoneCmd.Flags().DurationVar(&shellDoneDuration, "duration", 0, "How long the command ran")
+	shellDoneCmd.Flags().StringSliceVar(&shellIgnore, "ignore", nil, "Programs to never report")
+
+	rootCmd.AddCommand(shellInitCmd)
+	rootCmd.AddCommand(shellDoneCmd)
+}
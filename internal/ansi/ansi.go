@@ -0,0 +1,13 @@
+// Package ansi removes terminal escape sequences from command output.
+package ansi
+
+import "regexp"
+
+// escape matches CSI and OSC terminal escape sequences and the other
+// two-character escapes
+var escape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// Strip removes the terminal escape sequences from s
+func Strip(s string) string {
+	return escape.ReplaceAllString(s, "")
+}
@@ -0,0 +1,28 @@
+package ansi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain", "hello", "hello"},
+		{"color", "\x1b[1;31merror\x1b[0m: failed", "error: failed"},
+		{"cursor", "\x1b[2K\x1b[1Gdone", "done"},
+		{"title", "\x1b]0;make\x07building", "building"},
+		{"title with st", "\x1b]2;make\x1b\\building", "building"},
+		{"two character", "\x1bMup", "up"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Strip(tt.input))
+		})
+	}
+}
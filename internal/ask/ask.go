@@ -0,0 +1,142 @@
+// Package ask asks yes/no questions, with notification buttons or on a
+// terminal.
+package ask
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"wsl-notify-send/internal/notify"
+)
+
+// The answers to a question, which are also the keys of its actions
+const (
+	Yes = "yes"
+	No  = "no"
+)
+
+// Question is a yes/no question and how it is presented
+type Question struct {
+	Title   string
+	Message string
+
+	// Labels of the yes and no buttons
+	YesLabel string
+	NoLabel  string
+
+	// Default is the answer when the question times out or is dismissed,
+	// or "" for none
+	Default string
+}
+
+// ParseAnswer checks that s is Yes, No or "" for no answer
+func ParseAnswer(s string) (string, error) {
+	switch s = strings.ToLower(s); s {
+	case Yes, No, "":
+		return s, nil
+	default:
+		return "", fmt.Errorf("invalid answer: %s (supported: yes, no)", s)
+	}
+}
+
+// Actions returns the notification buttons for the question
+func (q Question) Actions() []notify.Action {
+	return []notify.Action{{Key: Yes, Label: q.YesLabel}, {Key: No, Label: q.NoLabel}}
+}
+
+// Resolve turns the response to the notification into an answer. Clicking
+// the notification instead of a button doesn't answer, so it counts as
+// dismissing it. A question that times out or is dismissed gets the
+// default answer, or fails with notify.ErrTimeout or notify.ErrDismissed
+// when there is none.
+func (q Question) Resolve(action string, err error) (string, error) {
+	if err == nil {
+		if action == Yes || action == No {
+			return action, nil
+		}
+		err = notify.ErrDismissed
+	}
+
+	if q.Default != "" && (errors.Is(err, notify.ErrDismissed) || errors.Is(err, notify.ErrTimeout)) {
+		return q.Default, nil
+	}
+	return "", err
+}
+
+// Prompt asks the question on a terminal, writing to out and reading lines
+// from in until one answers it. The labels, y, yes, n and no are accepted
+// in any case, and an empty line picks the default. The end of the input
+// dismisses the question, and ctx ending times it out.
+func Prompt(ctx context.Context, in io.Reader, out io.Writer, q Question) (string, error) {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		fmt.Fprint(out, q.prompt())
+
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				fmt.Fprintln(out)
+				return q.Resolve("", notify.ErrDismissed)
+			}
+			if answer, ok := q.match(line); ok {
+				return answer, nil
+			}
+			fmt.Fprintf(out, "Please answer %s or %s.\n", q.YesLabel, q.NoLabel)
+		case <-ctx.Done():
+			fmt.Fprintln(out)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return q.Resolve("", notify.ErrTimeout)
+			}
+			return "", ctx.Err()
+		}
+	}
+}
+
+// prompt is the line the question is asked with
+func (q Question) prompt() string {
+	text := q.Title
+	if q.Message != "" {
+		text += " " + q.Message
+	}
+
+	choices := q.YesLabel + "/" + q.NoLabel
+	switch q.Default {
+	case Yes:
+		choices += ", default " + q.YesLabel
+	case No:
+		choices += ", default " + q.NoLabel
+	}
+
+	return text + " [" + choices + "] "
+}
+
+// match maps a line typed at the prompt to an answer
+func (q Question) match(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+
+	switch {
+	case line == "":
+		return q.Default, q.Default != ""
+	case strings.EqualFold(line, q.YesLabel), strings.EqualFold(line, "y"), strings.EqualFold(line, Yes):
+		return Yes, true
+	case strings.EqualFold(line, q.NoLabel), strings.EqualFold(line, "n"), strings.EqualFold(line, No):
+		return No, true
+	default:
+		return "", false
+	}
+}
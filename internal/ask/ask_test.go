@@ -0,0 +1,132 @@
+package ask
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+	"wsl-notify-send/internal/notify"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var deploy = Question{Title: "Deploy to prod?", YesLabel: "Deploy", NoLabel: "Cancel"}
+
+func TestParseAnswer(t *testing.T) {
+	for _, s := range []string{"yes", "NO", ""} {
+		answer, err := ParseAnswer(s)
+		require.NoError(t, err)
+		assert.Equal(t, strings.ToLower(s), answer)
+	}
+
+	_, err := ParseAnswer("maybe")
+	assert.ErrorContains(t, err, "invalid answer: maybe")
+}
+
+func TestResolve(t *testing.T) {
+	withDefault := deploy
+	withDefault.Default = No
+
+	tests := []struct {
+		name     string
+		question Question
+		action   string
+		err      error
+		answer   string
+		wantErr  error
+	}{
+		{"yes", deploy, Yes, nil, Yes, nil},
+		{"no", deploy, No, nil, No, nil},
+		{"clicked", deploy, notify.DefaultAction, nil, "", notify.ErrDismissed},
+		{"dismissed", deploy, "", notify.ErrDismissed, "", notify.ErrDismissed},
+		{"timeout", deploy, "", notify.ErrTimeout, "", notify.ErrTimeout},
+		{"dismissed with default", withDefault, "", notify.ErrDismissed, No, nil},
+		{"timeout with default", withDefault, "", notify.ErrTimeout, No, nil},
+		{"clicked with default", withDefault, notify.DefaultAction, nil, No, nil},
+		{"failure", withDefault, "", assert.AnError, "", assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer, err := tt.question.Resolve(tt.action, tt.err)
+
+			assert.Equal(t, tt.answer, answer)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		def     string
+		answer  string
+		wantErr error
+	}{
+		{"label", "deploy\n", "", Yes, nil},
+		{"short", "n\n", "", No, nil},
+		{"word", "YES\n", "", Yes, nil},
+		{"retry after invalid", "maybe\n\ncancel\n", "", No, nil},
+		{"empty picks default", "\n", Yes, Yes, nil},
+		{"end of input", "", "", "", notify.ErrDismissed},
+		{"end of input with default", "", No, No, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := deploy
+			q.Default = tt.def
+
+			var out bytes.Buffer
+			answer, err := Prompt(context.Background(), strings.NewReader(tt.input), &out, q)
+
+			assert.Equal(t, tt.answer, answer)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.True(t, strings.HasPrefix(out.String(), "Deploy to prod? [Deploy/Cancel"))
+		})
+	}
+}
+
+func TestPrompt_Output(t *testing.T) {
+	q := deploy
+	q.Message = "api v2"
+	q.Default = No
+
+	var out bytes.Buffer
+	_, err := Prompt(context.Background(), strings.NewReader("maybe\ny\n"), &out, q)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Deploy to prod? api v2 [Deploy/Cancel, default Cancel] "+
+		"Please answer Deploy or Cancel.\n"+
+		"Deploy to prod? api v2 [Deploy/Cancel, default Cancel] ", out.String())
+}
+
+func TestPrompt_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// A pipe that is never written blocks like an idle terminal
+	r, w := io.Pipe()
+	defer w.Close()
+
+	_, err := Prompt(ctx, r, io.Discard, deploy)
+	assert.ErrorIs(t, err, notify.ErrTimeout)
+
+	q := deploy
+	q.Default = Yes
+	answer, err := Prompt(ctx, r, io.Discard, q)
+	require.NoError(t, err)
+	assert.Equal(t, Yes, answer)
+}
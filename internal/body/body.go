@@ -0,0 +1,82 @@
+// Package body reads notification messages from stdin or files, cleaning up
+// terminal output so it reads well in a notification.
+package body
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"wsl-notify-send/internal/ansi"
+)
+
+// MaxBytes is the length a message is truncated to. Notifications only show
+// the first few lines, and some servers reject very long bodies.
+const MaxBytes = 4096
+
+// maxReadBytes bounds how much input is read, so a chatty command piped in
+// can't make us buffer all of its output
+const maxReadBytes = 64 << 10
+
+// Read reads a message from r and cleans it up
+func Read(r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxReadBytes))
+	if err != nil {
+		return "", fmt.Errorf("cannot read message: %w", err)
+	}
+	return Clean(string(data)), nil
+}
+
+// ReadFile reads a message from the file at path and cleans it up
+func ReadFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read body file: %w", err)
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Clean strips terminal escapes and control characters, trims trailing
+// whitespace from every line and from the message, and truncates it to
+// MaxBytes
+func Clean(s string) string {
+	s = strings.ToValidUTF8(ansi.Strip(s), "")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		// Progress output redraws the line after a carriage return
+		if j := strings.LastIndexByte(line, '\r'); j >= 0 {
+			line = line[j+1:]
+		}
+		lines[i] = strings.TrimRightFunc(strings.Map(controlChar, line), unicode.IsSpace)
+	}
+
+	return truncate(strings.TrimRight(strings.Join(lines, "\n"), "\n"), MaxBytes)
+}
+
+// controlChar drops control characters, keeping tabs
+func controlChar(r rune) rune {
+	if r == '\t' || (r >= ' ' && r != 0x7f) {
+		return r
+	}
+	return -1
+}
+
+// truncate shortens s to at most n bytes without splitting a character,
+// marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	cut := n - len("…")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
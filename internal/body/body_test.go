@@ -0,0 +1,76 @@
+package body
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain", "hello", "hello"},
+		{"trailing newline", "a\nb\n\n\n", "a\nb"},
+		{"trailing spaces", "a  \nb\t\n", "a\nb"},
+		{"leading indent kept", "  a\n\tb", "  a\n\tb"},
+		{"blank lines inside kept", "a\n\nb", "a\n\nb"},
+		{"crlf", "a\r\nb\r\n", "a\nb"},
+		{"carriage return", "10%\r50%\r100%\ndone", "100%\ndone"},
+		{"ansi", "\x1b[33mcommit abc\x1b[m\nAuthor: me", "commit abc\nAuthor: me"},
+		{"control characters", "a\x07b\x00c", "abc"},
+		{"invalid utf8", "a\xffb", "ab"},
+		{"empty", " \n\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Clean(tt.input))
+		})
+	}
+}
+
+func TestCleanTruncates(t *testing.T) {
+	s := Clean(strings.Repeat("é", MaxBytes))
+
+	assert.LessOrEqual(t, len(s), MaxBytes)
+	assert.True(t, utf8.ValidString(s))
+	assert.True(t, strings.HasSuffix(s, "é…"))
+}
+
+func TestRead(t *testing.T) {
+	s, err := Read(strings.NewReader("\x1b[1mTitle\x1b[0m\nline  \n"))
+
+	require.NoError(t, err)
+	assert.Equal(t, "Title\nline", s)
+}
+
+func TestReadLimitsInput(t *testing.T) {
+	// More input than is ever read
+	input := strings.Repeat("x", maxReadBytes*2)
+
+	s, err := Read(strings.NewReader(input))
+
+	require.NoError(t, err)
+	assert.Len(t, s, MaxBytes)
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "body.txt")
+	require.NoError(t, os.WriteFile(path, []byte("Deployed\nv1.2.3\n"), 0644))
+
+	s, err := ReadFile(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Deployed\nv1.2.3", s)
+
+	_, err = ReadFile(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.ErrorContains(t, err, "cannot read body file")
+}
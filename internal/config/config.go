@@ -4,51 +4,310 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/notify"
+	"wsl-notify-send/internal/tmpl"
 )
 
+// maxTagLength is the longest tag or group Windows accepts for a toast
+const maxTagLength = 64
+
+// Failure kinds returned by this package, check for them with errors.Is
+var (
+	ErrInvalidConfig = exitcode.NewKind("invalid configuration", exitcode.InvalidArgs)
+	ErrInvalidArgs   = exitcode.NewKind("invalid arguments", exitcode.InvalidArgs)
+)
+
+// OptionError is a validation error caused by the value of one option,
+// named by its flag
+type OptionError struct {
+	Option string
+	Err    error
+}
+
+func (e *OptionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *OptionError) Unwrap() error {
+	return e.Err
+}
+
+func optionError(option string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &OptionError{Option: option, Err: err}
+}
+
 type Config struct {
 	// Mode flags
 	AlertMode bool
 	BeepMode  bool
 
 	// Content options
-	Icon    string
-	AppName string
+	Icon     string
+	AppName  string
+	BodyFile string
+
+	// Rich content, folded into the message by backends that can't show it
+	Lines       []string
+	Attribution string
+	Timestamp   string
+
+	// Progress bar, from "0" to "100", or "" for none
+	Progress       string
+	ProgressLabel  string
+	ProgressStatus string
+
+	// Identity of the notification, so sending it again updates it
+	Tag   string
+	Group string
+
+	// Templates for the title and message
+	TitleTemplate string
+	Template      string
 
 	// Beep options
 	Frequency float64
 	Duration  int
 
+	// notify-send compatibility options
+	Urgency    string
+	ExpireTime int
+	Category   string
+	Hints      []string
+	ReplaceID  uint32
+	PrintID    bool
+	Transient  bool
+	Actions    []string
+	Wait       bool
+
+	// Input options
+	JSON  bool
+	JSONL bool
+
+	// Backend options
+	Backend string
+
+	// Profile selects a named set of options from the config files
+	Profile string
+
 	// Utility options
 	Quiet   bool
 	Version bool
 }
 
+// Validate checks the configuration. Its errors are ErrInvalidConfig,
+// notify.ErrIconUnreadable for icon files that can't be used, or
+// tmpl.ErrTemplate for templates that can't be parsed.
 func (c *Config) Validate() error {
+	return exitcode.Mark(ErrInvalidConfig, c.validate())
+}
+
+func (c *Config) validate() error {
 	// Can't have both alert and beep mode
 	if c.AlertMode && c.BeepMode {
 		return errors.New("cannot use both --alert and --beep modes")
 	}
 
+	if c.JSON && c.JSONL {
+		return errors.New("cannot use both --json and --jsonl")
+	}
+
+	if (c.JSON || c.JSONL) && c.BodyFile != "" {
+		return errors.New("cannot use --body-file with --json or --jsonl")
+	}
+
+	// Waiting on every line would hold up the rest of the stream
+	if c.JSONL && c.Wait {
+		return errors.New("cannot use --wait with --jsonl")
+	}
+
 	// Validate icon file if provided
 	if c.Icon != "" {
 		if err := c.validateIcon(); err != nil {
-			return err
+			return optionError("icon", err)
 		}
 	}
 
+	// Template errors keep their own kind
+	if c.TitleTemplate != "" {
+		if _, err := tmpl.Parse("title", c.TitleTemplate); err != nil {
+			return optionError("title-template", err)
+		}
+	}
+	if c.Template != "" {
+		if _, err := tmpl.Parse("message", c.Template); err != nil {
+			return optionError("template", err)
+		}
+	}
+
+	if c.Timestamp != "" {
+		if _, err := parseTimestamp(c.Timestamp); err != nil {
+			return optionError("timestamp", err)
+		}
+	}
+
+	if c.Progress != "" {
+		if _, err := parseProgress(c.Progress); err != nil {
+			return optionError("progress", err)
+		}
+	} else if c.ProgressLabel != "" || c.ProgressStatus != "" {
+		return errors.New("cannot use --progress-label or --progress-status without --progress")
+	}
+
+	if len(c.Tag) > maxTagLength {
+		return optionError("tag", errors.New("tag must be at most "+strconv.Itoa(maxTagLength)+" characters"))
+	}
+	if len(c.Group) > maxTagLength {
+		return optionError("group", errors.New("group must be at most "+strconv.Itoa(maxTagLength)+" characters"))
+	}
+	if c.Group != "" && c.Tag == "" {
+		return errors.New("cannot use --group without --tag")
+	}
+
 	// Validate beep parameters
 	if c.Frequency <= 0 {
-		return errors.New("frequency must be positive")
+		return optionError("freq", errors.New("frequency must be positive"))
 	}
 
 	if c.Duration <= 0 {
-		return errors.New("duration must be positive")
+		return optionError("duration", errors.New("duration must be positive"))
+	}
+
+	// Validate notify-send compatibility options
+	switch c.Urgency {
+	case "", notify.UrgencyLow, notify.UrgencyNormal, notify.UrgencyCritical:
+	default:
+		return optionError("urgency", errors.New("invalid urgency: "+c.Urgency+" (supported: low, normal, critical)"))
+	}
+
+	// Low urgency is silent, which contradicts an alert
+	if c.AlertMode && c.Urgency == notify.UrgencyLow {
+		return errors.New("cannot use --alert with low urgency")
+	}
+
+	if c.ExpireTime < -1 {
+		return optionError("expire-time", errors.New("expire time must be -1 or greater"))
+	}
+
+	for _, spec := range c.Hints {
+		if _, err := notify.ParseHint(spec); err != nil {
+			return optionError("hint", err)
+		}
+	}
+
+	if _, err := notify.ParseActions(c.Actions); err != nil {
+		return optionError("action", err)
+	}
+
+	// Validate backend name if provided
+	if c.Backend != "" {
+		if _, ok := notify.LookupBackend(c.Backend); !ok {
+			return optionError("backend", errors.New("unknown backend: "+c.Backend+" (available: "+strings.Join(notify.BackendNames(), ", ")+")"))
+		}
 	}
 
 	return nil
 }
 
+// NotifyOptions converts the notify-send compatibility fields into the
+// options understood by the notify package, parsing hints and actions.
+func (c *Config) NotifyOptions() (notify.Options, error) {
+	opts := notify.Options{
+		Urgency:       c.Urgency,
+		ExpireTimeout: c.ExpireTime,
+		Category:      c.Category,
+		Transient:     c.Transient,
+		ReplaceID:     c.ReplaceID,
+		Lines:         c.Lines,
+		Attribution:   c.Attribution,
+		Tag:           c.Tag,
+		Group:         c.Group,
+	}
+
+	if c.Progress != "" {
+		percent, err := parseProgress(c.Progress)
+		if err != nil {
+			return notify.Options{}, err
+		}
+		opts.Progress = &notify.Progress{Percent: percent, Label: c.ProgressLabel, Status: c.ProgressStatus}
+	}
+
+	if c.Timestamp != "" {
+		timestamp, err := parseTimestamp(c.Timestamp)
+		if err != nil {
+			return notify.Options{}, err
+		}
+		opts.Timestamp = timestamp
+	}
+
+	for _, spec := range c.Hints {
+		hint, err := notify.ParseHint(spec)
+		if err != nil {
+			return notify.Options{}, err
+		}
+		opts.Hints = append(opts.Hints, hint)
+	}
+
+	actions, err := notify.ParseActions(c.Actions)
+	if err != nil {
+		return notify.Options{}, err
+	}
+	if len(actions) > 0 {
+		opts.Actions = actions
+	}
+
+	return opts, nil
+}
+
+// RenderTemplates replaces the title and message with their templates, if
+// they have one. The templates see the JSON input fields, if any.
+func (c *Config) RenderTemplates(title, message string, input map[string]interface{}) (string, string, error) {
+	if c.TitleTemplate == "" && c.Template == "" {
+		return title, message, nil
+	}
+
+	// Both templates see the original title and message
+	data := tmpl.NewData(title, message, input)
+
+	var err error
+	if c.TitleTemplate != "" {
+		if title, err = tmpl.Render("title", c.TitleTemplate, data); err != nil {
+			return "", "", err
+		}
+	}
+	if c.Template != "" {
+		if message, err = tmpl.Render("message", c.Template, data); err != nil {
+			return "", "", err
+		}
+	}
+
+	return title, message, nil
+}
+
+// parseTimestamp parses an RFC 3339 time like 2026-10-16T09:30:00Z
+func parseTimestamp(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errors.New("invalid timestamp: " + s + " (expected RFC 3339, e.g. 2026-10-16T09:30:00Z)")
+	}
+	return t, nil
+}
+
+// parseProgress parses a progress percentage from 0 to 100
+func parseProgress(s string) (int, error) {
+	percent, err := strconv.Atoi(s)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, errors.New("invalid progress: " + s + " (expected 0 to 100)")
+	}
+	return percent, nil
+}
+
 func (c *Config) validateIcon() error {
 	// Check if it's an absolute path
 	if filepath.IsAbs(c.Icon) {
@@ -75,9 +334,9 @@ func (c *Config) validateIconFile() error {
 	// Check if file exists
 	if _, err := os.Stat(c.Icon); err != nil {
 		if os.IsNotExist(err) {
-			return errors.New("icon file does not exist: " + c.Icon)
+			return exitcode.Mark(notify.ErrIconUnreadable, errors.New("icon file does not exist: "+c.Icon))
 		}
-		return errors.New("cannot access icon file: " + err.Error())
+		return exitcode.Mark(notify.ErrIconUnreadable, errors.New("cannot access icon file: "+err.Error()))
 	}
 
 	// Check file extension
@@ -86,6 +345,6 @@ func (c *Config) validateIconFile() error {
 	case ".png", ".jpg", ".jpeg", ".ico", ".bmp":
 		return nil
 	default:
-		return errors.New("unsupported icon format: " + ext + " (supported: .png, .jpg, .jpeg, .ico, .bmp)")
+		return exitcode.Mark(notify.ErrIconUnreadable, errors.New("unsupported icon format: "+ext+" (supported: .png, .jpg, .jpeg, .ico, .bmp)"))
 	}
 }
@@ -3,7 +3,10 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+	"wsl-notify-send/internal/notify"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -289,3 +292,197 @@ func TestConfig_ValidateDurationBoundaries(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_ValidateNotifySendOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:   "all options valid",
+			config: Config{Urgency: "critical", ExpireTime: 5000, Hints: []string{"string:x:y"}, Actions: []string{"ok=OK"}},
+		},
+		{
+			name:   "server default expire time",
+			config: Config{ExpireTime: -1},
+		},
+		{
+			name:        "invalid urgency",
+			config:      Config{Urgency: "urgent"},
+			expectError: true,
+			errorMsg:    "invalid urgency: urgent",
+		},
+		{
+			name:        "alert with low urgency",
+			config:      Config{AlertMode: true, Urgency: "low"},
+			expectError: true,
+			errorMsg:    "cannot use --alert with low urgency",
+		},
+		{
+			name:        "invalid expire time",
+			config:      Config{ExpireTime: -2},
+			expectError: true,
+			errorMsg:    "expire time must be -1 or greater",
+		},
+		{
+			name:        "invalid hint",
+			config:      Config{Hints: []string{"nonsense"}},
+			expectError: true,
+			errorMsg:    "invalid hint",
+		},
+		{
+			name:   "known backend",
+			config: Config{Backend: "beeep"},
+		},
+		{
+			name:        "unknown backend",
+			config:      Config{Backend: "carrier-pigeon"},
+			expectError: true,
+			errorMsg:    "unknown backend: carrier-pigeon",
+		},
+		{
+			name:        "invalid action",
+			config:      Config{Actions: []string{"=Label"}},
+			expectError: true,
+			errorMsg:    "invalid action",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Frequency = 587.0
+			tt.config.Duration = 500
+
+			err := tt.config.Validate()
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_NotifyOptions(t *testing.T) {
+	cfg := Config{
+		Urgency:    "low",
+		ExpireTime: 1000,
+		Category:   "im.received",
+		Transient:  true,
+		ReplaceID:  3,
+		Hints:      []string{"byte:urgency:0"},
+		Actions:    []string{"Reply"},
+	}
+
+	opts, err := cfg.NotifyOptions()
+	require.NoError(t, err)
+	assert.Equal(t, "low", opts.Urgency)
+	assert.Equal(t, 1000, opts.ExpireTimeout)
+	assert.Equal(t, "im.received", opts.Category)
+	assert.True(t, opts.Transient)
+	assert.Equal(t, uint32(3), opts.ReplaceID)
+	require.Len(t, opts.Hints, 1)
+	assert.Equal(t, "urgency", opts.Hints[0].Name)
+	assert.Equal(t, byte(0), opts.Hints[0].Value)
+	require.Len(t, opts.Actions, 1)
+	assert.Equal(t, "0", opts.Actions[0].Key)
+}
+
+func TestConfig_NotifyOptionsRichContent(t *testing.T) {
+	cfg := Config{
+		Lines:       []string{"3 passed", "1 skipped"},
+		Attribution: "via CI",
+		Timestamp:   "2026-10-16T11:30:00+02:00",
+	}
+
+	opts, err := cfg.NotifyOptions()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3 passed", "1 skipped"}, opts.Lines)
+	assert.Equal(t, "via CI", opts.Attribution)
+	assert.True(t, opts.Timestamp.Equal(time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)))
+
+	cfg.Timestamp = "16/10/2026"
+	assert.ErrorContains(t, cfg.Validate(), "invalid timestamp: 16/10/2026 (expected RFC 3339")
+}
+
+func TestConfig_NotifyOptionsProgress(t *testing.T) {
+	cfg := Config{Frequency: 587, Duration: 500}
+
+	opts, err := cfg.NotifyOptions()
+	require.NoError(t, err)
+	assert.Nil(t, opts.Progress, "no progress bar unless asked for")
+
+	cfg.Progress = "42"
+	cfg.ProgressLabel = "Copying"
+	cfg.ProgressStatus = "3/7"
+	cfg.Tag = "backup"
+	cfg.Group = "nightly"
+	require.NoError(t, cfg.Validate())
+
+	opts, err = cfg.NotifyOptions()
+	require.NoError(t, err)
+	assert.Equal(t, &notify.Progress{Percent: 42, Label: "Copying", Status: "3/7"}, opts.Progress)
+	assert.Equal(t, "backup", opts.Tag)
+	assert.Equal(t, "nightly", opts.Group)
+
+	for _, progress := range []string{"-1", "101", "42%", "0.5"} {
+		cfg.Progress = progress
+		assert.ErrorContains(t, cfg.Validate(), "invalid progress: "+progress+" (expected 0 to 100)")
+	}
+
+	cfg = Config{Frequency: 587, Duration: 500, ProgressLabel: "Copying"}
+	assert.ErrorContains(t, cfg.Validate(), "without --progress")
+
+	cfg = Config{Frequency: 587, Duration: 500, Group: "nightly"}
+	assert.ErrorContains(t, cfg.Validate(), "cannot use --group without --tag")
+
+	cfg = Config{Frequency: 587, Duration: 500, Tag: strings.Repeat("x", 65)}
+	assert.ErrorContains(t, cfg.Validate(), "tag must be at most 64 characters")
+}
+
+func TestConfig_ValidateErrorKinds(t *testing.T) {
+	err := (&Config{Frequency: -1, Duration: 500}).Validate()
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	err = (&Config{Icon: "/nonexistent/icon.png", Frequency: 587, Duration: 500}).Validate()
+	assert.ErrorIs(t, err, notify.ErrIconUnreadable)
+	assert.NotErrorIs(t, err, ErrInvalidConfig)
+
+	assert.NoError(t, (&Config{Frequency: 587, Duration: 500}).Validate())
+}
+
+func TestConfig_ValidateOptionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		option string
+	}{
+		{"icon", Config{Icon: "/nonexistent/icon.png", Frequency: 587, Duration: 500}, "icon"},
+		{"frequency", Config{Frequency: 0, Duration: 500}, "freq"},
+		{"duration", Config{Frequency: 587, Duration: 0}, "duration"},
+		{"urgency", Config{Frequency: 587, Duration: 500, Urgency: "urgent"}, "urgency"},
+		{"expire time", Config{Frequency: 587, Duration: 500, ExpireTime: -2}, "expire-time"},
+		{"hint", Config{Frequency: 587, Duration: 500, Hints: []string{"bad"}}, "hint"},
+		{"action", Config{Frequency: 587, Duration: 500, Actions: []string{"="}}, "action"},
+		{"backend", Config{Frequency: 587, Duration: 500, Backend: "carrier-pigeon"}, "backend"},
+		{"timestamp", Config{Frequency: 587, Duration: 500, Timestamp: "yesterday"}, "timestamp"},
+		{"progress", Config{Frequency: 587, Duration: 500, Progress: "half"}, "progress"},
+		{"tag", Config{Frequency: 587, Duration: 500, Tag: strings.Repeat("x", 65)}, "tag"},
+		{"title template", Config{Frequency: 587, Duration: 500, TitleTemplate: "{{.Title"}, "title-template"},
+		{"template", Config{Frequency: 587, Duration: 500, Template: "{{.Message"}, "template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+
+			var optErr *OptionError
+			require.ErrorAs(t, err, &optErr)
+			assert.Equal(t, tt.option, optErr.Option)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// EnvPrefix starts the name of every environment variable that sets an option
+const EnvPrefix = "WSL_NOTIFY_SEND_"
+
+// EnvVar returns the environment variable for a flag, e.g.
+// WSL_NOTIFY_SEND_APP_NAME for --app-name
+func EnvVar(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// EnvFlags returns the flags in flags that can be set by an environment
+// variable, sorted by name
+func EnvFlags(flags *pflag.FlagSet) []*pflag.Flag {
+	var settable []*pflag.Flag
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if !commandLineOnly[flag.Name] {
+			settable = append(settable, flag)
+		}
+	})
+	return settable
+}
+
+// EnvLayers reads the environment variable of every flag in flags. Each set
+// variable becomes its own layer so errors name the variable. Repeatable
+// flags take one value per line. Empty variables are ignored.
+func EnvLayers(flags *pflag.FlagSet, getenv func(string) string) []Layer {
+	var layers []Layer
+
+	for _, flag := range EnvFlags(flags) {
+		name := EnvVar(flag.Name)
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+
+		values := []string{value}
+		if _, ok := flag.Value.(pflag.SliceValue); ok {
+			values = strings.Split(strings.TrimRight(value, "\n"), "\n")
+		}
+
+		layers = append(layers, Layer{
+			Source: name,
+			Values: map[string][]string{flag.Name: values},
+		})
+	}
+
+	return layers
+}
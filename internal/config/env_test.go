@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvVar(t *testing.T) {
+	assert.Equal(t, "WSL_NOTIFY_SEND_APP_NAME", EnvVar("app-name"))
+	assert.Equal(t, "WSL_NOTIFY_SEND_FREQ", EnvVar("freq"))
+	assert.Equal(t, "WSL_NOTIFY_SEND_EXPIRE_TIME", EnvVar("expire-time"))
+}
+
+func TestEnvFlags(t *testing.T) {
+	var cfg Config
+	var names []string
+	for _, flag := range EnvFlags(newTestFlags(&cfg)) {
+		names = append(names, flag.Name)
+	}
+
+	assert.Contains(t, names, "app-name")
+	assert.Contains(t, names, "hint")
+	assert.NotContains(t, names, "version", "command-line only options have no variable")
+	assert.IsIncreasing(t, names)
+}
+
+func TestEnvLayers(t *testing.T) {
+	env := map[string]string{
+		"WSL_NOTIFY_SEND_APP_NAME": "CI",
+		"WSL_NOTIFY_SEND_FREQ":     "800.5",
+		"WSL_NOTIFY_SEND_QUIET":    "true",
+		"WSL_NOTIFY_SEND_HINT":     "string:x-job:nightly\nint:value:3\n",
+		"WSL_NOTIFY_SEND_ICON":     "",
+		"WSL_NOTIFY_SEND_VERSION":  "true",
+	}
+	getenv := func(name string) string { return env[name] }
+
+	var cfg Config
+	flags := newTestFlags(&cfg)
+	layers := EnvLayers(flags, getenv)
+
+	// One layer per set variable, command-line only options are skipped
+	require.Len(t, layers, 4)
+	assert.Equal(t, "WSL_NOTIFY_SEND_APP_NAME", layers[0].Source)
+
+	sources, err := ApplyLayers(flags, layers)
+	require.NoError(t, err)
+
+	assert.Equal(t, "CI", cfg.AppName)
+	assert.Equal(t, 800.5, cfg.Frequency)
+	assert.True(t, cfg.Quiet)
+	assert.Equal(t, []string{"string:x-job:nightly", "int:value:3"}, cfg.Hints)
+	assert.Equal(t, "", cfg.Icon)
+	assert.False(t, cfg.Version)
+	assert.Equal(t, "WSL_NOTIFY_SEND_FREQ", sources["freq"])
+}
+
+func TestEnvLayersInvalidValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		errorMsg string
+	}{
+		{
+			name:     "float",
+			env:      map[string]string{"WSL_NOTIFY_SEND_FREQ": "high"},
+			errorMsg: "invalid value for freq in WSL_NOTIFY_SEND_FREQ",
+		},
+		{
+			name:     "int",
+			env:      map[string]string{"WSL_NOTIFY_SEND_DURATION": "1.5"},
+			errorMsg: "invalid value for duration in WSL_NOTIFY_SEND_DURATION",
+		},
+		{
+			name:     "bool",
+			env:      map[string]string{"WSL_NOTIFY_SEND_QUIET": "maybe"},
+			errorMsg: "invalid value for quiet in WSL_NOTIFY_SEND_QUIET",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			flags := newTestFlags(&cfg)
+
+			_, err := ApplyLayers(flags, EnvLayers(flags, func(name string) string { return tt.env[name] }))
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
+
+func TestEnvOverridesFiles(t *testing.T) {
+	var cfg Config
+	flags := newTestFlags(&cfg)
+	require.NoError(t, flags.Parse([]string{"--icon", "error"}))
+
+	env := map[string]string{
+		"WSL_NOTIFY_SEND_APP_NAME": "Env",
+		"WSL_NOTIFY_SEND_ICON":     "info",
+	}
+	layers := append([]Layer{
+		{Source: "project", Values: map[string][]string{"app-name": {"Project"}, "duration": {"900"}}},
+	}, EnvLayers(flags, func(name string) string { return env[name] })...)
+
+	_, err := ApplyLayers(flags, layers)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Env", cfg.AppName)
+	assert.Equal(t, "error", cfg.Icon)
+	assert.Equal(t, 900, cfg.Duration)
+}
+
+func TestSourcesAnnotate(t *testing.T) {
+	sources := Sources{"freq": "WSL_NOTIFY_SEND_FREQ"}
+
+	cfg := Config{Frequency: -5, Duration: 500}
+	err := sources.Annotate(cfg.Validate())
+	assert.EqualError(t, err, "frequency must be positive (from WSL_NOTIFY_SEND_FREQ)")
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	// Options given on the command line have no source to add
+	cfg = Config{Frequency: 587, Duration: -1}
+	err = sources.Annotate(cfg.Validate())
+	assert.EqualError(t, err, "duration must be positive")
+
+	plain := errors.New("cannot use both --alert and --beep modes")
+	assert.Equal(t, plain, sources.Annotate(plain))
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Config file locations: the user file lives in the user config directory,
+// project files are found by walking up from the working directory
+const (
+	userConfigDir   = "wsl-notify-send"
+	userConfigName  = "config"
+	projectFileName = ".wsl-notify-send"
+)
+
+var configExtensions = []string{".yaml", ".yml"}
+
+// Options that only make sense on the command line
+var commandLineOnly = map[string]bool{
+	"help":      true,
+	"version":   true,
+	"json":      true,
+	"jsonl":     true,
+	"body-file": true,
+}
+
+// Layer is one source of option values, keyed by flag name. Layers are
+// applied lowest precedence first, so later layers win.
+type Layer struct {
+	Source string
+	Values map[string][]string
+
+	// Profiles holds the named option sets of a config file
+	Profiles map[string]map[string][]string
+}
+
+// LoadFile reads a YAML config file into a layer. Keys are flag names,
+// values are scalars or lists for repeatable flags.
+func LoadFile(path string) (Layer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Layer{}, fmt.Errorf("cannot read config file: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return Layer{}, fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+
+	layer := Layer{Source: path}
+	if value, ok := raw[profilesKey]; ok {
+		profiles, err := loadProfiles(value, path)
+		if err != nil {
+			return Layer{}, err
+		}
+		layer.Profiles = profiles
+		delete(raw, profilesKey)
+	}
+
+	values, err := fileOptions(raw, path)
+	if err != nil {
+		return Layer{}, err
+	}
+	layer.Values = values
+
+	return layer, nil
+}
+
+// fileOptions converts the YAML options of a file or profile into flag values
+func fileOptions(raw map[string]interface{}, path string) (map[string][]string, error) {
+	options := make(map[string][]string)
+	for key, value := range raw {
+		values, err := fileValues(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s in %s: %w", key, path, err)
+		}
+		if key == "icon" && len(values) == 1 {
+			values[0] = resolvePath(values[0], filepath.Dir(path))
+		}
+		options[key] = values
+	}
+	return options, nil
+}
+
+// fileValues converts a YAML value into flag strings
+func fileValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, errors.New("missing value")
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if _, ok := item.([]interface{}); ok {
+				return nil, errors.New("nested lists are not supported")
+			}
+			if _, ok := item.(map[string]interface{}); ok {
+				return nil, errors.New("maps are not supported")
+			}
+			values = append(values, fmt.Sprint(item))
+		}
+		return values, nil
+	case map[string]interface{}:
+		return nil, errors.New("maps are not supported")
+	default:
+		return []string{fmt.Sprint(v)}, nil
+	}
+}
+
+// resolvePath expands ~/ and resolves icon paths relative to the config
+// file that names them. Stock icon names are left alone.
+func resolvePath(value, dir string) string {
+	if strings.HasPrefix(value, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, value[2:])
+		}
+	}
+
+	if filepath.IsAbs(value) {
+		return value
+	}
+
+	candidate := filepath.Join(dir, value)
+	if _, err := os.Stat(candidate); err == nil {
+		return candidate
+	}
+
+	return value
+}
+
+// UserConfigFile returns the path of the user config file,
+// $XDG_CONFIG_HOME/wsl-notify-send/config.yaml, or "" if there is none
+func UserConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return findConfigFile(filepath.Join(dir, userConfigDir), userConfigName)
+}
+
+// ProjectConfigFile returns the nearest .wsl-notify-send.yaml in dir or one
+// of its parents, or "" if there is none
+func ProjectConfigFile(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		if path := findConfigFile(dir, projectFileName); path != "" {
+			return path
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func findConfigFile(dir, name string) string {
+	for _, ext := range configExtensions {
+		path := filepath.Join(dir, name+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// LoadLayers loads the user and project config files that exist, lowest
+// precedence first
+func LoadLayers(dir string) ([]Layer, error) {
+	var layers []Layer
+
+	for _, path := range []string{UserConfigFile(), ProjectConfigFile(dir)} {
+		if path == "" {
+			continue
+		}
+		layer, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+
+	return layers, nil
+}
+
+// Sources records the layer each option's value was taken from, keyed by
+// flag name. Options left at their default or given as flags are absent.
+type Sources map[string]string
+
+// Annotate adds the source of the offending option to a validation error,
+// so a bad value can be traced back to the file or variable holding it
+func (s Sources) Annotate(err error) error {
+	var optErr *OptionError
+	if !errors.As(err, &optErr) {
+		return err
+	}
+	source, ok := s[optErr.Option]
+	if !ok {
+		return err
+	}
+	return fmt.Errorf("%w (from %s)", err, source)
+}
+
+// ApplyLayers sets every flag that wasn't given on the command line from the
+// layers, so the precedence is flags > later layers > earlier layers >
+// built-in defaults
+func ApplyLayers(flags *pflag.FlagSet, layers []Layer) (Sources, error) {
+	sources := make(Sources)
+
+	for _, layer := range layers {
+		// Sort keys so errors are deterministic
+		keys := make([]string, 0, len(layer.Values))
+		for key := range layer.Values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			flag := flags.Lookup(key)
+			if flag == nil || commandLineOnly[key] {
+				return nil, fmt.Errorf("unknown option %q in %s", key, layer.Source)
+			}
+
+			// Command-line flags always win
+			if flag.Changed {
+				continue
+			}
+
+			if err := setFlag(flag, layer.Values[key]); err != nil {
+				return nil, fmt.Errorf("invalid value for %s in %s: %w", key, layer.Source, err)
+			}
+			sources[key] = layer.Source
+		}
+	}
+
+	return sources, nil
+}
+
+// setFlag sets a flag's value without marking it as changed on the command line
+func setFlag(flag *pflag.Flag, values []string) error {
+	if sv, ok := flag.Value.(pflag.SliceValue); ok {
+		return sv.Replace(values)
+	}
+
+	if len(values) != 1 {
+		return errors.New("expected a single value")
+	}
+	return flag.Value.Set(values[0])
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFlags returns a flag set bound to cfg like the root command's
+func newTestFlags(cfg *Config) *pflag.FlagSet {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringVar(&cfg.AppName, "app-name", "wsl-notify-send", "")
+	flags.StringVar(&cfg.Icon, "icon", "", "")
+	flags.Float64Var(&cfg.Frequency, "freq", 587.0, "")
+	flags.IntVar(&cfg.Duration, "duration", 500, "")
+	flags.BoolVar(&cfg.Quiet, "quiet", false, "")
+	flags.StringArrayVar(&cfg.Hints, "hint", nil, "")
+	flags.BoolVar(&cfg.Version, "version", false, "")
+	return flags
+}
+
+func writeFile(t *testing.T, path, content string) string {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "bot.png"), "icon")
+	path := writeFile(t, filepath.Join(dir, "config.yaml"), `
+app-name: Build Bot
+icon: bot.png
+freq: 800
+quiet: true
+hint:
+  - string:x-job:nightly
+  - int:value:3
+`)
+
+	layer, err := LoadFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, path, layer.Source)
+	assert.Equal(t, []string{"Build Bot"}, layer.Values["app-name"])
+	assert.Equal(t, []string{filepath.Join(dir, "bot.png")}, layer.Values["icon"])
+	assert.Equal(t, []string{"800"}, layer.Values["freq"])
+	assert.Equal(t, []string{"true"}, layer.Values["quiet"])
+	assert.Equal(t, []string{"string:x-job:nightly", "int:value:3"}, layer.Values["hint"])
+}
+
+func TestLoadFileStockIcon(t *testing.T) {
+	path := writeFile(t, filepath.Join(t.TempDir(), "config.yaml"), "icon: warning\n")
+
+	layer, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"warning"}, layer.Values["icon"])
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := LoadFile(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot read config file")
+
+	path := writeFile(t, filepath.Join(dir, "bad.yaml"), "app-name: [unterminated\n")
+	_, err = LoadFile(path)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot parse config file")
+
+	path = writeFile(t, filepath.Join(dir, "map.yaml"), "app-name:\n  nested: true\n")
+	_, err = LoadFile(path)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "maps are not supported")
+}
+
+func TestUserConfigFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+
+	assert.Equal(t, "", UserConfigFile())
+
+	path := writeFile(t, filepath.Join(home, "wsl-notify-send", "config.yml"), "quiet: true\n")
+	assert.Equal(t, path, UserConfigFile())
+}
+
+func TestProjectConfigFile(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+
+	assert.Equal(t, "", ProjectConfigFile(sub))
+
+	path := writeFile(t, filepath.Join(root, ".wsl-notify-send.yaml"), "quiet: true\n")
+	assert.Equal(t, path, ProjectConfigFile(sub))
+
+	// The nearest file wins
+	nearer := writeFile(t, filepath.Join(root, "a", ".wsl-notify-send.yaml"), "quiet: false\n")
+	assert.Equal(t, nearer, ProjectConfigFile(sub))
+}
+
+func TestLoadLayers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	userPath := writeFile(t, filepath.Join(home, "wsl-notify-send", "config.yaml"), "app-name: User\n")
+
+	project := t.TempDir()
+	projectPath := writeFile(t, filepath.Join(project, ".wsl-notify-send.yaml"), "app-name: Project\n")
+
+	layers, err := LoadLayers(project)
+	require.NoError(t, err)
+	require.Len(t, layers, 2)
+	assert.Equal(t, userPath, layers[0].Source)
+	assert.Equal(t, projectPath, layers[1].Source)
+}
+
+func TestApplyLayers(t *testing.T) {
+	var cfg Config
+	flags := newTestFlags(&cfg)
+	require.NoError(t, flags.Parse([]string{"--icon", "error"}))
+
+	sources, err := ApplyLayers(flags, []Layer{
+		{Source: "user", Values: map[string][]string{
+			"app-name": {"User"},
+			"icon":     {"warning"},
+			"freq":     {"800"},
+			"hint":     {"string:a:b"},
+		}},
+		{Source: "project", Values: map[string][]string{
+			"app-name": {"Project"},
+			"duration": {"1000"},
+		}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Project", cfg.AppName)
+	assert.Equal(t, "error", cfg.Icon) // command line wins
+	assert.Equal(t, 800.0, cfg.Frequency)
+	assert.Equal(t, 1000, cfg.Duration)
+	assert.Equal(t, []string{"string:a:b"}, cfg.Hints)
+	assert.False(t, flags.Changed("app-name"))
+	assert.Equal(t, Sources{
+		"app-name": "project",
+		"freq":     "user",
+		"hint":     "user",
+		"duration": "project",
+	}, sources)
+}
+
+func TestApplyLayersErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   map[string][]string
+		errorMsg string
+	}{
+		{
+			name:     "unknown option",
+			values:   map[string][]string{"colour": {"red"}},
+			errorMsg: `unknown option "colour" in test.yaml`,
+		},
+		{
+			name:     "command-line only option",
+			values:   map[string][]string{"version": {"true"}},
+			errorMsg: `unknown option "version" in test.yaml`,
+		},
+		{
+			name:     "invalid number",
+			values:   map[string][]string{"freq": {"loud"}},
+			errorMsg: "invalid value for freq in test.yaml",
+		},
+		{
+			name:     "list for single value",
+			values:   map[string][]string{"app-name": {"a", "b"}},
+			errorMsg: "expected a single value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			flags := newTestFlags(&cfg)
+
+			_, err := ApplyLayers(flags, []Layer{{Source: "test.yaml", Values: tt.values}})
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
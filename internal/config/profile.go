@@ -0,0 +1,146 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// profilesKey is the config file section holding the named profiles
+const profilesKey = "profiles"
+
+// Profile is a named set of option values from a config file, selected
+// with --profile
+type Profile struct {
+	Name   string
+	Source string
+	Values map[string][]string
+}
+
+// Layer returns the profile as a layer that applies its values
+func (p Profile) Layer() Layer {
+	return Layer{
+		Source: fmt.Sprintf("profile %s in %s", p.Name, p.Source),
+		Values: p.Values,
+	}
+}
+
+// loadProfiles reads the profiles section of a config file. Besides flag
+// names a profile may set mode: notify, alert or beep.
+func loadProfiles(value interface{}, path string) (map[string]map[string][]string, error) {
+	section, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid profiles in %s: expected a map of profile names", path)
+	}
+
+	profiles := make(map[string]map[string][]string)
+	for name, raw := range section {
+		options, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid profile %s in %s: expected a map of options", name, path)
+		}
+
+		if _, ok := options["profile"]; ok {
+			return nil, fmt.Errorf("invalid profile %s in %s: a profile cannot select another profile", name, path)
+		}
+
+		values, err := fileOptions(options, path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile %s: %w", name, err)
+		}
+
+		if mode, ok := values["mode"]; ok {
+			if err := expandMode(values, mode); err != nil {
+				return nil, fmt.Errorf("invalid profile %s in %s: %w", name, path, err)
+			}
+		}
+
+		profiles[name] = values
+	}
+
+	return profiles, nil
+}
+
+// expandMode replaces the mode option with the alert and beep flags
+func expandMode(values map[string][]string, mode []string) error {
+	delete(values, "mode")
+
+	if len(mode) != 1 {
+		return errors.New("mode must be notify, alert or beep")
+	}
+
+	switch mode[0] {
+	case "notify":
+		values["alert"] = []string{"false"}
+		values["beep"] = []string{"false"}
+	case "alert":
+		values["alert"] = []string{"true"}
+		values["beep"] = []string{"false"}
+	case "beep":
+		values["alert"] = []string{"false"}
+		values["beep"] = []string{"true"}
+	default:
+		return fmt.Errorf("mode must be notify, alert or beep, not %s", mode[0])
+	}
+	return nil
+}
+
+// Profiles returns the profiles defined by the layers sorted by name. A
+// profile in a later layer replaces one with the same name in an earlier one.
+func Profiles(layers []Layer) []Profile {
+	byName := make(map[string]Profile)
+	for _, layer := range layers {
+		for name, values := range layer.Profiles {
+			byName[name] = Profile{Name: name, Source: layer.Source, Values: values}
+		}
+	}
+
+	profiles := make([]Profile, 0, len(byName))
+	for _, p := range byName {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles
+}
+
+// FindProfile returns the named profile from the layers
+func FindProfile(layers []Layer, name string) (Profile, error) {
+	profiles := Profiles(layers)
+
+	names := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+		names = append(names, p.Name)
+	}
+
+	if len(names) == 0 {
+		return Profile{}, fmt.Errorf("unknown profile: %s (no profiles defined)", name)
+	}
+	return Profile{}, fmt.Errorf("unknown profile: %s (available: %s)", name, strings.Join(names, ", "))
+}
+
+// ProfileName returns the profile to use: the --profile flag when given,
+// otherwise the value from the highest precedence layer that sets it
+func ProfileName(flags *pflag.FlagSet, layers []Layer) string {
+	flag := flags.Lookup("profile")
+	if flag == nil {
+		return ""
+	}
+	if flag.Changed {
+		return flag.Value.String()
+	}
+
+	for i := len(layers) - 1; i >= 0; i-- {
+		if values, ok := layers[i].Values["profile"]; ok && len(values) == 1 {
+			return values[0]
+		}
+	}
+	return ""
+}
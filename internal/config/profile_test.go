@@ -0,0 +1,149 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFileProfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "red.png"), "icon")
+	path := writeFile(t, filepath.Join(dir, "config.yaml"), `
+app-name: Dev Box
+profiles:
+  build-failed:
+    mode: alert
+    icon: red.png
+    app-name: CI
+    urgency: critical
+  deploy-done:
+    icon: info
+`)
+
+	layer, err := LoadFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]string{"app-name": {"Dev Box"}}, layer.Values)
+	require.Len(t, layer.Profiles, 2)
+	assert.Equal(t, map[string][]string{
+		"alert":    {"true"},
+		"beep":     {"false"},
+		"icon":     {filepath.Join(dir, "red.png")},
+		"app-name": {"CI"},
+		"urgency":  {"critical"},
+	}, layer.Profiles["build-failed"])
+	assert.Equal(t, map[string][]string{"icon": {"info"}}, layer.Profiles["deploy-done"])
+}
+
+func TestLoadFileProfileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		errorMsg string
+	}{
+		{
+			name:     "profiles not a map",
+			content:  "profiles: [a, b]\n",
+			errorMsg: "expected a map of profile names",
+		},
+		{
+			name:     "profile not a map",
+			content:  "profiles:\n  loud: true\n",
+			errorMsg: "invalid profile loud",
+		},
+		{
+			name:     "unknown mode",
+			content:  "profiles:\n  loud:\n    mode: shout\n",
+			errorMsg: "mode must be notify, alert or beep, not shout",
+		},
+		{
+			name:     "nested profile",
+			content:  "profiles:\n  loud:\n    profile: quiet\n",
+			errorMsg: "a profile cannot select another profile",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, filepath.Join(t.TempDir(), "config.yaml"), tt.content)
+
+			_, err := LoadFile(path)
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	layers := []Layer{
+		{Source: "user.yaml", Profiles: map[string]map[string][]string{
+			"deploy": {"icon": {"info"}},
+			"build":  {"icon": {"error"}},
+		}},
+		{Source: "project.yaml", Profiles: map[string]map[string][]string{
+			"build": {"icon": {"warning"}},
+		}},
+	}
+
+	profiles := Profiles(layers)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, Profile{Name: "build", Source: "project.yaml", Values: map[string][]string{"icon": {"warning"}}}, profiles[0])
+	assert.Equal(t, "deploy", profiles[1].Name)
+	assert.Equal(t, "user.yaml", profiles[1].Source)
+
+	p, err := FindProfile(layers, "deploy")
+	require.NoError(t, err)
+	assert.Equal(t, "profile deploy in user.yaml", p.Layer().Source)
+
+	_, err = FindProfile(layers, "missing")
+	assert.EqualError(t, err, "unknown profile: missing (available: build, deploy)")
+
+	_, err = FindProfile(nil, "missing")
+	assert.EqualError(t, err, "unknown profile: missing (no profiles defined)")
+}
+
+func TestProfileName(t *testing.T) {
+	var cfg Config
+	flags := newTestFlags(&cfg)
+	flags.StringVar(&cfg.Profile, "profile", "", "")
+
+	layers := []Layer{
+		{Source: "user.yaml", Values: map[string][]string{"profile": {"user"}}},
+		{Source: "WSL_NOTIFY_SEND_PROFILE", Values: map[string][]string{"profile": {"env"}}},
+	}
+
+	assert.Equal(t, "", ProfileName(flags, nil))
+	assert.Equal(t, "env", ProfileName(flags, layers))
+
+	require.NoError(t, flags.Parse([]string{"--profile", "flag"}))
+	assert.Equal(t, "flag", ProfileName(flags, layers))
+}
+
+func TestApplyProfileLayer(t *testing.T) {
+	var cfg Config
+	flags := newTestFlags(&cfg)
+	require.NoError(t, flags.Parse([]string{"--app-name", "Flag"}))
+
+	profile := Profile{Name: "build", Source: "config.yaml", Values: map[string][]string{
+		"app-name": {"Profile"},
+		"icon":     {"error"},
+		"freq":     {"-1"},
+	}}
+
+	sources, err := ApplyLayers(flags, []Layer{
+		{Source: "config.yaml", Values: map[string][]string{"icon": {"info"}}},
+		profile.Layer(),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Flag", cfg.AppName)
+	assert.Equal(t, "error", cfg.Icon)
+
+	cfg.Duration = 500
+	err = sources.Annotate(cfg.Validate())
+	assert.EqualError(t, err, "frequency must be positive (from profile build in config.yaml)")
+}
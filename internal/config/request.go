@@ -0,0 +1,141 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Request is a notification given as a JSON object with --json or --jsonl.
+// Fields that are left out keep the value set by the command line,
+// environment and config files.
+type Request struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+
+	Alert *bool `json:"alert"`
+	Beep  *bool `json:"beep"`
+
+	Icon    *string `json:"icon"`
+	AppName *string `json:"app_name"`
+
+	Frequency *float64 `json:"frequency"`
+	Duration  *int     `json:"duration"`
+
+	Urgency    *string  `json:"urgency"`
+	ExpireTime *int     `json:"expire_time"`
+	Category   *string  `json:"category"`
+	Hints      []string `json:"hints"`
+	ReplaceID  *uint32  `json:"replace_id"`
+	Transient  *bool    `json:"transient"`
+	Actions    []string `json:"actions"`
+
+	Lines       []string `json:"lines"`
+	Attribution *string  `json:"attribution"`
+	Timestamp   *string  `json:"timestamp"`
+
+	Progress       *int    `json:"progress"`
+	ProgressLabel  *string `json:"progress_label"`
+	ProgressStatus *string `json:"progress_status"`
+	Tag            *string `json:"tag"`
+	Group          *string `json:"group"`
+
+	// Fields holds every field of the object, for templates
+	Fields map[string]interface{} `json:"-"`
+}
+
+// DecodeRequest parses a single JSON notification object. Unknown fields are
+// rejected so a misspelled field doesn't go unnoticed, unless templates are
+// going to use them.
+func DecodeRequest(data []byte, allowUnknown bool) (Request, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return Request{}, errors.New("no notification given")
+	}
+	if data[0] != '{' {
+		return Request{}, errors.New("cannot parse notification: expected a JSON object")
+	}
+
+	var req Request
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&req.Fields); err != nil {
+		return Request{}, fmt.Errorf("cannot parse notification: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return Request{}, errors.New("cannot parse notification: unexpected data after the JSON object")
+	}
+
+	decoder = json.NewDecoder(bytes.NewReader(data))
+	if !allowUnknown {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&req); err != nil {
+		return Request{}, fmt.Errorf("cannot parse notification: %w", err)
+	}
+
+	return req, nil
+}
+
+// Apply returns base with the fields set in the request replacing its values
+func (r Request) Apply(base Config) Config {
+	c := base
+
+	setBool(&c.AlertMode, r.Alert)
+	setBool(&c.BeepMode, r.Beep)
+	setString(&c.Icon, r.Icon)
+	setString(&c.AppName, r.AppName)
+	if r.Frequency != nil {
+		c.Frequency = *r.Frequency
+	}
+	setInt(&c.Duration, r.Duration)
+	setString(&c.Urgency, r.Urgency)
+	setInt(&c.ExpireTime, r.ExpireTime)
+	setString(&c.Category, r.Category)
+	if r.Hints != nil {
+		c.Hints = r.Hints
+	}
+	if r.ReplaceID != nil {
+		c.ReplaceID = *r.ReplaceID
+	}
+	setBool(&c.Transient, r.Transient)
+	if r.Actions != nil {
+		c.Actions = r.Actions
+	}
+	if r.Lines != nil {
+		c.Lines = r.Lines
+	}
+	setString(&c.Attribution, r.Attribution)
+	setString(&c.Timestamp, r.Timestamp)
+	if r.Progress != nil {
+		c.Progress = strconv.Itoa(*r.Progress)
+	}
+	setString(&c.ProgressLabel, r.ProgressLabel)
+	setString(&c.ProgressStatus, r.ProgressStatus)
+	setString(&c.Tag, r.Tag)
+	setString(&c.Group, r.Group)
+
+	return c
+}
+
+func setBool(dst *bool, v *bool) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func setInt(dst *int, v *int) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func setString(dst *string, v *string) {
+	if v != nil {
+		*dst = *v
+	}
+}
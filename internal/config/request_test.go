@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeRequest(t *testing.T) {
+	req, err := DecodeRequest([]byte(`{"title": "Build", "message": "Done", "alert": true,
+		"urgency": "critical", "expire_time": 0, "hints": ["string:x-job:nightly"], "replace_id": 7}`), false)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Build", req.Title)
+	assert.Equal(t, "Done", req.Message)
+	require.NotNil(t, req.Alert)
+	assert.True(t, *req.Alert)
+	require.NotNil(t, req.ExpireTime)
+	assert.Equal(t, 0, *req.ExpireTime)
+	assert.Equal(t, []string{"string:x-job:nightly"}, req.Hints)
+	assert.Nil(t, req.Icon)
+}
+
+func TestDecodeRequestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		errorMsg string
+	}{
+		{"empty", "  \n", "no notification given"},
+		{"not an object", `["title"]`, "expected a JSON object"},
+		{"invalid json", `{"title": }`, "cannot parse notification"},
+		{"unknown field", `{"title": "x", "body": "y"}`, `unknown field "body"`},
+		{"wrong type", `{"title": "x", "alert": "yes"}`, "cannot parse notification"},
+		{"trailing data", `{"title": "x"} {"title": "y"}`, "unexpected data after the JSON object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeRequest([]byte(tt.data), false)
+			assert.ErrorContains(t, err, tt.errorMsg)
+		})
+	}
+}
+
+func TestRequestApply(t *testing.T) {
+	base := Config{
+		AlertMode:  true,
+		AppName:    "CI",
+		Frequency:  587,
+		Duration:   500,
+		Urgency:    "normal",
+		ExpireTime: -1,
+		Hints:      []string{"string:x-job:nightly"},
+	}
+
+	req, err := DecodeRequest([]byte(`{"title": "x", "alert": false, "app_name": "Deploy", "expire_time": 0}`), false)
+	require.NoError(t, err)
+	c := req.Apply(base)
+
+	// Fields given in the request win, the others are kept
+	assert.False(t, c.AlertMode)
+	assert.Equal(t, "Deploy", c.AppName)
+	assert.Equal(t, 0, c.ExpireTime)
+	assert.Equal(t, "normal", c.Urgency)
+	assert.Equal(t, []string{"string:x-job:nightly"}, c.Hints)
+
+	// The base is left alone
+	assert.True(t, base.AlertMode)
+	assert.Equal(t, "CI", base.AppName)
+}
+
+func TestRequestApplyValidation(t *testing.T) {
+	base := Config{Frequency: 587, Duration: 500}
+
+	req, err := DecodeRequest([]byte(`{"title": "x", "urgency": "urgent"}`), false)
+	require.NoError(t, err)
+
+	c := req.Apply(base)
+	assert.ErrorContains(t, c.Validate(), "invalid urgency: urgent")
+}
+
+func TestRequestApplyProgress(t *testing.T) {
+	base := Config{Frequency: 587, Duration: 500, Tag: "backup"}
+
+	req, err := DecodeRequest([]byte(`{"title": "x", "progress": 42, "progress_status": "3/7", "group": "nightly"}`), false)
+	require.NoError(t, err)
+
+	c := req.Apply(base)
+	assert.Equal(t, "42", c.Progress)
+	assert.Equal(t, "3/7", c.ProgressStatus)
+	assert.Equal(t, "backup", c.Tag)
+	assert.Equal(t, "nightly", c.Group)
+	assert.NoError(t, c.Validate())
+}
+
+func TestDecodeRequestFields(t *testing.T) {
+	data := []byte(`{"title": "Deploy", "service": "api", "took": 93.5, "replicas": 3}`)
+
+	_, err := DecodeRequest(data, false)
+	assert.ErrorContains(t, err, `unknown field "service"`)
+
+	req, err := DecodeRequest(data, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Deploy", req.Title)
+	assert.Equal(t, "api", req.Fields["service"])
+	assert.Equal(t, "3", fmt.Sprint(req.Fields["replicas"]), "numbers keep their JSON form")
+	assert.Equal(t, "93.5", fmt.Sprint(req.Fields["took"]))
+}
@@ -0,0 +1,242 @@
+// Package daemon implements the long-running notification servers.
+package daemon
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"wsl-notify-send/internal/notify"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+// D-Bus names of the freedesktop notification service
+const (
+	DBusName      = "org.freedesktop.Notifications"
+	DBusPath      = dbus.ObjectPath("/org/freedesktop/Notifications")
+	DBusInterface = "org.freedesktop.Notifications"
+)
+
+// closedByCall is the NotificationClosed reason for CloseNotification
+const closedByCall uint32 = 3
+
+const introspectXML = introspect.IntrospectDeclarationString + `<node>` + introspect.IntrospectDataString + `
+<interface name="org.freedesktop.Notifications">
+	<method name="Notify">
+		<arg name="app_name" type="s" direction="in"/>
+		<arg name="replaces_id" type="u" direction="in"/>
+		<arg name="app_icon" type="s" direction="in"/>
+		<arg name="summary" type="s" direction="in"/>
+		<arg name="body" type="s" direction="in"/>
+		<arg name="actions" type="as" direction="in"/>
+		<arg name="hints" type="a{sv}" direction="in"/>
+		<arg name="expire_timeout" type="i" direction="in"/>
+		<arg name="id" type="u" direction="out"/>
+	</method>
+	<method name="CloseNotification">
+		<arg name="id" type="u" direction="in"/>
+	</method>
+	<method name="GetCapabilities">
+		<arg name="capabilities" type="as" direction="out"/>
+	</method>
+	<method name="GetServerInformation">
+		<arg name="name" type="s" direction="out"/>
+		<arg name="vendor" type="s" direction="out"/>
+		<arg name="version" type="s" direction="out"/>
+		<arg name="spec_version" type="s" direction="out"/>
+	</method>
+	<signal name="NotificationClosed">
+		<arg name="id" type="u"/>
+		<arg name="reason" type="u"/>
+	</signal>
+	<signal name="ActionInvoked">
+		<arg name="id" type="u"/>
+		<arg name="action_key" type="s"/>
+	</signal>
+</interface>
+</node>`
+
+// DBusServer implements the org.freedesktop.Notifications service and
+// forwards every notification it receives to a notify.Beeper
+type DBusServer struct {
+	// ErrorLog receives the problems that don't fail a notification, like
+	// an icon that can't be read. Nil logs through the log package.
+	ErrorLog *log.Logger
+
+	conn    *dbus.Conn
+	beeper  notify.Beeper
+	version string
+
+	mu     sync.Mutex
+	nextID uint32
+	open   map[uint32]struct{}
+}
+
+// NewDBusServer creates a server that forwards notifications to b
+func NewDBusServer(conn *dbus.Conn, b notify.Beeper, version string) *DBusServer {
+	return &DBusServer{
+		conn:    conn,
+		beeper:  b,
+		version: version,
+		open:    make(map[uint32]struct{}),
+	}
+}
+
+// Start exports the service and claims its well-known name on the bus
+func (s *DBusServer) Start() error {
+	if err := s.conn.Export(s, DBusPath, DBusInterface); err != nil {
+		return err
+	}
+
+	if err := s.conn.Export(introspect.Introspectable(introspectXML), DBusPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return err
+	}
+
+	reply, err := s.conn.RequestName(DBusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return errors.New(DBusName + " is already owned by another process")
+	}
+
+	return nil
+}
+
+// Stop releases the well-known name and unexports the service
+func (s *DBusServer) Stop() error {
+	_ = s.conn.Export(nil, DBusPath, DBusInterface)
+	_ = s.conn.Export(nil, DBusPath, "org.freedesktop.DBus.Introspectable")
+	_, err := s.conn.ReleaseName(DBusName)
+	return err
+}
+
+// logf reports a problem to ErrorLog
+func (s *DBusServer) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// Notify implements org.freedesktop.Notifications.Notify
+func (s *DBusServer) Notify(appName string, replacesID uint32, appIcon, summary, body string, actions []string, hints map[string]dbus.Variant, expireTimeout int32) (uint32, *dbus.Error) {
+	opts := OptionsFromDBus(actions, hints, expireTimeout)
+	opts.ReplaceID = replacesID
+
+	// image-path takes precedence over app_icon, as in the spec
+	icon := appIcon
+	if v, ok := hints["image-path"]; ok {
+		if path, ok := v.Value().(string); ok && path != "" {
+			icon = path
+		}
+	}
+	icon = strings.TrimPrefix(icon, "file://")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A bad icon is no reason to lose the notification
+	_, err := notify.Send(s.beeper, summary, body, icon, appName, opts, false)
+	if errors.Is(err, notify.ErrIconUnreadable) {
+		s.logf("showing %q without its icon: %v", summary, err)
+		_, err = notify.Send(s.beeper, summary, body, "", appName, opts, false)
+	}
+	if err != nil {
+		return 0, dbus.MakeFailedError(err)
+	}
+
+	id := replacesID
+	if _, ok := s.open[id]; !ok || id == 0 {
+		s.nextID++
+		id = s.nextID
+	}
+	s.open[id] = struct{}{}
+
+	return id, nil
+}
+
+// CloseNotification implements org.freedesktop.Notifications.CloseNotification.
+// Windows toasts can't be withdrawn, so this only emits NotificationClosed.
+func (s *DBusServer) CloseNotification(id uint32) *dbus.Error {
+	s.mu.Lock()
+	_, ok := s.open[id]
+	delete(s.open, id)
+	s.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	if err := s.conn.Emit(DBusPath, DBusInterface+".NotificationClosed", id, closedByCall); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// GetCapabilities implements org.freedesktop.Notifications.GetCapabilities
+func (s *DBusServer) GetCapabilities() ([]string, *dbus.Error) {
+	return []string{"body", "icon-static"}, nil
+}
+
+// GetServerInformation implements org.freedesktop.Notifications.GetServerInformation
+func (s *DBusServer) GetServerInformation() (string, string, string, string, *dbus.Error) {
+	return "wsl-notify-send", "wsl-notify-send", s.version, "1.2", nil
+}
+
+// OptionsFromDBus converts the actions, hints and timeout of a Notify call
+// into notify.Options. The well-known urgency, category and transient hints
+// are mapped onto their fields, every other hint is passed through.
+func OptionsFromDBus(actions []string, hints map[string]dbus.Variant, expireTimeout int32) notify.Options {
+	opts := notify.Options{
+		ExpireTimeout: int(expireTimeout),
+	}
+
+	// Actions are a flat list of key, label pairs
+	for i := 0; i+1 < len(actions); i += 2 {
+		opts.Actions = append(opts.Actions, notify.Action{Key: actions[i], Label: actions[i+1]})
+	}
+
+	for name, v := range hints {
+		switch name {
+		case "urgency":
+			if level, ok := v.Value().(byte); ok {
+				opts.Urgency = urgencyFromByte(level)
+			}
+		case "category":
+			if category, ok := v.Value().(string); ok {
+				opts.Category = category
+			}
+		case "transient":
+			if transient, ok := v.Value().(bool); ok {
+				opts.Transient = transient
+			}
+		case "image-path", "image-data", "image_data", "icon_data":
+			// Handled as the icon, image data is not supported
+		default:
+			opts.Hints = append(opts.Hints, notify.Hint{Name: name, Value: v.Value()})
+		}
+	}
+
+	// Map iteration order is random, keep the hints stable
+	sort.Slice(opts.Hints, func(i, j int) bool {
+		return opts.Hints[i].Name < opts.Hints[j].Name
+	})
+
+	return opts
+}
+
+func urgencyFromByte(level byte) string {
+	switch level {
+	case 0:
+		return notify.UrgencyLow
+	case 2:
+		return notify.UrgencyCritical
+	default:
+		return notify.UrgencyNormal
+	}
+}
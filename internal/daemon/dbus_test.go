@@ -0,0 +1,284 @@
+package daemon
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+	"wsl-notify-send/internal/dbustest"
+	"wsl-notify-send/internal/notify"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockBeeper is a mock implementation of the Beeper interface
+type MockBeeper struct {
+	mock.Mock
+}
+
+func (m *MockBeeper) Notify(title, message string, icon interface{}) error {
+	args := m.Called(title, message, icon)
+	return args.Error(0)
+}
+
+func (m *MockBeeper) Alert(title, message string, icon interface{}) error {
+	args := m.Called(title, message, icon)
+	return args.Error(0)
+}
+
+func (m *MockBeeper) Beep(freq float64, duration int) error {
+	args := m.Called(freq, duration)
+	return args.Error(0)
+}
+
+func (m *MockBeeper) SetAppName(name string) {
+	m.Called(name)
+}
+
+// lockedBuffer is a bytes.Buffer the server can log to while the test reads
+// it
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// startServer runs a DBusServer on a private bus and returns a client
+// connection to the same bus
+func startServer(t *testing.T, b notify.Beeper) *dbus.Conn {
+	return startServerWithLog(t, b, log.New(io.Discard, "", 0))
+}
+
+// startServerWithLog is startServer with the server logging to errorLog
+func startServerWithLog(t *testing.T, b notify.Beeper, errorLog *log.Logger) *dbus.Conn {
+	address := dbustest.StartSessionBus(t)
+
+	serverConn, err := dbus.Connect(address)
+	require.NoError(t, err)
+	t.Cleanup(func() { serverConn.Close() })
+
+	server := NewDBusServer(serverConn, b, "1.2.3")
+	server.ErrorLog = errorLog
+	require.NoError(t, server.Start())
+
+	clientConn, err := dbus.Connect(address)
+	require.NoError(t, err)
+	t.Cleanup(func() { clientConn.Close() })
+
+	return clientConn
+}
+
+func callNotify(conn *dbus.Conn, appName string, replacesID uint32, summary, body string, hints map[string]dbus.Variant) (uint32, error) {
+	var id uint32
+	err := conn.Object(DBusName, DBusPath).Call(DBusInterface+".Notify", 0,
+		appName, replacesID, "", summary, body, []string{}, hints, int32(-1)).Store(&id)
+	return id, err
+}
+
+func TestDBusServer_Notify(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	conn := startServer(t, mockBeeper)
+
+	mockBeeper.On("SetAppName", "MyApp").Twice()
+	mockBeeper.On("Notify", "Hello", "World", "").Return(nil).Once()
+	mockBeeper.On("Alert", "Down", "Database", "").Return(nil).Once()
+
+	id, err := callNotify(conn, "MyApp", 0, "Hello", "World", map[string]dbus.Variant{})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), id)
+
+	// Critical urgency is delivered as an alert
+	id, err = callNotify(conn, "MyApp", 0, "Down", "Database", map[string]dbus.Variant{
+		"urgency": dbus.MakeVariant(byte(2)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), id)
+
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestDBusServer_NotifyReplacesID(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	conn := startServer(t, mockBeeper)
+
+	mockBeeper.On("Notify", "Progress", mock.Anything, "").Return(nil).Times(3)
+
+	id, err := callNotify(conn, "", 0, "Progress", "10%", map[string]dbus.Variant{})
+	require.NoError(t, err)
+
+	replaced, err := callNotify(conn, "", id, "Progress", "50%", map[string]dbus.Variant{})
+	require.NoError(t, err)
+	assert.Equal(t, id, replaced)
+
+	// Unknown IDs get a fresh one
+	fresh, err := callNotify(conn, "", 99, "Progress", "90%", map[string]dbus.Variant{})
+	require.NoError(t, err)
+	assert.NotEqual(t, uint32(99), fresh)
+
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestDBusServer_NotifyFailure(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	conn := startServer(t, mockBeeper)
+
+	mockBeeper.On("Notify", "Hello", "World", "").Return(errors.New("toast failed")).Once()
+
+	_, err := callNotify(conn, "", 0, "Hello", "World", map[string]dbus.Variant{})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "toast failed")
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestDBusServer_NotifyIcon(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	logged := &lockedBuffer{}
+	conn := startServerWithLog(t, mockBeeper, log.New(logged, "", 0))
+
+	iconPath := filepath.Join(t.TempDir(), "icon.png")
+	require.NoError(t, os.WriteFile(iconPath, []byte("png data"), 0o644))
+
+	// Icon files are read as the CLI reads them, and names are left as
+	// stock icons
+	mockBeeper.On("Notify", "File", "World", []byte("png data")).Return(nil).Once()
+	mockBeeper.On("Notify", "Stock", "World", "dialog-information").Return(nil).Once()
+	mockBeeper.On("Notify", "Missing", "World", "").Return(nil).Once()
+
+	_, err := callNotify(conn, "", 0, "File", "World", map[string]dbus.Variant{
+		"image-path": dbus.MakeVariant("file://" + iconPath),
+	})
+	require.NoError(t, err)
+
+	_, err = callNotify(conn, "", 0, "Stock", "World", map[string]dbus.Variant{
+		"image-path": dbus.MakeVariant("dialog-information"),
+	})
+	require.NoError(t, err)
+
+	// An icon that can't be read is dropped rather than the notification
+	_, err = callNotify(conn, "", 0, "Missing", "World", map[string]dbus.Variant{
+		"image-path": dbus.MakeVariant(filepath.Join(t.TempDir(), "missing.png")),
+	})
+	require.NoError(t, err)
+	assert.Contains(t, logged.String(), `showing "Missing" without its icon: failed to process icon`)
+
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestDBusServer_CloseNotification(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	conn := startServer(t, mockBeeper)
+
+	mockBeeper.On("Notify", "Hello", "World", "").Return(nil).Once()
+
+	require.NoError(t, conn.AddMatchSignal(
+		dbus.WithMatchInterface(DBusInterface),
+		dbus.WithMatchMember("NotificationClosed"),
+	))
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+
+	id, err := callNotify(conn, "", 0, "Hello", "World", map[string]dbus.Variant{})
+	require.NoError(t, err)
+
+	err = conn.Object(DBusName, DBusPath).Call(DBusInterface+".CloseNotification", 0, id).Err
+	require.NoError(t, err)
+
+	select {
+	case sig := <-signals:
+		assert.Equal(t, []interface{}{id, uint32(3)}, sig.Body)
+	case <-time.After(5 * time.Second):
+		t.Fatal("NotificationClosed was not emitted")
+	}
+
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestDBusServer_GetCapabilities(t *testing.T) {
+	conn := startServer(t, new(MockBeeper))
+
+	var capabilities []string
+	err := conn.Object(DBusName, DBusPath).Call(DBusInterface+".GetCapabilities", 0).Store(&capabilities)
+
+	require.NoError(t, err)
+	assert.Contains(t, capabilities, "body")
+}
+
+func TestDBusServer_GetServerInformation(t *testing.T) {
+	conn := startServer(t, new(MockBeeper))
+
+	var name, vendor, version, specVersion string
+	err := conn.Object(DBusName, DBusPath).Call(DBusInterface+".GetServerInformation", 0).
+		Store(&name, &vendor, &version, &specVersion)
+
+	require.NoError(t, err)
+	assert.Equal(t, "wsl-notify-send", name)
+	assert.Equal(t, "1.2.3", version)
+	assert.Equal(t, "1.2", specVersion)
+}
+
+func TestDBusServer_NameAlreadyOwned(t *testing.T) {
+	address := dbustest.StartSessionBus(t)
+
+	first, err := dbus.Connect(address)
+	require.NoError(t, err)
+	defer first.Close()
+	require.NoError(t, NewDBusServer(first, new(MockBeeper), "1.0").Start())
+
+	second, err := dbus.Connect(address)
+	require.NoError(t, err)
+	defer second.Close()
+
+	err = NewDBusServer(second, new(MockBeeper), "1.0").Start()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already owned")
+}
+
+func TestOptionsFromDBus(t *testing.T) {
+	opts := OptionsFromDBus(
+		[]string{"default", "Open", "retry", "Retry"},
+		map[string]dbus.Variant{
+			"urgency":    dbus.MakeVariant(byte(0)),
+			"category":   dbus.MakeVariant("email.arrived"),
+			"transient":  dbus.MakeVariant(true),
+			"image-path": dbus.MakeVariant("/tmp/icon.png"),
+			"x-b":        dbus.MakeVariant(int32(2)),
+			"x-a":        dbus.MakeVariant("a"),
+		},
+		5000,
+	)
+
+	assert.Equal(t, notify.Options{
+		Urgency:       notify.UrgencyLow,
+		ExpireTimeout: 5000,
+		Category:      "email.arrived",
+		Transient:     true,
+		Actions: []notify.Action{
+			{Key: "default", Label: "Open"},
+			{Key: "retry", Label: "Retry"},
+		},
+		Hints: []notify.Hint{
+			{Name: "x-a", Value: "a"},
+			{Name: "x-b", Value: int32(2)},
+		},
+	}, opts)
+}
@@ -0,0 +1,221 @@
+package daemon
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/notify"
+)
+
+// maxRequestBytes bounds the body of an HTTP request
+const maxRequestBytes = 64 << 10
+
+// HTTP API modes, named after their endpoints
+const (
+	modeNotify = "notify"
+	modeAlert  = "alert"
+	modeBeep   = "beep"
+)
+
+// HTTPServer serves notifications over a local HTTP API: POST /notify,
+// /alert and /beep with a JSON object in the same format as --json. Fields
+// left out keep the values of the base configuration.
+type HTTPServer struct {
+	// Token, when set, must be sent by every request as a bearer token in
+	// the Authorization header. Requests may then use any host name.
+	// Otherwise only requests to localhost or a loopback address are served.
+	Token string
+
+	beeper notify.Beeper
+	base   config.Config
+
+	// mu keeps the app name of one request from leaking into another
+	mu sync.Mutex
+}
+
+// HTTPResponse is the JSON body of every response. ID is the notification
+// ID assigned by the backend; failures carry the error and the exit code
+// the command line would have exited with.
+type HTTPResponse struct {
+	ID       uint32 `json:"id"`
+	Error    string `json:"error,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+}
+
+// NewHTTPServer creates a server that forwards notifications to b, applying
+// each request on top of base
+func NewHTTPServer(b notify.Beeper, base config.Config) *HTTPServer {
+	return &HTTPServer{beeper: b, base: base}
+}
+
+// Handler returns the handler serving the API
+func (s *HTTPServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, mode := range []string{modeNotify, modeAlert, modeBeep} {
+		mux.Handle("/"+mode, s.handle(mode))
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, http.StatusNotFound, HTTPResponse{Error: "not found, use /notify, /alert or /beep"})
+	})
+
+	// A web page can point a name of its own at the loopback address, but
+	// the browser still sends that name as the host, and it can't know the
+	// token
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case s.Token != "":
+			if !s.authorized(r) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeResponse(w, http.StatusUnauthorized, HTTPResponse{Error: "missing or wrong token, send it as Authorization: Bearer TOKEN"})
+				return
+			}
+		case !LoopbackHost(r.Host):
+			writeResponse(w, http.StatusForbidden, HTTPResponse{Error: "host not allowed, use localhost or a loopback address"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// authorized reports whether r carries the token
+func (s *HTTPServer) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+// LoopbackHost reports whether host, as given in the Host header or a
+// listen address, is localhost or a loopback address
+func LoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *HTTPServer) handle(mode string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeResponse(w, http.StatusMethodNotAllowed, HTTPResponse{Error: "method not allowed, use POST"})
+			return
+		}
+
+		// Browsers can't send JSON to another origin without asking first,
+		// so web pages can't raise notifications
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			writeResponse(w, http.StatusUnsupportedMediaType, HTTPResponse{Error: "content type must be application/json"})
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeResponse(w, http.StatusRequestEntityTooLarge, HTTPResponse{Error: "request body too large"})
+				return
+			}
+			writeResponse(w, http.StatusBadRequest, HTTPResponse{Error: err.Error()})
+			return
+		}
+
+		id, err := s.send(mode, data)
+		if err != nil {
+			writeResponse(w, HTTPStatus(err), HTTPResponse{Error: err.Error(), ExitCode: exitcode.Of(err)})
+			return
+		}
+		writeResponse(w, http.StatusOK, HTTPResponse{ID: id})
+	}
+}
+
+// send decodes one request and delivers it the way the command line would
+func (s *HTTPServer) send(mode string, data []byte) (uint32, error) {
+	// A beep needs no settings at all
+	if mode == modeBeep && len(bytes.TrimSpace(data)) == 0 {
+		data = []byte("{}")
+	}
+
+	base := s.base
+	base.AlertMode = mode == modeAlert
+	base.BeepMode = mode == modeBeep
+
+	// Templates may use fields of their own
+	templated := base.TitleTemplate != "" || base.Template != ""
+
+	req, err := config.DecodeRequest(data, templated)
+	if err != nil {
+		return 0, exitcode.Mark(config.ErrInvalidArgs, err)
+	}
+
+	c := req.Apply(base)
+	if err := c.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if c.BeepMode {
+		if err := s.beeper.Beep(c.Frequency, c.Duration); err != nil {
+			return 0, exitcode.Mark(notify.ErrBackendFailed, fmt.Errorf("failed to beep: %w", err))
+		}
+		return 0, nil
+	}
+
+	if req.Title == "" && c.TitleTemplate == "" {
+		return 0, exitcode.Mark(config.ErrInvalidArgs, errors.New("notification has no title"))
+	}
+
+	title, message, err := c.RenderTemplates(req.Title, req.Message, req.Fields)
+	if err != nil {
+		return 0, err
+	}
+
+	opts, err := c.NotifyOptions()
+	if err != nil {
+		return 0, exitcode.Mark(config.ErrInvalidConfig, fmt.Errorf("invalid configuration: %w", err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return notify.Send(s.beeper, title, message, c.Icon, c.AppName, opts, c.AlertMode)
+}
+
+// HTTPStatus maps an error to the HTTP status equivalent to its exit code
+func HTTPStatus(err error) int {
+	switch exitcode.Of(err) {
+	case exitcode.Success:
+		return http.StatusOK
+	case exitcode.InvalidArgs, exitcode.TemplateError:
+		return http.StatusBadRequest
+	case exitcode.IconError:
+		return http.StatusUnprocessableEntity
+	case exitcode.NotificationFailed:
+		return http.StatusBadGateway
+	case exitcode.BackendUnavailable:
+		return http.StatusServiceUnavailable
+	case exitcode.Timeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp HTTPResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,282 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/notify"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// baseConfig holds the defaults of the command-line flags
+var baseConfig = config.Config{
+	AppName:    "wsl-notify-send",
+	Frequency:  587,
+	Duration:   500,
+	Urgency:    notify.UrgencyNormal,
+	ExpireTime: notify.ExpireDefault,
+}
+
+// startHTTPServer serves the HTTP API, forwarding to b
+func startHTTPServer(t *testing.T, b notify.Beeper, base config.Config) *httptest.Server {
+	server := httptest.NewServer(NewHTTPServer(b, base).Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// post sends body to path and decodes the response
+func post(t *testing.T, server *httptest.Server, path, body string) (int, HTTPResponse) {
+	t.Helper()
+
+	resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var decoded HTTPResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+	return resp.StatusCode, decoded
+}
+
+func TestHTTPServer_Notify(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	server := startHTTPServer(t, mockBeeper, baseConfig)
+
+	mockBeeper.On("SetAppName", "CI").Once()
+	mockBeeper.On("Notify", "Build", "Done", "").Return(nil).Once()
+
+	status, resp := post(t, server, "/notify", `{"title": "Build", "message": "Done", "app_name": "CI"}`)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, HTTPResponse{}, resp)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestHTTPServer_Alert(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	server := startHTTPServer(t, mockBeeper, baseConfig)
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Alert", "Down", "Database", "").Return(nil).Once()
+
+	status, _ := post(t, server, "/alert", `{"title": "Down", "message": "Database"}`)
+
+	assert.Equal(t, http.StatusOK, status)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestHTTPServer_Beep(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	server := startHTTPServer(t, mockBeeper, baseConfig)
+
+	mockBeeper.On("Beep", 587.0, 500).Return(nil).Once()
+	mockBeeper.On("Beep", 440.0, 200).Return(nil).Once()
+
+	status, _ := post(t, server, "/beep", "")
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = post(t, server, "/beep", `{"frequency": 440, "duration": 200}`)
+	assert.Equal(t, http.StatusOK, status)
+
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestHTTPServer_BaseConfig(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	base := baseConfig
+	base.AppName = "Dev Box"
+	base.TitleTemplate = "[{{.Input.env}}] {{.Title}}"
+	server := startHTTPServer(t, mockBeeper, base)
+
+	mockBeeper.On("SetAppName", "Dev Box").Once()
+	mockBeeper.On("Notify", "[staging] Deploy", "", "").Return(nil).Once()
+
+	// Templates may use fields of their own
+	status, _ := post(t, server, "/notify", `{"title": "Deploy", "env": "staging"}`)
+
+	assert.Equal(t, http.StatusOK, status)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestHTTPServer_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		code   int
+		errMsg string
+	}{
+		{"no title", "/notify", `{"message": "Done"}`, http.StatusBadRequest, exitcode.InvalidArgs, "notification has no title"},
+		{"not json", "/notify", `title=Build`, http.StatusBadRequest, exitcode.InvalidArgs, "cannot parse notification"},
+		{"unknown field", "/notify", `{"title": "Build", "colour": "red"}`, http.StatusBadRequest, exitcode.InvalidArgs, "unknown field"},
+		{"invalid urgency", "/notify", `{"title": "Build", "urgency": "urgent"}`, http.StatusBadRequest, exitcode.InvalidArgs, "invalid urgency"},
+		{"low urgency alert", "/alert", `{"title": "Build", "urgency": "low"}`, http.StatusBadRequest, exitcode.InvalidArgs, "cannot use --alert with low urgency"},
+		{"invalid beep", "/beep", `{"duration": 0}`, http.StatusBadRequest, exitcode.InvalidArgs, "duration must be positive"},
+		{"missing icon", "/notify", `{"title": "Build", "icon": "/nonexistent/icon.png"}`, http.StatusUnprocessableEntity, exitcode.IconError, "icon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBeeper := new(MockBeeper)
+			server := startHTTPServer(t, mockBeeper, baseConfig)
+
+			status, resp := post(t, server, tt.path, tt.body)
+
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.code, resp.ExitCode)
+			assert.Contains(t, resp.Error, tt.errMsg)
+			mockBeeper.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHTTPServer_Icon(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	server := startHTTPServer(t, mockBeeper, baseConfig)
+
+	// Icon files are read like on the command line
+	dir := t.TempDir()
+	icon := filepath.Join(dir, "icon.png")
+	require.NoError(t, os.WriteFile(icon, []byte("png"), 0o600))
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Twice()
+	mockBeeper.On("Notify", "Build", "", []byte("png")).Return(nil).Once()
+
+	status, _ := post(t, server, "/notify", `{"title": "Build", "icon": `+strconv.Quote(icon)+`}`)
+	assert.Equal(t, http.StatusOK, status)
+
+	// One that can't be read is an icon error
+	unreadable := filepath.Join(dir, "dir.png")
+	require.NoError(t, os.Mkdir(unreadable, 0o700))
+
+	status, resp := post(t, server, "/notify", `{"title": "Build", "icon": `+strconv.Quote(unreadable)+`}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, exitcode.IconError, resp.ExitCode)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestHTTPServer_BackendFailure(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	server := startHTTPServer(t, mockBeeper, baseConfig)
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Notify", "Build", "", "").Return(errors.New("toast failed")).Once()
+
+	status, resp := post(t, server, "/notify", `{"title": "Build"}`)
+
+	assert.Equal(t, http.StatusBadGateway, status)
+	assert.Equal(t, exitcode.NotificationFailed, resp.ExitCode)
+	assert.Contains(t, resp.Error, "toast failed")
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestHTTPServer_Requests(t *testing.T) {
+	server := startHTTPServer(t, new(MockBeeper), baseConfig)
+
+	resp, err := http.Get(server.URL + "/notify")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, http.MethodPost, resp.Header.Get("Allow"))
+
+	// Web pages can only send simple requests to another origin
+	resp, err = http.Post(server.URL+"/notify", "text/plain", strings.NewReader(`{"title": "Build"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+"/notify", "application/json", strings.NewReader(`{"title": "`+strings.Repeat("x", maxRequestBytes)+`"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	status, resp2 := post(t, server, "/toast", `{}`)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Contains(t, resp2.Error, "/notify")
+}
+
+func TestHTTPServer_Host(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	server := startHTTPServer(t, mockBeeper, baseConfig)
+
+	// A page on a rebound name reaches the server under that name
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/notify", strings.NewReader(`{"title": "Build"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Host = "attacker.example:8765"
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestHTTPServer_Token(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	handler := NewHTTPServer(mockBeeper, baseConfig)
+	handler.Token = "s3cret"
+	server := httptest.NewServer(handler.Handler())
+	t.Cleanup(server.Close)
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Notify", "Build", "", "").Return(nil).Once()
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong", "Bearer guess", http.StatusUnauthorized},
+		{"not bearer", "Basic s3cret", http.StatusUnauthorized},
+		// With the token, containers can use the name they reach the host by
+		{"valid", "Bearer s3cret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/notify", strings.NewReader(`{"title": "Build"}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			req.Host = "host.docker.internal:8765"
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestLoopbackHost(t *testing.T) {
+	for _, host := range []string{"localhost", "LOCALHOST:8765", "127.0.0.1:8765", "127.1.2.3", "[::1]:8765", "::1"} {
+		assert.True(t, LoopbackHost(host), host)
+	}
+	for _, host := range []string{"", "attacker.example", "localhost.attacker.example:8765", "192.168.1.10:8765", "[::]:8765"} {
+		assert.False(t, LoopbackHost(host), host)
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	assert.Equal(t, http.StatusOK, HTTPStatus(nil))
+	assert.Equal(t, http.StatusServiceUnavailable, HTTPStatus(exitcode.Mark(notify.ErrBackendUnavailable, errors.New("no bus"))))
+	assert.Equal(t, http.StatusGatewayTimeout, HTTPStatus(notify.ErrTimeout))
+	assert.Equal(t, http.StatusInternalServerError, HTTPStatus(errors.New("boom")))
+}
@@ -0,0 +1,33 @@
+//go:build unix
+
+package daemon
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// checkPrivate checks that path belongs to the current user and, for a
+// directory, that nobody else can enter it. Symbolic links are not
+// followed.
+func checkPrivate(path string, dir bool) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || int(stat.Uid) != os.Getuid() {
+		return errors.New(path + " belongs to another user")
+	}
+	if dir {
+		if !info.IsDir() {
+			return errors.New(path + " is not a directory")
+		}
+		if info.Mode().Perm()&0o077 != 0 {
+			return errors.New(path + " can be accessed by other users")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package daemon
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/notify"
+)
+
+// SocketName is the name of the socket in $XDG_RUNTIME_DIR
+const SocketName = "wsl-notify-send.sock"
+
+// protocolVersion is bumped whenever requests or responses change in a way
+// an older daemon would misread
+const protocolVersion = 2
+
+// maxFrameBytes bounds the JSON of one frame
+const maxFrameBytes = 1 << 20
+
+// forwardTimeout bounds a whole exchange with the daemon, including the
+// delivery itself
+const forwardTimeout = 30 * time.Second
+
+// ErrNoDaemon is returned by Forward when no daemon took the request, so it
+// can still be delivered directly
+var ErrNoDaemon = errors.New("no daemon running")
+
+// socketKinds are the failure kinds a delivery can end with. They are sent
+// by name, so the sender can still check for them with errors.Is.
+var socketKinds = []*exitcode.Kind{
+	notify.ErrBackendFailed,
+	notify.ErrIconUnreadable,
+	notify.ErrBackendUnavailable,
+	notify.ErrTimeout,
+	notify.ErrDismissed,
+	notify.ErrUnsafeText,
+	config.ErrInvalidConfig,
+}
+
+// SocketRequest asks the daemon to deliver one notification. Config is the
+// sender's configuration, already validated, and Title and Message are
+// already rendered.
+type SocketRequest struct {
+	Version int           `json:"version"`
+	Backend string        `json:"backend"`
+	Config  config.Config `json:"config"`
+	Title   string        `json:"title"`
+	Message string        `json:"message"`
+}
+
+// SocketResponse answers a SocketRequest. Declined is set when the daemon
+// can't deliver the way the sender would have, which then delivers itself.
+type SocketResponse struct {
+	ID       uint32 `json:"id"`
+	Error    string `json:"error,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+	Declined bool   `json:"declined,omitempty"`
+}
+
+// SocketPath returns where the daemon listens: $XDG_RUNTIME_DIR, or a
+// per-user directory in the temporary directory when it isn't set
+func SocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, SocketName)
+	}
+	return filepath.Join(os.TempDir(), "wsl-notify-send-"+strconv.Itoa(os.Getuid()), SocketName)
+}
+
+// SocketServer delivers the notifications handed over by the command line
+// on a Unix socket, saving it the start-up cost of the backend
+type SocketServer struct {
+	beeper  notify.Beeper
+	backend string
+
+	// mu keeps the app name of one request from leaking into another
+	mu sync.Mutex
+}
+
+// NewSocketServer creates a server that delivers through b, the backend
+// named backend ("" for the default one). Requests for another backend
+// are declined.
+func NewSocketServer(b notify.Beeper, backend string) *SocketServer {
+	return &SocketServer{beeper: b, backend: backend}
+}
+
+// ListenSocket listens on path, replacing a socket left behind by a daemon
+// that is no longer running. The directory of the socket is created if
+// needed and must be private to the current user, so nobody else can
+// connect, not even before the socket's own permissions are set.
+func ListenSocket(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, err
+	}
+	if err := checkPrivate(dir, true); err != nil {
+		return nil, err
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, errors.New("a daemon is already listening")
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// Serve answers connections on l until it is closed
+func (s *SocketServer) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn answers requests on conn until the client hangs up
+func (s *SocketServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		var req SocketRequest
+		if err := readFrame(conn, &req); err != nil {
+			return
+		}
+		if err := writeFrame(conn, s.handle(req)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *SocketServer) handle(req SocketRequest) SocketResponse {
+	if req.Version != protocolVersion || req.Backend != s.backend {
+		return SocketResponse{Declined: true}
+	}
+
+	id, err := s.send(req)
+	if err != nil {
+		resp := SocketResponse{Error: err.Error(), ExitCode: exitcode.Of(err)}
+		var kind *exitcode.Kind
+		if errors.As(err, &kind) {
+			resp.Kind = kind.Error()
+		}
+		return resp
+	}
+	return SocketResponse{ID: id}
+}
+
+// send delivers one request the way the command line would
+func (s *SocketServer) send(req SocketRequest) (uint32, error) {
+	c := req.Config
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c.BeepMode {
+		if err := s.beeper.Beep(c.Frequency, c.Duration); err != nil {
+			return 0, exitcode.Mark(notify.ErrBackendFailed, fmt.Errorf("failed to beep: %w", err))
+		}
+		return 0, nil
+	}
+
+	opts, err := c.NotifyOptions()
+	if err != nil {
+		return 0, exitcode.Mark(config.ErrInvalidConfig, fmt.Errorf("invalid configuration: %w", err))
+	}
+	return notify.Send(s.beeper, req.Title, req.Message, c.Icon, c.AppName, opts, c.AlertMode)
+}
+
+// Forward hands req over to the daemon listening on path and returns its
+// result. It returns ErrNoDaemon when there is no daemon or it declined,
+// and the request can be delivered directly without showing it twice. A
+// socket that another user could have put there is never connected to, as
+// the daemon sees the whole notification.
+func Forward(path string, req SocketRequest) (uint32, error) {
+	if checkPrivate(filepath.Dir(path), true) != nil || checkPrivate(path, false) != nil {
+		return 0, ErrNoDaemon
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return 0, ErrNoDaemon
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(forwardTimeout))
+
+	req.Version = protocolVersion
+	if err := writeFrame(conn, req); err != nil {
+		return 0, ErrNoDaemon
+	}
+
+	// The daemon may have delivered already, so falling back could show
+	// the notification twice
+	var resp SocketResponse
+	if err := readFrame(conn, &resp); err != nil {
+		return 0, exitcode.Mark(notify.ErrBackendFailed, fmt.Errorf("no answer from daemon: %w", err))
+	}
+
+	switch {
+	case resp.Declined:
+		return 0, ErrNoDaemon
+	case resp.Error != "":
+		return 0, remoteError(resp)
+	default:
+		return resp.ID, nil
+	}
+}
+
+// remoteError rebuilds the error the daemon answered with, classified as
+// the kind it had there
+func remoteError(resp SocketResponse) error {
+	err := errors.New(resp.Error)
+	for _, kind := range socketKinds {
+		if kind.Error() == resp.Kind {
+			return exitcode.Mark(kind, err)
+		}
+	}
+	return exitcode.Mark(exitcode.NewKind(resp.Kind, resp.ExitCode), err)
+}
+
+// writeFrame writes v as JSON, preceded by its length as a big-endian
+// 32-bit integer
+func writeFrame(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxFrameBytes {
+		return fmt.Errorf("frame of %d bytes is too large", len(data))
+	}
+
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+	_, err = w.Write(frame)
+	return err
+}
+
+// readFrame reads a frame written by writeFrame into v
+func readFrame(r io.Reader, v interface{}) error {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameBytes {
+		return fmt.Errorf("frame of %d bytes is too large", size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
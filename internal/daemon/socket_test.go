@@ -0,0 +1,265 @@
+package daemon
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/notify"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// socketPath returns a socket path in a directory that doesn't exist yet,
+// so ListenSocket creates it private
+func socketPath(tb testing.TB) string {
+	return filepath.Join(tb.TempDir(), "runtime", SocketName)
+}
+
+// startSocketServer serves the socket protocol in a temporary directory,
+// delivering through b, and returns the path of the socket
+func startSocketServer(tb testing.TB, b notify.Beeper, backend string) string {
+	tb.Helper()
+
+	path := socketPath(tb)
+	listener, err := ListenSocket(path)
+	require.NoError(tb, err)
+	tb.Cleanup(func() { listener.Close() })
+
+	go NewSocketServer(b, backend).Serve(listener)
+	return path
+}
+
+func TestSocketServer_Notify(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	path := startSocketServer(t, mockBeeper, "")
+
+	mockBeeper.On("SetAppName", "CI").Once()
+	mockBeeper.On("Notify", "Build", "Done", "").Return(nil).Once()
+	mockBeeper.On("SetAppName", "wsl-notify-send").Once()
+	mockBeeper.On("Alert", "Down", "Database", "").Return(nil).Once()
+	mockBeeper.On("Beep", 440.0, 200).Return(nil).Once()
+
+	c := baseConfig
+	c.AppName = "CI"
+	_, err := Forward(path, SocketRequest{Config: c, Title: "Build", Message: "Done"})
+	require.NoError(t, err)
+
+	c = baseConfig
+	c.AlertMode = true
+	_, err = Forward(path, SocketRequest{Config: c, Title: "Down", Message: "Database"})
+	require.NoError(t, err)
+
+	c = baseConfig
+	c.BeepMode = true
+	c.Frequency = 440
+	c.Duration = 200
+	_, err = Forward(path, SocketRequest{Config: c})
+	require.NoError(t, err)
+
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestSocketServer_ID(t *testing.T) {
+	fake := notify.NewFakeBeeper("")
+	path := startSocketServer(t, fake, "fake")
+
+	for want := uint32(1); want <= 2; want++ {
+		id, err := Forward(path, SocketRequest{Backend: "fake", Config: baseConfig, Title: "Build"})
+		require.NoError(t, err)
+		assert.Equal(t, want, id)
+	}
+
+	c := baseConfig
+	c.ReplaceID = 1
+	id, err := Forward(path, SocketRequest{Backend: "fake", Config: c, Title: "Build"})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), id)
+}
+
+func TestSocketServer_Errors(t *testing.T) {
+	mockBeeper := new(MockBeeper)
+	path := startSocketServer(t, mockBeeper, "")
+
+	mockBeeper.On("SetAppName", "wsl-notify-send").Twice()
+	mockBeeper.On("Notify", "Build", "", "").Return(errors.New("toast failed")).Once()
+
+	_, err := Forward(path, SocketRequest{Config: baseConfig, Title: "Build"})
+	assert.ErrorContains(t, err, "toast failed")
+	assert.ErrorIs(t, err, notify.ErrBackendFailed)
+	assert.Equal(t, exitcode.NotificationFailed, exitcode.Of(err))
+
+	c := baseConfig
+	c.Icon = "/nonexistent/icon.png"
+	_, err = Forward(path, SocketRequest{Config: c, Title: "Build"})
+	assert.ErrorContains(t, err, "icon")
+	assert.ErrorIs(t, err, notify.ErrIconUnreadable)
+	assert.Equal(t, exitcode.IconError, exitcode.Of(err))
+
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestForward_NoDaemon(t *testing.T) {
+	// Nothing listening
+	_, err := Forward(socketPath(t), SocketRequest{Config: baseConfig, Title: "Build"})
+	assert.ErrorIs(t, err, ErrNoDaemon)
+
+	// Another backend than the daemon's
+	mockBeeper := new(MockBeeper)
+	path := startSocketServer(t, mockBeeper, "")
+	_, err = Forward(path, SocketRequest{Backend: "fake", Config: baseConfig, Title: "Build"})
+	assert.ErrorIs(t, err, ErrNoDaemon)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestListenSocket(t *testing.T) {
+	path := socketPath(t)
+
+	// A socket left behind is replaced
+	require.NoError(t, os.Mkdir(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+	listener, err := ListenSocket(path)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// A live one is not
+	_, err = ListenSocket(path)
+	assert.ErrorContains(t, err, "already listening")
+}
+
+func TestListenSocket_PrivateDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes don't tell who can reach the socket on windows")
+	}
+
+	// The directory is created for the socket
+	path := socketPath(t)
+	listener, err := ListenSocket(path)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	info, err := os.Stat(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+	// One that others can enter is refused
+	dir := filepath.Join(t.TempDir(), "shared")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	require.NoError(t, os.Chmod(dir, 0o755))
+	_, err = ListenSocket(filepath.Join(dir, SocketName))
+	assert.ErrorContains(t, err, "can be accessed by other users")
+}
+
+func TestForward_SharedDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes don't tell who can reach the socket on windows")
+	}
+
+	// A daemon that others could have put there is not trusted
+	mockBeeper := new(MockBeeper)
+	path := startSocketServer(t, mockBeeper, "")
+	require.NoError(t, os.Chmod(filepath.Dir(path), 0o755))
+
+	_, err := Forward(path, SocketRequest{Config: baseConfig, Title: "Build"})
+	assert.ErrorIs(t, err, ErrNoDaemon)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestRemoteError(t *testing.T) {
+	err := remoteError(SocketResponse{Error: "text refused", Kind: notify.ErrUnsafeText.Error(), ExitCode: exitcode.InvalidArgs})
+	assert.EqualError(t, err, "text refused")
+	assert.ErrorIs(t, err, notify.ErrUnsafeText)
+	assert.NotErrorIs(t, err, config.ErrInvalidConfig)
+
+	// Kinds from a newer daemon keep their exit code
+	err = remoteError(SocketResponse{Error: "failed", Kind: "something new", ExitCode: 42})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, 42, exitcode.Of(err))
+}
+
+func TestSocketPath(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	assert.Equal(t, "/run/user/1000/wsl-notify-send.sock", SocketPath())
+
+	// Without it the socket gets a directory of its own
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	assert.True(t, strings.HasPrefix(SocketPath(), os.TempDir()))
+	assert.NotEqual(t, os.TempDir(), filepath.Dir(SocketPath()))
+}
+
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeFrame(&buf, SocketResponse{ID: 7}))
+	assert.Equal(t, []byte{0, 0, 0, 8}, buf.Bytes()[:4])
+
+	var resp SocketResponse
+	require.NoError(t, readFrame(&buf, &resp))
+	assert.Equal(t, SocketResponse{ID: 7}, resp)
+
+	// The length is checked before anything is allocated
+	err := readFrame(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff}), &resp)
+	assert.ErrorContains(t, err, "too large")
+
+	// A client that hangs up mid-frame doesn't take the server down
+	path := startSocketServer(t, notify.NewFakeBeeper(""), "fake")
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte{0, 0, 1})
+	require.NoError(t, err)
+	conn.Close()
+
+	_, err = Forward(path, SocketRequest{Backend: "fake", Config: baseConfig, Title: "Build"})
+	assert.NoError(t, err)
+}
+
+// The benchmarks measure what handing over to the daemon adds to the
+// delivery itself, through the fake backend so nothing is shown. It is paid
+// instead of starting the backend, which direct delivery does on every
+// command.
+
+func BenchmarkDeliverDirect(b *testing.B) {
+	fake := notify.NewFakeBeeper("")
+	opts, err := baseConfig.NotifyOptions()
+	require.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := notify.Send(fake, "Build", "Done", "", "wsl-notify-send", opts, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDeliverSocket(b *testing.B) {
+	path := startSocketServer(b, notify.NewFakeBeeper(""), "fake")
+	req := SocketRequest{Backend: "fake", Config: baseConfig, Title: "Build", Message: "Done"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Forward(path, req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkForwardNoDaemon(b *testing.B) {
+	path := socketPath(b)
+	req := SocketRequest{Config: config.Config{}, Title: "Build"}
+
+	for i := 0; i < b.N; i++ {
+		if _, err := Forward(path, req); !errors.Is(err, ErrNoDaemon) {
+			b.Fatal(err)
+		}
+	}
+}
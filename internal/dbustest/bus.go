@@ -0,0 +1,41 @@
+// Package dbustest starts private D-Bus session buses for tests.
+package dbustest
+
+import (
+	"bufio"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// StartSessionBus launches a private dbus-daemon and returns its address.
+// The test is skipped when dbus-daemon is not installed, and the daemon is
+// stopped when the test finishes.
+func StartSessionBus(t *testing.T) string {
+	t.Helper()
+
+	path, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not available")
+	}
+
+	cmd := exec.Command(path, "--session", "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Failed to create dbus-daemon pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read dbus-daemon address: %v", err)
+	}
+
+	return strings.TrimSpace(address)
+}
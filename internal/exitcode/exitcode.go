@@ -0,0 +1,102 @@
+// Package exitcode classifies errors by the process exit code they map to.
+package exitcode
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Process exit codes
+const (
+	Success            = 0
+	General            = 1
+	InvalidArgs        = 2
+	NotificationFailed = 3
+	IconError          = 4
+	BackendUnavailable = 5
+	Timeout            = 6
+	Dismissed          = 7
+	TemplateError      = 8
+
+	// Shell conventions for commands that could not be run
+	CannotExecute   = 126
+	CommandNotFound = 127
+)
+
+// Kind is a sentinel error that classifies failures and carries the exit
+// code they map to. Check for one with errors.Is.
+type Kind struct {
+	msg  string
+	code int
+}
+
+// NewKind creates a sentinel for failures that exit with code
+func NewKind(msg string, code int) *Kind {
+	return &Kind{msg: msg, code: code}
+}
+
+func (k *Kind) Error() string {
+	return k.msg
+}
+
+// Code returns the exit code for this kind of failure
+func (k *Kind) Code() int {
+	return k.code
+}
+
+// marked is an error classified as a Kind without changing its message
+type marked struct {
+	kind *Kind
+	err  error
+}
+
+func (m *marked) Error() string {
+	return m.err.Error()
+}
+
+func (m *marked) Unwrap() []error {
+	return []error{m.kind, m.err}
+}
+
+// Mark classifies err as kind while keeping its message. Errors that are
+// already classified keep their kind, so the most specific one wins.
+func Mark(kind *Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var existing *Kind
+	if errors.As(err, &existing) {
+		return err
+	}
+
+	return &marked{kind: kind, err: err}
+}
+
+// Status is an error that only carries an exit code, for commands that exit
+// with the status of a child process. It has nothing else to report.
+type Status int
+
+func (s Status) Error() string {
+	return "exit status " + strconv.Itoa(int(s))
+}
+
+// Of returns the exit code for err: Success for nil, the code of its Status
+// or Kind if it has one and General otherwise
+func Of(err error) int {
+	if err == nil {
+		return Success
+	}
+
+	var status Status
+	if errors.As(err, &status) {
+		return int(status)
+	}
+
+	var kind *Kind
+	if errors.As(err, &kind) {
+		return kind.Code()
+	}
+
+	return General
+}
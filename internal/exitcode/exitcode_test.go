@@ -0,0 +1,50 @@
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errTestA = NewKind("kind a", 10)
+	errTestB = NewKind("kind b", 11)
+)
+
+func TestMark(t *testing.T) {
+	err := Mark(errTestA, errors.New("something broke"))
+
+	assert.Equal(t, "something broke", err.Error())
+	assert.ErrorIs(t, err, errTestA)
+	assert.Equal(t, 10, Of(err))
+}
+
+func TestMarkNil(t *testing.T) {
+	assert.NoError(t, Mark(errTestA, nil))
+}
+
+func TestMarkKeepsMostSpecificKind(t *testing.T) {
+	inner := Mark(errTestA, errors.New("icon missing"))
+	outer := Mark(errTestB, fmt.Errorf("invalid configuration: %w", inner))
+
+	assert.Equal(t, "invalid configuration: icon missing", outer.Error())
+	assert.ErrorIs(t, outer, errTestA)
+	assert.NotErrorIs(t, outer, errTestB)
+	assert.Equal(t, 10, Of(outer))
+}
+
+func TestOf(t *testing.T) {
+	assert.Equal(t, Success, Of(nil))
+	assert.Equal(t, General, Of(errors.New("plain error")))
+	assert.Equal(t, 11, Of(fmt.Errorf("wrapped: %w", errTestB)))
+}
+
+func TestStatus(t *testing.T) {
+	err := fmt.Errorf("child failed: %w", Status(42))
+
+	assert.Equal(t, "child failed: exit status 42", err.Error())
+	assert.Equal(t, 42, Of(err))
+	assert.Equal(t, Success, Of(Status(0)))
+}
@@ -0,0 +1,262 @@
+// Package hook turns the JSON payloads of coding agent hooks into
+// notifications.
+package hook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"wsl-notify-send/internal/notify"
+	"wsl-notify-send/internal/tmpl"
+)
+
+// Stock icons used for the default notifications
+const (
+	iconInfo    = "dialog-information"
+	iconWarning = "dialog-warning"
+	iconError   = "dialog-error"
+)
+
+// maxDetailLength bounds prompts and commands shown in a notification
+const maxDetailLength = 200
+
+// ClaudeEvent is the payload Claude Code passes to hook commands on stdin.
+// Fields that don't apply to an event are left empty.
+type ClaudeEvent struct {
+	SessionID      string                 `json:"session_id"`
+	TranscriptPath string                 `json:"transcript_path"`
+	Cwd            string                 `json:"cwd"`
+	Event          string                 `json:"hook_event_name"`
+	ToolName       string                 `json:"tool_name"`
+	ToolInput      map[string]interface{} `json:"tool_input"`
+	ToolResponse   interface{}            `json:"tool_response"`
+	Message        string                 `json:"message"`
+	Prompt         string                 `json:"prompt"`
+	Source         string                 `json:"source"`
+	Reason         string                 `json:"reason"`
+	Trigger        string                 `json:"trigger"`
+	StopHookActive bool                   `json:"stop_hook_active"`
+}
+
+// ParseClaude reads a hook payload
+func ParseClaude(r io.Reader) (ClaudeEvent, error) {
+	var e ClaudeEvent
+	if err := json.NewDecoder(r).Decode(&e); err != nil {
+		if errors.Is(err, io.EOF) {
+			return ClaudeEvent{}, errors.New("no hook payload on stdin")
+		}
+		return ClaudeEvent{}, fmt.Errorf("cannot parse hook payload: %w", err)
+	}
+	if e.Event == "" {
+		return ClaudeEvent{}, errors.New("hook payload has no hook_event_name")
+	}
+	return e, nil
+}
+
+// Command returns the shell command of a Bash tool call
+func (e ClaudeEvent) Command() string {
+	return e.input("command")
+}
+
+// FilePath returns the file a tool call reads or changes
+func (e ClaudeEvent) FilePath() string {
+	if path := e.input("file_path"); path != "" {
+		return path
+	}
+	return e.input("notebook_path")
+}
+
+// Description returns the description Claude gave a tool call
+func (e ClaudeEvent) Description() string {
+	return e.input("description")
+}
+
+// Project returns the name of the working directory
+func (e ClaudeEvent) Project() string {
+	if e.Cwd == "" {
+		return ""
+	}
+	return filepath.Base(e.Cwd)
+}
+
+// Failed reports whether a tool call failed: a failure event, or a
+// response that is marked as an error, was interrupted or has a non-zero
+// exit code
+func (e ClaudeEvent) Failed() bool {
+	if e.Event == "PostToolUseFailure" {
+		return true
+	}
+
+	response, ok := e.ToolResponse.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, key := range []string{"is_error", "isError", "interrupted"} {
+		if v, ok := response[key].(bool); ok && v {
+			return true
+		}
+	}
+	for _, key := range []string{"exit_code", "exitCode", "returnCode"} {
+		if v, ok := response[key].(float64); ok && v != 0 {
+			return true
+		}
+	}
+	if v, ok := response["error"].(string); ok && v != "" {
+		return true
+	}
+	return false
+}
+
+func (e ClaudeEvent) input(key string) string {
+	if v, ok := e.ToolInput[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// Notification is what a hook event is shown as
+type Notification struct {
+	Title   string
+	Body    string
+	Urgency string
+	Icon    string
+	Alert   bool
+}
+
+// Notification maps the event to its default notification
+func (e ClaudeEvent) Notification() Notification {
+	n := Notification{Urgency: notify.UrgencyNormal, Icon: iconInfo}
+
+	switch e.Event {
+	case "Notification":
+		n.Title = "Claude needs your attention"
+		n.Body = e.Message
+		n.Urgency = notify.UrgencyCritical
+		n.Icon = iconWarning
+		n.Alert = true
+	case "Stop":
+		n.Title = "Claude finished"
+		n.Body = "Finished responding"
+	case "SubagentStop":
+		n.Title = "Subagent finished"
+		n.Body = "A subagent finished its task"
+	case "PreToolUse":
+		n.Title = "Claude wants to use " + e.ToolName
+		n.Body = e.toolDetail()
+	case "PostToolUse", "PostToolUseFailure":
+		if e.Failed() {
+			n.Title = e.ToolName + " failed"
+			n.Icon = iconError
+			n.Alert = true
+		} else {
+			n.Title = e.ToolName + " finished"
+		}
+		n.Body = e.toolDetail()
+	case "UserPromptSubmit":
+		n.Title = "Prompt submitted"
+		n.Body = e.Prompt
+		n.Urgency = notify.UrgencyLow
+	case "PreCompact":
+		n.Title = "Compacting conversation"
+		n.Body = joinNonEmpty("Trigger: ", e.Trigger)
+		n.Urgency = notify.UrgencyLow
+	case "SessionStart":
+		n.Title = "Session started"
+		n.Body = joinNonEmpty("Source: ", e.Source)
+		n.Urgency = notify.UrgencyLow
+	case "SessionEnd":
+		n.Title = "Session ended"
+		n.Body = joinNonEmpty("Reason: ", e.Reason)
+	default:
+		n.Title = "Claude Code: " + e.Event
+	}
+
+	n.Body = truncate(n.Body, maxDetailLength)
+	if project := e.Project(); project != "" {
+		n.Body = strings.TrimSpace(n.Body + "\nin " + project)
+	}
+
+	return n
+}
+
+// toolDetail describes what a tool call did
+func (e ClaudeEvent) toolDetail() string {
+	for _, detail := range []string{e.Command(), e.FilePath(), e.Description()} {
+		if detail != "" {
+			return detail
+		}
+	}
+	return ""
+}
+
+// Templates override fields of the default notification. Empty templates
+// keep the default.
+type Templates struct {
+	Title   string
+	Body    string
+	Urgency string
+	Icon    string
+}
+
+// templateData is what the templates are executed with
+type templateData struct {
+	ClaudeEvent
+	Default Notification
+}
+
+// Render builds the notification for the event, applying the templates on
+// top of the default mapping
+func Render(e ClaudeEvent, t Templates) (Notification, error) {
+	n := e.Notification()
+	data := templateData{ClaudeEvent: e, Default: n}
+
+	fields := []struct {
+		name string
+		tmpl string
+		dest *string
+	}{
+		{"title", t.Title, &n.Title},
+		{"body", t.Body, &n.Body},
+		{"urgency", t.Urgency, &n.Urgency},
+		{"icon", t.Icon, &n.Icon},
+	}
+
+	for _, f := range fields {
+		if f.tmpl == "" {
+			continue
+		}
+
+		t, err := tmpl.Parse(f.name, f.tmpl)
+		if err != nil {
+			return Notification{}, err
+		}
+
+		value, err := tmpl.Execute(t.Option("missingkey=error"), data)
+		if err != nil {
+			return Notification{}, err
+		}
+		*f.dest = value
+	}
+
+	return n, nil
+}
+
+func joinNonEmpty(prefix, value string) string {
+	if value == "" {
+		return ""
+	}
+	return prefix + value
+}
+
+// truncate shortens s to n characters, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n || n < 1 {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
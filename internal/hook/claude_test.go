@@ -0,0 +1,208 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+	"wsl-notify-send/internal/notify"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, payload string) ClaudeEvent {
+	e, err := ParseClaude(strings.NewReader(payload))
+	require.NoError(t, err)
+	return e
+}
+
+func TestParseClaude(t *testing.T) {
+	e := parse(t, `{
+		"session_id": "abc123",
+		"transcript_path": "/home/me/.claude/projects/x.jsonl",
+		"cwd": "/home/me/src/api",
+		"hook_event_name": "PostToolUse",
+		"tool_name": "Bash",
+		"tool_input": {"command": "go test ./...", "description": "Run tests"},
+		"tool_response": {"stdout": "ok", "stderr": "", "interrupted": false}
+	}`)
+
+	assert.Equal(t, "abc123", e.SessionID)
+	assert.Equal(t, "PostToolUse", e.Event)
+	assert.Equal(t, "Bash", e.ToolName)
+	assert.Equal(t, "go test ./...", e.Command())
+	assert.Equal(t, "Run tests", e.Description())
+	assert.Equal(t, "api", e.Project())
+	assert.False(t, e.Failed())
+}
+
+func TestParseClaudeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		errorMsg string
+	}{
+		{"empty", "", "no hook payload on stdin"},
+		{"invalid json", "{not json", "cannot parse hook payload"},
+		{"no event", `{"session_id": "abc"}`, "hook payload has no hook_event_name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseClaude(strings.NewReader(tt.payload))
+			assert.ErrorContains(t, err, tt.errorMsg)
+		})
+	}
+}
+
+func TestClaudeEventFailed(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected bool
+	}{
+		{"success", `{"hook_event_name": "PostToolUse", "tool_response": {"stdout": "ok"}}`, false},
+		{"no response", `{"hook_event_name": "PostToolUse"}`, false},
+		{"string response", `{"hook_event_name": "PostToolUse", "tool_response": "done"}`, false},
+		{"failure event", `{"hook_event_name": "PostToolUseFailure"}`, true},
+		{"is_error", `{"hook_event_name": "PostToolUse", "tool_response": {"is_error": true}}`, true},
+		{"interrupted", `{"hook_event_name": "PostToolUse", "tool_response": {"interrupted": true}}`, true},
+		{"exit code", `{"hook_event_name": "PostToolUse", "tool_response": {"exit_code": 1}}`, true},
+		{"zero exit code", `{"hook_event_name": "PostToolUse", "tool_response": {"exitCode": 0}}`, false},
+		{"error message", `{"hook_event_name": "PostToolUse", "tool_response": {"error": "boom"}}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parse(t, tt.payload).Failed())
+		})
+	}
+}
+
+func TestClaudeEventNotification(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected Notification
+	}{
+		{
+			name:    "notification",
+			payload: `{"hook_event_name": "Notification", "message": "Claude needs your permission to use Bash", "cwd": "/src/api"}`,
+			expected: Notification{
+				Title:   "Claude needs your attention",
+				Body:    "Claude needs your permission to use Bash\nin api",
+				Urgency: notify.UrgencyCritical,
+				Icon:    iconWarning,
+				Alert:   true,
+			},
+		},
+		{
+			name:    "stop",
+			payload: `{"hook_event_name": "Stop", "cwd": "/src/api"}`,
+			expected: Notification{
+				Title:   "Claude finished",
+				Body:    "Finished responding\nin api",
+				Urgency: notify.UrgencyNormal,
+				Icon:    iconInfo,
+			},
+		},
+		{
+			name:    "failed bash",
+			payload: `{"hook_event_name": "PostToolUse", "tool_name": "Bash", "tool_input": {"command": "make test"}, "tool_response": {"interrupted": true}}`,
+			expected: Notification{
+				Title:   "Bash failed",
+				Body:    "make test",
+				Urgency: notify.UrgencyNormal,
+				Icon:    iconError,
+				Alert:   true,
+			},
+		},
+		{
+			name:    "edit",
+			payload: `{"hook_event_name": "PostToolUse", "tool_name": "Edit", "tool_input": {"file_path": "/src/api/main.go"}}`,
+			expected: Notification{
+				Title:   "Edit finished",
+				Body:    "/src/api/main.go",
+				Urgency: notify.UrgencyNormal,
+				Icon:    iconInfo,
+			},
+		},
+		{
+			name:    "pre tool use",
+			payload: `{"hook_event_name": "PreToolUse", "tool_name": "Bash", "tool_input": {"command": "rm -rf build"}}`,
+			expected: Notification{
+				Title:   "Claude wants to use Bash",
+				Body:    "rm -rf build",
+				Urgency: notify.UrgencyNormal,
+				Icon:    iconInfo,
+			},
+		},
+		{
+			name:    "session end",
+			payload: `{"hook_event_name": "SessionEnd", "reason": "logout"}`,
+			expected: Notification{
+				Title:   "Session ended",
+				Body:    "Reason: logout",
+				Urgency: notify.UrgencyNormal,
+				Icon:    iconInfo,
+			},
+		},
+		{
+			name:    "unknown event",
+			payload: `{"hook_event_name": "Elsewhere"}`,
+			expected: Notification{
+				Title:   "Claude Code: Elsewhere",
+				Urgency: notify.UrgencyNormal,
+				Icon:    iconInfo,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parse(t, tt.payload).Notification())
+		})
+	}
+}
+
+func TestClaudeEventNotificationTruncatesPrompt(t *testing.T) {
+	e := parse(t, `{"hook_event_name": "UserPromptSubmit", "prompt": "`+strings.Repeat("x", 500)+`"}`)
+
+	n := e.Notification()
+	assert.Len(t, []rune(n.Body), maxDetailLength)
+	assert.True(t, strings.HasSuffix(n.Body, "…"))
+}
+
+func TestRender(t *testing.T) {
+	e := parse(t, `{"hook_event_name": "PostToolUse", "tool_name": "Bash", "cwd": "/src/api", "tool_input": {"command": "make"}, "tool_response": {"exit_code": 2}}`)
+
+	n, err := Render(e, Templates{
+		Title:   "{{.Project}}: {{.Default.Title}}",
+		Body:    "{{if .Failed}}✗{{else}}✓{{end}} {{.Command}}",
+		Urgency: `{{if .Failed}}critical{{else}}low{{end}}`,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "api: Bash failed", n.Title)
+	assert.Equal(t, "✗ make", n.Body)
+	assert.Equal(t, "critical", n.Urgency)
+	assert.Equal(t, iconError, n.Icon, "fields without a template keep the default")
+	assert.True(t, n.Alert)
+}
+
+func TestRenderFuncs(t *testing.T) {
+	e := parse(t, `{"hook_event_name": "PostToolUse", "tool_name": "Write", "tool_input": {"file_path": "/src/api/handlers/users.go"}}`)
+
+	n, err := Render(e, Templates{Body: "{{base .FilePath}} {{truncate 5 .ToolName}}"})
+	require.NoError(t, err)
+	assert.Equal(t, "users.go Write", n.Body)
+}
+
+func TestRenderErrors(t *testing.T) {
+	e := parse(t, `{"hook_event_name": "Stop"}`)
+
+	_, err := Render(e, Templates{Title: "{{.Project"})
+	assert.ErrorContains(t, err, "invalid title template")
+
+	_, err = Render(e, Templates{Icon: "{{.NoSuchField}}"})
+	assert.ErrorContains(t, err, "cannot render icon template")
+}
@@ -0,0 +1,51 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lines(n int, prefix string) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		b.WriteString(prefix)
+		b.WriteString(strings.Repeat("x", i))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestUnifiedDiffEqual(t *testing.T) {
+	assert.Empty(t, unifiedDiff("a", "b", "same\n", "same\n"))
+}
+
+func TestUnifiedDiffNewFile(t *testing.T) {
+	diff := unifiedDiff("old", "new", "", "one\ntwo\n")
+	assert.Equal(t, "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+one\n+two\n", diff)
+}
+
+func TestUnifiedDiffContext(t *testing.T) {
+	a := lines(10, "")
+	b := strings.Replace(a, "xxxxx\n", "five\n", 1)
+
+	diff := unifiedDiff("a", "b", a, b)
+	assert.Equal(t, "--- a\n+++ b\n@@ -2,7 +2,7 @@\n xx\n xxx\n xxxx\n-xxxxx\n+five\n xxxxxx\n xxxxxxx\n xxxxxxxx\n", diff)
+}
+
+func TestUnifiedDiffSeparateHunks(t *testing.T) {
+	a := lines(20, "")
+	b := strings.Replace(a, "x\n", "first\n", 1)
+	b = strings.Replace(b, strings.Repeat("x", 20)+"\n", "last\n", 1)
+
+	diff := unifiedDiff("a", "b", a, b)
+	assert.Equal(t, 2, strings.Count(diff, "@@ -"))
+	assert.Contains(t, diff, "@@ -1,4 +1,4 @@\n-x\n+first\n")
+	assert.Contains(t, diff, "@@ -17,4 +17,4 @@\n")
+}
+
+func TestUnifiedDiffRemovalsFirst(t *testing.T) {
+	diff := unifiedDiff("a", "b", "keep\nold\n", "keep\nnew\n")
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,2 +1,2 @@\n keep\n-old\n+new\n", diff)
+}
@@ -0,0 +1,176 @@
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// object is a JSON object that keeps its keys in their original order, so
+// rewriting a settings file only changes what was meant to change. Values
+// are *object, []interface{}, string, json.Number, bool or nil.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newObject() *object {
+	return &object{values: make(map[string]interface{})}
+}
+
+func (o *object) get(key string) (interface{}, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// set replaces the value of key, appending the key if it is new
+func (o *object) set(key string, value interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *object) remove(key string) {
+	if _, ok := o.values[key]; !ok {
+		return
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// parseObject decodes a JSON document that must be an object
+func parseObject(data []byte) (*object, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	v, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(*object)
+	if !ok {
+		return nil, errors.New("expected a JSON object")
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON object")
+	}
+	return obj, nil
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := newObject()
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.set(keyTok.(string), value)
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := dec.Token()
+		return list, err
+	default:
+		return tok, nil
+	}
+}
+
+// marshalIndent encodes v with two space indentation, like json.MarshalIndent
+// but keeping object key order and without escaping HTML characters
+func marshalIndent(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeValue(&buf, v, ""); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func encodeValue(buf *bytes.Buffer, v interface{}, indent string) error {
+	inner := indent + "  "
+
+	switch v := v.(type) {
+	case *object:
+		if len(v.keys) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i, key := range v.keys {
+			buf.WriteString(inner)
+			if err := encodeScalar(buf, key); err != nil {
+				return err
+			}
+			buf.WriteString(": ")
+			if err := encodeValue(buf, v.values[key], inner); err != nil {
+				return err
+			}
+			if i < len(v.keys)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "}")
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, item := range v {
+			buf.WriteString(inner)
+			if err := encodeValue(buf, item, inner); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "]")
+	default:
+		return encodeScalar(buf, v)
+	}
+	return nil
+}
+
+func encodeScalar(buf *bytes.Buffer, v interface{}) error {
+	var scalar bytes.Buffer
+	enc := json.NewEncoder(&scalar)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("cannot encode %v: %w", v, err)
+	}
+	buf.WriteString(strings.TrimSuffix(scalar.String(), "\n"))
+	return nil
+}
@@ -0,0 +1,310 @@
+package hook
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HookEvent is a Claude Code hook event the notification hook is installed
+// for, with the tool matcher it applies to, if any
+type HookEvent struct {
+	Event   string
+	Matcher string
+}
+
+// DefaultEvents are the events hooks install sets up: waiting for input,
+// finished responses and Bash commands
+var DefaultEvents = []HookEvent{
+	{Event: "Notification"},
+	{Event: "Stop"},
+	{Event: "PostToolUse", Matcher: "Bash"},
+}
+
+// Settings is a Claude Code settings file. Everything but the notification
+// hooks is left as it was, in its original order.
+type Settings struct {
+	Path string
+	root *object
+
+	// original is the file as read, loaded the same settings as they
+	// would be written, to tell real changes from formatting
+	original string
+	loaded   string
+}
+
+// ProjectSettingsFile returns the project settings file for dir
+func ProjectSettingsFile(dir string) string {
+	return filepath.Join(dir, ".claude", "settings.json")
+}
+
+// UserSettingsFile returns the user settings file
+func UserSettingsFile() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".claude", "settings.json"), nil
+}
+
+// LoadSettings reads a settings file. A missing file is an empty one.
+func LoadSettings(path string) (*Settings, error) {
+	s := &Settings{Path: path, root: newObject()}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read settings: %w", err)
+	}
+
+	s.original = string(data)
+	if strings.TrimSpace(s.original) == "" {
+		return s, nil
+	}
+
+	root, err := parseObject(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse settings %s: %w", path, err)
+	}
+	s.root = root
+
+	if s.loaded, err = s.Content(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Install adds a command hook running command for each event, or updates
+// the one that is already there. isOurs recognizes previously installed
+// hook commands.
+func (s *Settings) Install(command string, events []HookEvent, isOurs func(string) bool) error {
+	hooks, err := s.hooks(true)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		groups, err := eventGroups(hooks, e.Event)
+		if err != nil {
+			return err
+		}
+
+		// Keep a single hook of ours, in a group with the right matcher
+		found := false
+		emptied := map[*object]bool{}
+		for _, g := range groups {
+			group, ok := g.(*object)
+			if !ok {
+				continue
+			}
+
+			matcher, _ := group.get("matcher")
+			sameMatcher := matcher == e.Matcher || (matcher == nil && e.Matcher == "")
+
+			entries, _ := group.get("hooks")
+			list, _ := entries.([]interface{})
+			for i := 0; i < len(list); i++ {
+				entry, ok := list[i].(*object)
+				if !ok || !isOurs(hookCommand(entry)) {
+					continue
+				}
+				if sameMatcher && !found {
+					entry.set("command", command)
+					found = true
+					continue
+				}
+				list = append(list[:i], list[i+1:]...)
+				i--
+				emptied[group] = len(list) == 0
+			}
+			if list != nil {
+				group.set("hooks", list)
+			}
+		}
+
+		groups = dropGroups(groups, emptied)
+		if !found {
+			group := newObject()
+			if e.Matcher != "" {
+				group.set("matcher", e.Matcher)
+			}
+			entry := newObject()
+			entry.set("type", "command")
+			entry.set("command", command)
+			group.set("hooks", []interface{}{entry})
+			groups = append(groups, group)
+		}
+		hooks.set(e.Event, groups)
+	}
+
+	return nil
+}
+
+// Uninstall removes every hook command isOurs recognizes, along with the
+// groups and events that removing them left empty. Groups and events that
+// were already empty are the user's and are kept.
+func (s *Settings) Uninstall(isOurs func(string) bool) error {
+	hooks, err := s.hooks(false)
+	if err != nil || hooks == nil {
+		return err
+	}
+
+	removed := false
+	for _, event := range append([]string(nil), hooks.keys...) {
+		groups, ok := hooks.values[event].([]interface{})
+		if !ok {
+			continue
+		}
+
+		emptied := map[*object]bool{}
+		for _, g := range groups {
+			group, ok := g.(*object)
+			if !ok {
+				continue
+			}
+			entries, _ := group.get("hooks")
+			list, ok := entries.([]interface{})
+			if !ok {
+				continue
+			}
+
+			kept := []interface{}{}
+			for _, e := range list {
+				if entry, ok := e.(*object); ok && isOurs(hookCommand(entry)) {
+					continue
+				}
+				kept = append(kept, e)
+			}
+			if len(kept) == len(list) {
+				continue
+			}
+			group.set("hooks", kept)
+			emptied[group] = len(kept) == 0
+		}
+		if len(emptied) == 0 {
+			continue
+		}
+
+		removed = true
+		groups = dropGroups(groups, emptied)
+		if len(groups) == 0 {
+			hooks.remove(event)
+		} else {
+			hooks.set(event, groups)
+		}
+	}
+
+	if removed && len(hooks.keys) == 0 {
+		s.root.remove("hooks")
+	}
+	return nil
+}
+
+// Content returns the settings file as it would be written
+func (s *Settings) Content() (string, error) {
+	data, err := marshalIndent(s.root)
+	return string(data), err
+}
+
+// Changed reports whether the settings differ from the file on disk
+func (s *Settings) Changed() (bool, error) {
+	content, err := s.Content()
+	if err != nil {
+		return false, err
+	}
+	if s.loaded == "" {
+		return len(s.root.keys) > 0, nil
+	}
+	return content != s.loaded, nil
+}
+
+// Diff returns a unified diff of the pending changes, or "" if there are none
+func (s *Settings) Diff() (string, error) {
+	changed, err := s.Changed()
+	if err != nil || !changed {
+		return "", err
+	}
+	content, err := s.Content()
+	if err != nil {
+		return "", err
+	}
+	return unifiedDiff(s.Path, s.Path, s.original, content), nil
+}
+
+// Save writes the settings, creating the directory if needed
+func (s *Settings) Save() error {
+	content, err := s.Content()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return fmt.Errorf("cannot create settings directory: %w", err)
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(s.Path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if err := os.WriteFile(s.Path, []byte(content), mode); err != nil {
+		return fmt.Errorf("cannot write settings: %w", err)
+	}
+	s.original = content
+	s.loaded = content
+	return nil
+}
+
+// hooks returns the hooks object, creating it if asked to
+func (s *Settings) hooks(create bool) (*object, error) {
+	v, ok := s.root.get("hooks")
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+		hooks := newObject()
+		s.root.set("hooks", hooks)
+		return hooks, nil
+	}
+
+	hooks, ok := v.(*object)
+	if !ok {
+		return nil, fmt.Errorf("invalid settings %s: hooks is not an object", s.Path)
+	}
+	return hooks, nil
+}
+
+func eventGroups(hooks *object, event string) ([]interface{}, error) {
+	v, ok := hooks.get(event)
+	if !ok {
+		return nil, nil
+	}
+	groups, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid settings: hooks.%s is not a list", event)
+	}
+	return groups, nil
+}
+
+// dropGroups removes the matcher groups marked in emptied, the ones that
+// removing our hooks left without any
+func dropGroups(groups []interface{}, emptied map[*object]bool) []interface{} {
+	kept := []interface{}{}
+	for _, g := range groups {
+		if group, ok := g.(*object); ok && emptied[group] {
+			continue
+		}
+		kept = append(kept, g)
+	}
+	return kept
+}
+
+func hookCommand(entry *object) string {
+	v, _ := entry.get("command")
+	command, _ := v.(string)
+	return command
+}
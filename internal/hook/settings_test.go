@@ -0,0 +1,257 @@
+package hook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ourCommand = "wsl-notify-send hook claude"
+
+func isOurs(command string) bool {
+	return strings.HasSuffix(command, "hook claude")
+}
+
+const existingSettings = `{
+  "permissions": {
+    "allow": [
+      "Bash(go test:*)"
+    ]
+  },
+  "hooks": {
+    "PostToolUse": [
+      {
+        "matcher": "Edit",
+        "hooks": [
+          {
+            "type": "command",
+            "command": "gofmt -w \"$FILE\" && echo <ok>",
+            "timeout": 30
+          }
+        ]
+      }
+    ]
+  },
+  "model": "sonnet"
+}
+`
+
+func writeSettings(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), ".claude", "settings.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestSettingsInstall(t *testing.T) {
+	path := writeSettings(t, existingSettings)
+
+	s, err := LoadSettings(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Install(ourCommand, DefaultEvents, isOurs))
+
+	changed, err := s.Changed()
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	content, err := s.Content()
+	require.NoError(t, err)
+
+	// Existing settings keep their order and values
+	assert.Less(t, strings.Index(content, `"permissions"`), strings.Index(content, `"hooks"`))
+	assert.Less(t, strings.Index(content, `"hooks"`), strings.Index(content, `"model"`))
+	assert.Contains(t, content, `"command": "gofmt -w \"$FILE\" && echo <ok>",`)
+	assert.Contains(t, content, `"timeout": 30`)
+
+	assert.Contains(t, content, `"matcher": "Bash"`)
+	assert.Contains(t, content, `"Notification": [`)
+	assert.Contains(t, content, `"Stop": [`)
+	assert.Equal(t, 3, strings.Count(content, ourCommand))
+}
+
+func TestSettingsInstallIsIdempotent(t *testing.T) {
+	path := writeSettings(t, existingSettings)
+
+	s, err := LoadSettings(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Install(ourCommand, DefaultEvents, isOurs))
+	require.NoError(t, s.Save())
+
+	s, err = LoadSettings(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Install(ourCommand, DefaultEvents, isOurs))
+
+	changed, err := s.Changed()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	content, err := s.Content()
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(content, ourCommand))
+}
+
+func TestSettingsInstallUpdatesCommand(t *testing.T) {
+	path := writeSettings(t, `{"hooks": {"Stop": [
+		{"hooks": [{"type": "command", "command": "/old/wsl-notify-send hook claude"}]},
+		{"hooks": [{"type": "command", "command": "/older/wsl-notify-send hook claude"}, {"type": "command", "command": "say done"}]}
+	]}}`)
+
+	s, err := LoadSettings(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Install(ourCommand, []HookEvent{{Event: "Stop"}}, isOurs))
+
+	content, err := s.Content()
+	require.NoError(t, err)
+
+	// One hook of ours is updated, duplicates are removed, others are kept
+	assert.Equal(t, 1, strings.Count(content, "hook claude"))
+	assert.Contains(t, content, ourCommand)
+	assert.Contains(t, content, "say done")
+}
+
+func TestSettingsInstallMovesMatcher(t *testing.T) {
+	path := writeSettings(t, `{"hooks": {"PostToolUse": [
+		{"matcher": "Edit", "hooks": [{"type": "command", "command": "wsl-notify-send hook claude"}]}
+	]}}`)
+
+	s, err := LoadSettings(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Install(ourCommand, []HookEvent{{Event: "PostToolUse", Matcher: "Bash"}}, isOurs))
+
+	content, err := s.Content()
+	require.NoError(t, err)
+	assert.NotContains(t, content, `"Edit"`, "the group left empty is removed")
+	assert.Contains(t, content, `"matcher": "Bash"`)
+}
+
+func TestSettingsInstallMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".claude", "settings.json")
+
+	s, err := LoadSettings(path)
+	require.NoError(t, err)
+
+	changed, err := s.Changed()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	require.NoError(t, s.Install(ourCommand, DefaultEvents, isOurs))
+	require.NoError(t, s.Save())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), ourCommand)
+}
+
+func TestSettingsUninstall(t *testing.T) {
+	path := writeSettings(t, existingSettings)
+
+	s, err := LoadSettings(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Install(ourCommand, DefaultEvents, isOurs))
+	require.NoError(t, s.Save())
+
+	s, err = LoadSettings(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Uninstall(isOurs))
+
+	content, err := s.Content()
+	require.NoError(t, err)
+	assert.Equal(t, existingSettings, content)
+
+	// Nothing left to remove
+	require.NoError(t, s.Save())
+	require.NoError(t, s.Uninstall(isOurs))
+	changed, err := s.Changed()
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestSettingsUninstallRemovesEmptyHooks(t *testing.T) {
+	path := writeSettings(t, `{"model": "x", "hooks": {"Stop": [{"hooks": [{"type": "command", "command": "wsl-notify-send hook claude"}]}]}}`)
+
+	s, err := LoadSettings(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Uninstall(isOurs))
+
+	content, err := s.Content()
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"model\": \"x\"\n}\n", content)
+}
+
+func TestSettingsKeepsEmptyUserGroups(t *testing.T) {
+	const userGroups = `{"hooks": {"Stop": [
+		{"matcher": "", "hooks": []},
+		{"hooks": [{"type": "command", "command": "wsl-notify-send hook claude"}]}
+	], "SessionStart": []}}`
+
+	s, err := LoadSettings(writeSettings(t, userGroups))
+	require.NoError(t, err)
+	require.NoError(t, s.Uninstall(isOurs))
+
+	content, err := s.Content()
+	require.NoError(t, err)
+	assert.NotContains(t, content, "hook claude")
+	assert.Contains(t, content, `"matcher": ""`, "the group that was already empty is kept")
+	assert.Contains(t, content, `"SessionStart": []`)
+
+	s, err = LoadSettings(writeSettings(t, userGroups))
+	require.NoError(t, err)
+	require.NoError(t, s.Install(ourCommand, []HookEvent{{Event: "Stop"}}, isOurs))
+
+	content, err = s.Content()
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(content, ourCommand))
+	assert.Contains(t, content, `"matcher": ""`)
+}
+
+func TestSettingsDiff(t *testing.T) {
+	path := writeSettings(t, "{\n  \"model\": \"x\"\n}\n")
+
+	s, err := LoadSettings(path)
+	require.NoError(t, err)
+
+	diff, err := s.Diff()
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	require.NoError(t, s.Install(ourCommand, []HookEvent{{Event: "Stop"}}, isOurs))
+	diff, err = s.Diff()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(diff, "--- "+path+"\n+++ "+path+"\n@@ -1,3 +1,15 @@\n"))
+	assert.Contains(t, diff, "\n-  \"model\": \"x\"\n+  \"model\": \"x\",\n+  \"hooks\": {\n")
+	assert.Contains(t, diff, "+            \"command\": \"wsl-notify-send hook claude\"\n")
+}
+
+func TestLoadSettingsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		errorMsg string
+	}{
+		{"invalid json", "{", "cannot parse settings"},
+		{"not an object", "[]", "expected a JSON object"},
+		{"trailing data", "{} {}", "unexpected data after the JSON object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadSettings(writeSettings(t, tt.content))
+			assert.ErrorContains(t, err, tt.errorMsg)
+		})
+	}
+}
+
+func TestSettingsInstallInvalidHooks(t *testing.T) {
+	s, err := LoadSettings(writeSettings(t, `{"hooks": []}`))
+	require.NoError(t, err)
+	assert.ErrorContains(t, s.Install(ourCommand, DefaultEvents, isOurs), "hooks is not an object")
+
+	s, err = LoadSettings(writeSettings(t, `{"hooks": {"Stop": {}}}`))
+	require.NoError(t, err)
+	assert.ErrorContains(t, s.Install(ourCommand, DefaultEvents, isOurs), "hooks.Stop is not a list")
+}
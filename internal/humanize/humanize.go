@@ -0,0 +1,16 @@
+// Package humanize formats values for notification text.
+package humanize
+
+import "time"
+
+// Duration rounds d to a precision that reads well in a notification
+func Duration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < time.Minute:
+		return d.Round(100 * time.Millisecond).String()
+	default:
+		return d.Round(time.Second).String()
+	}
+}
@@ -0,0 +1,15 @@
+package humanize
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuration(t *testing.T) {
+	assert.Equal(t, "250ms", Duration(250*time.Millisecond+300*time.Microsecond))
+	assert.Equal(t, "12.3s", Duration(12345*time.Millisecond))
+	assert.Equal(t, "2m5s", Duration(2*time.Minute+4600*time.Millisecond))
+	assert.Equal(t, "1h0m0s", Duration(time.Hour))
+}
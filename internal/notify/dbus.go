@@ -0,0 +1,384 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"wsl-notify-send/internal/exitcode"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// D-Bus names of the freedesktop notification service
+const (
+	dbusName      = "org.freedesktop.Notifications"
+	dbusPath      = dbus.ObjectPath("/org/freedesktop/Notifications")
+	dbusInterface = "org.freedesktop.Notifications"
+)
+
+// alertSound is the freedesktop sound theme name played for alerts
+const alertSound = "message-new-instant"
+
+func init() {
+	RegisterBackend(Backend{
+		Name:        "dbus",
+		Description: "Native freedesktop notifications on the session bus",
+		Available:   dbusAvailable,
+		New: func() (Beeper, error) {
+			return NewDBusBeeper(nil), nil
+		},
+	})
+}
+
+// DBusBeeper implements Beeper by talking to the org.freedesktop.Notifications
+// service on the session bus, as used by native Linux desktops and WSLg
+type DBusBeeper struct {
+	mu      sync.Mutex
+	conn    *dbus.Conn
+	appName string
+}
+
+// NewDBusBeeper creates a D-Bus backend. With a nil conn it connects to
+// the session bus on first use.
+func NewDBusBeeper(conn *dbus.Conn) *DBusBeeper {
+	return &DBusBeeper{conn: conn, appName: "wsl-notify-send"}
+}
+
+func (b *DBusBeeper) Notify(title, message string, icon interface{}) error {
+	_, err := b.NotifyWithOptions(title, message, icon, Options{ExpireTimeout: ExpireDefault})
+	return err
+}
+
+func (b *DBusBeeper) Alert(title, message string, icon interface{}) error {
+	_, err := b.AlertWithOptions(title, message, icon, Options{ExpireTimeout: ExpireDefault})
+	return err
+}
+
+// Beep has no D-Bus equivalent, so it uses beeep directly
+func (b *DBusBeeper) Beep(freq float64, duration int) error {
+	return beepBeep(freq, duration)
+}
+
+func (b *DBusBeeper) SetAppName(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.appName = name
+}
+
+// UsesDBus reports that notifications go through D-Bus
+func (b *DBusBeeper) UsesDBus() bool {
+	return true
+}
+
+// CanUpdate reports that the server replaces the notification ReplaceID
+// names
+func (b *DBusBeeper) CanUpdate() bool {
+	return true
+}
+
+// CanWait reports that the server reports responses
+func (b *DBusBeeper) CanWait() bool {
+	return true
+}
+
+func (b *DBusBeeper) NotifyWithOptions(title, message string, icon interface{}, opts Options) (uint32, error) {
+	return b.send(title, message, icon, opts, false)
+}
+
+// AlertWithOptions asks the server to play the alert sound unless the
+// hints already name one
+func (b *DBusBeeper) AlertWithOptions(title, message string, icon interface{}, opts Options) (uint32, error) {
+	return b.send(title, message, icon, opts, true)
+}
+
+func (b *DBusBeeper) send(title, message string, icon interface{}, opts Options, sound bool) (uint32, error) {
+	conn, err := b.connect()
+	if err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	appName := b.appName
+	b.mu.Unlock()
+
+	hints := DBusHints(opts)
+	appIcon := ""
+	switch i := icon.(type) {
+	case string:
+		appIcon = i
+		if _, err := os.Stat(i); err == nil {
+			if abs, err := filepath.Abs(i); err == nil {
+				appIcon = abs
+			}
+		}
+	case []byte:
+		if data, ok := imageData(i); ok {
+			hints["image-data"] = dbus.MakeVariant(data)
+		}
+	}
+
+	if sound {
+		if _, ok := hints["sound-name"]; !ok {
+			hints["sound-name"] = dbus.MakeVariant(alertSound)
+		}
+	}
+
+	actions := make([]string, 0, len(opts.Actions)*2)
+	for _, action := range opts.Actions {
+		actions = append(actions, action.Key, action.Label)
+	}
+
+	var id uint32
+	err = conn.Object(dbusName, dbusPath).Call(dbusInterface+".Notify", 0,
+		appName, opts.ReplaceID, appIcon, title, message, actions, hints, int32(opts.ExpireTimeout),
+	).Store(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// SendAndWait listens for the ActionInvoked and NotificationClosed signals
+// of the notification. When the server doesn't expire it in time it is
+// closed and the wait times out.
+func (b *DBusBeeper) SendAndWait(ctx context.Context, title, message string, icon interface{}, opts Options, sound bool) (uint32, string, error) {
+	conn, err := b.connect()
+	if err != nil {
+		return 0, "", err
+	}
+
+	// Subscribe before sending, so a quick response isn't missed
+	match := []dbus.MatchOption{dbus.WithMatchObjectPath(dbusPath), dbus.WithMatchInterface(dbusInterface)}
+	if err := conn.AddMatchSignal(match...); err != nil {
+		return 0, "", err
+	}
+	defer func() { _ = conn.RemoveMatchSignal(match...) }()
+
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+
+	id, err := b.send(title, message, icon, opts, sound)
+	if err != nil {
+		return 0, "", err
+	}
+
+	var expired <-chan time.Time
+	if timeout := waitTimeout(opts); timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case sig := <-signals:
+			action, done, err := dbusResponse(sig, id)
+			if done {
+				return id, action, err
+			}
+		case <-expired:
+			b.close(conn, id)
+			return id, "", ErrTimeout
+		case <-ctx.Done():
+			b.close(conn, id)
+			return id, "", ctx.Err()
+		}
+	}
+}
+
+// dbusResponse interprets a signal about notification id, reporting whether
+// it ends the wait
+func dbusResponse(sig *dbus.Signal, id uint32) (string, bool, error) {
+	if len(sig.Body) != 2 {
+		return "", false, nil
+	}
+	if sigID, ok := sig.Body[0].(uint32); !ok || sigID != id {
+		return "", false, nil
+	}
+
+	switch sig.Name {
+	case dbusInterface + ".ActionInvoked":
+		action, _ := sig.Body[1].(string)
+		return action, true, nil
+	case dbusInterface + ".NotificationClosed":
+		// Reason 1 is expiry; dismissal, CloseNotification and undefined
+		// reasons all mean the user won't respond
+		if reason, _ := sig.Body[1].(uint32); reason == 1 {
+			return "", true, ErrTimeout
+		}
+		return "", true, ErrDismissed
+	default:
+		return "", false, nil
+	}
+}
+
+// close withdraws a notification, ignoring failures since it may be gone
+func (b *DBusBeeper) close(conn *dbus.Conn, id uint32) {
+	_ = conn.Object(dbusName, dbusPath).Call(dbusInterface+".CloseNotification", 0, id).Err
+}
+
+func (b *DBusBeeper) connect() (*dbus.Conn, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn == nil {
+		conn, err := dbus.ConnectSessionBus()
+		if err != nil {
+			return nil, exitcode.Mark(ErrBackendUnavailable, err)
+		}
+		b.conn = conn
+	}
+
+	return b.conn, nil
+}
+
+// DBusHints converts the options into freedesktop notification hints
+func DBusHints(opts Options) map[string]dbus.Variant {
+	hints := make(map[string]dbus.Variant)
+
+	switch opts.Urgency {
+	case UrgencyLow:
+		hints["urgency"] = dbus.MakeVariant(byte(0))
+	case UrgencyNormal:
+		hints["urgency"] = dbus.MakeVariant(byte(1))
+	case UrgencyCritical:
+		hints["urgency"] = dbus.MakeVariant(byte(2))
+	}
+
+	if opts.Category != "" {
+		hints["category"] = dbus.MakeVariant(opts.Category)
+	}
+
+	if opts.Transient {
+		hints["transient"] = dbus.MakeVariant(true)
+	}
+
+	// Explicit hints win over the ones derived from options
+	for _, hint := range opts.Hints {
+		hints[hint.Name] = dbus.MakeVariant(hint.Value)
+	}
+
+	return hints
+}
+
+// dbusImage is the (iiibiiay) image-data hint structure
+type dbusImage struct {
+	Width         int32
+	Height        int32
+	RowStride     int32
+	HasAlpha      bool
+	BitsPerSample int32
+	Channels      int32
+	Data          []byte
+}
+
+// imageData decodes PNG or JPEG icon data into an image-data hint
+func imageData(data []byte) (dbusImage, bool) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return dbusImage{}, false
+	}
+
+	bounds := img.Bounds()
+	rgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+
+	return dbusImage{
+		Width:         int32(bounds.Dx()),
+		Height:        int32(bounds.Dy()),
+		RowStride:     int32(rgba.Stride),
+		HasAlpha:      true,
+		BitsPerSample: 8,
+		Channels:      4,
+		Data:          rgba.Pix,
+	}, true
+}
+
+// dbusSelected reports whether the environment calls for the native D-Bus
+// service instead of beeep: a Linux session bus outside of WSL, or inside
+// WSL when WSLg provides a desktop. WSL_NOTIFY_SEND_DBUS=1/0 overrides it.
+func dbusSelected(getenv func(string) string, goos string) bool {
+	switch strings.ToLower(getenv("WSL_NOTIFY_SEND_DBUS")) {
+	case "1", "true", "yes":
+		return true
+	case "0", "false", "no":
+		return false
+	}
+
+	switch goos {
+	case "linux", "freebsd", "netbsd", "openbsd", "illumos":
+	default:
+		return false
+	}
+
+	if !hasSessionBus(getenv) {
+		return false
+	}
+
+	// Inside WSL only WSLg provides a notification service
+	if getenv("WSL_DISTRO_NAME") != "" || getenv("WSL_INTEROP") != "" {
+		return getenv("WAYLAND_DISPLAY") != "" || getenv("DISPLAY") != ""
+	}
+
+	return true
+}
+
+// dbusAvailable checks that a notification service is running on, or can
+// be activated by, the session bus
+func dbusAvailable() (bool, string) {
+	if !hasSessionBus(os.Getenv) {
+		return false, "no session bus"
+	}
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return false, "cannot connect to session bus: " + err.Error()
+	}
+	defer conn.Close()
+
+	return dbusServiceAvailable(conn)
+}
+
+func dbusServiceAvailable(conn *dbus.Conn) (bool, string) {
+	var running bool
+	if err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, dbusName).Store(&running); err != nil {
+		return false, "cannot query session bus: " + err.Error()
+	}
+	if running {
+		return true, "notification service running on the session bus"
+	}
+
+	var activatable []string
+	if err := conn.BusObject().Call("org.freedesktop.DBus.ListActivatableNames", 0).Store(&activatable); err == nil {
+		for _, name := range activatable {
+			if name == dbusName {
+				return true, "notification service can be activated on the session bus"
+			}
+		}
+	}
+
+	return false, "no notification service on the session bus"
+}
+
+func hasSessionBus(getenv func(string) string) bool {
+	if getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+		return true
+	}
+
+	runtimeDir := getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(runtimeDir, "bus"))
+	return err == nil
+}
@@ -0,0 +1,323 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"sync"
+	"testing"
+	"time"
+	"wsl-notify-send/internal/dbustest"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// notifyCall records the arguments of a Notify call
+type notifyCall struct {
+	AppName       string
+	ReplacesID    uint32
+	AppIcon       string
+	Summary       string
+	Body          string
+	Actions       []string
+	Hints         map[string]dbus.Variant
+	ExpireTimeout int32
+}
+
+// fakeNotificationServer records Notify calls made over D-Bus
+type fakeNotificationServer struct {
+	mu     sync.Mutex
+	conn   *dbus.Conn
+	calls  []notifyCall
+	closed []uint32
+
+	// respond, when set, emits signals for each notification
+	respond func(conn *dbus.Conn, id uint32)
+}
+
+func (s *fakeNotificationServer) Notify(appName string, replacesID uint32, appIcon, summary, body string, actions []string, hints map[string]dbus.Variant, expireTimeout int32) (uint32, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, notifyCall{appName, replacesID, appIcon, summary, body, actions, hints, expireTimeout})
+	id := uint32(len(s.calls))
+	if replacesID != 0 {
+		id = replacesID
+	}
+	if s.respond != nil {
+		go s.respond(s.conn, id)
+	}
+	return id, nil
+}
+
+func (s *fakeNotificationServer) CloseNotification(id uint32) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = append(s.closed, id)
+	return nil
+}
+
+// setRespond sets how the server responds, under the lock Notify reads it
+// with on the D-Bus goroutine
+func (s *fakeNotificationServer) setRespond(respond func(conn *dbus.Conn, id uint32)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.respond = respond
+}
+
+func (s *fakeNotificationServer) lastCall() notifyCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[len(s.calls)-1]
+}
+
+// setupDBusBeeper returns a DBusBeeper connected to a private bus on which
+// a fake notification server is running
+func setupDBusBeeper(t *testing.T) (*DBusBeeper, *fakeNotificationServer) {
+	address := dbustest.StartSessionBus(t)
+
+	serverConn, err := dbus.Connect(address)
+	require.NoError(t, err)
+	t.Cleanup(func() { serverConn.Close() })
+
+	server := &fakeNotificationServer{conn: serverConn}
+	require.NoError(t, serverConn.Export(server, dbusPath, dbusInterface))
+	_, err = serverConn.RequestName(dbusName, dbus.NameFlagDoNotQueue)
+	require.NoError(t, err)
+
+	clientConn, err := dbus.Connect(address)
+	require.NoError(t, err)
+	t.Cleanup(func() { clientConn.Close() })
+
+	return NewDBusBeeper(clientConn), server
+}
+
+func TestDBusBeeper_Notify(t *testing.T) {
+	beeper, server := setupDBusBeeper(t)
+	beeper.SetAppName("MyApp")
+
+	err := beeper.Notify("Hello", "World", "dialog-information")
+	require.NoError(t, err)
+
+	call := server.lastCall()
+	assert.Equal(t, "MyApp", call.AppName)
+	assert.Equal(t, "dialog-information", call.AppIcon)
+	assert.Equal(t, "Hello", call.Summary)
+	assert.Equal(t, "World", call.Body)
+	assert.Equal(t, int32(-1), call.ExpireTimeout)
+	assert.NotContains(t, call.Hints, "sound-name")
+}
+
+func TestDBusBeeper_Alert(t *testing.T) {
+	beeper, server := setupDBusBeeper(t)
+
+	err := beeper.Alert("Down", "Database", "")
+	require.NoError(t, err)
+
+	call := server.lastCall()
+	assert.Equal(t, alertSound, call.Hints["sound-name"].Value())
+}
+
+func TestDBusBeeper_NotifyWithOptions(t *testing.T) {
+	beeper, server := setupDBusBeeper(t)
+
+	id, err := beeper.NotifyWithOptions("Build", "Failed", "", Options{
+		Urgency:       UrgencyCritical,
+		ExpireTimeout: 5000,
+		Category:      "build",
+		Transient:     true,
+		ReplaceID:     42,
+		Actions:       []Action{{Key: "retry", Label: "Retry"}},
+		Hints:         []Hint{{Name: "x-job", Value: "nightly"}, {Name: "value", Value: int32(3)}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(42), id)
+
+	call := server.lastCall()
+	assert.Equal(t, uint32(42), call.ReplacesID)
+	assert.Equal(t, []string{"retry", "Retry"}, call.Actions)
+	assert.Equal(t, int32(5000), call.ExpireTimeout)
+	assert.Equal(t, byte(2), call.Hints["urgency"].Value())
+	assert.Equal(t, "build", call.Hints["category"].Value())
+	assert.Equal(t, true, call.Hints["transient"].Value())
+	assert.Equal(t, "nightly", call.Hints["x-job"].Value())
+	assert.Equal(t, int32(3), call.Hints["value"].Value())
+}
+
+func TestDBusBeeper_ImageData(t *testing.T) {
+	beeper, server := setupDBusBeeper(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 2, 3))))
+
+	err := beeper.Notify("Image", "", buf.Bytes())
+	require.NoError(t, err)
+
+	call := server.lastCall()
+	require.Contains(t, call.Hints, "image-data")
+	fields := call.Hints["image-data"].Value().([]interface{})
+	assert.Equal(t, int32(2), fields[0])
+	assert.Equal(t, int32(3), fields[1])
+	assert.Equal(t, int32(8), fields[2])
+}
+
+func TestDBusBeeper_SendAndWait(t *testing.T) {
+	emit := func(name string, body ...interface{}) func(*dbus.Conn, uint32) {
+		return func(conn *dbus.Conn, id uint32) {
+			// A signal for another notification must be ignored
+			_ = conn.Emit(dbusPath, dbusInterface+".ActionInvoked", id+100, "other")
+			_ = conn.Emit(dbusPath, dbusInterface+"."+name, append([]interface{}{id}, body...)...)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		respond    func(*dbus.Conn, uint32)
+		wantAction string
+		wantErr    error
+	}{
+		{"action", emit("ActionInvoked", "retry"), "retry", nil},
+		{"default action", emit("ActionInvoked", DefaultAction), DefaultAction, nil},
+		{"expired", emit("NotificationClosed", uint32(1)), "", ErrTimeout},
+		{"dismissed", emit("NotificationClosed", uint32(2)), "", ErrDismissed},
+		{"closed", emit("NotificationClosed", uint32(3)), "", ErrDismissed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			beeper, server := setupDBusBeeper(t)
+			server.setRespond(tt.respond)
+
+			opts := Options{ExpireTimeout: ExpireDefault, Actions: []Action{{Key: "retry", Label: "Retry"}}}
+			id, action, err := beeper.SendAndWait(context.Background(), "Deploy failed", "Retry?", "", opts, false)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, uint32(1), id)
+			assert.Equal(t, tt.wantAction, action)
+			assert.Equal(t, []string{"retry", "Retry"}, server.lastCall().Actions)
+		})
+	}
+}
+
+func TestDBusBeeper_SendAndWaitCancelled(t *testing.T) {
+	beeper, server := setupDBusBeeper(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, _, err := beeper.SendAndWait(ctx, "Title", "Message", "", Options{ExpireTimeout: 0}, false)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, []uint32{1}, server.closed, "the notification is withdrawn")
+}
+
+func TestDBusBeeper_NoServer(t *testing.T) {
+	address := dbustest.StartSessionBus(t)
+
+	conn, err := dbus.Connect(address)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	err = NewDBusBeeper(conn).Notify("Hello", "World", "")
+	assert.Error(t, err)
+}
+
+func TestDBusHints_ExplicitHintWins(t *testing.T) {
+	hints := DBusHints(Options{
+		Urgency: UrgencyLow,
+		Hints:   []Hint{{Name: "urgency", Value: byte(2)}},
+	})
+
+	assert.Equal(t, byte(2), hints["urgency"].Value())
+}
+
+func TestDBusSelected(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		goos     string
+		expected bool
+	}{
+		{
+			name:     "native linux desktop",
+			env:      map[string]string{"DBUS_SESSION_BUS_ADDRESS": "unix:path=/run/user/1000/bus"},
+			goos:     "linux",
+			expected: true,
+		},
+		{
+			name:     "linux without session bus",
+			env:      map[string]string{},
+			goos:     "linux",
+			expected: false,
+		},
+		{
+			name:     "windows",
+			env:      map[string]string{"DBUS_SESSION_BUS_ADDRESS": "unix:path=/run/user/1000/bus"},
+			goos:     "windows",
+			expected: false,
+		},
+		{
+			name:     "wsl without wslg",
+			env:      map[string]string{"DBUS_SESSION_BUS_ADDRESS": "unix:path=/run/user/1000/bus", "WSL_DISTRO_NAME": "Ubuntu"},
+			goos:     "linux",
+			expected: false,
+		},
+		{
+			name: "wslg",
+			env: map[string]string{
+				"DBUS_SESSION_BUS_ADDRESS": "unix:path=/run/user/1000/bus",
+				"WSL_DISTRO_NAME":          "Ubuntu",
+				"WAYLAND_DISPLAY":          "wayland-0",
+			},
+			goos:     "linux",
+			expected: true,
+		},
+		{
+			name:     "forced on",
+			env:      map[string]string{"WSL_NOTIFY_SEND_DBUS": "1"},
+			goos:     "windows",
+			expected: true,
+		},
+		{
+			name:     "forced off",
+			env:      map[string]string{"WSL_NOTIFY_SEND_DBUS": "false", "DBUS_SESSION_BUS_ADDRESS": "unix:path=/run/user/1000/bus"},
+			goos:     "linux",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			assert.Equal(t, tt.expected, dbusSelected(getenv, tt.goos))
+		})
+	}
+}
+
+func TestDBusServiceAvailable(t *testing.T) {
+	address := dbustest.StartSessionBus(t)
+
+	conn, err := dbus.Connect(address)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ok, reason := dbusServiceAvailable(conn)
+	assert.False(t, ok)
+	assert.Equal(t, "no notification service on the session bus", reason)
+
+	_, err = conn.RequestName(dbusName, dbus.NameFlagDoNotQueue)
+	require.NoError(t, err)
+
+	ok, reason = dbusServiceAvailable(conn)
+	assert.True(t, ok)
+	assert.Contains(t, reason, "running")
+}
@@ -0,0 +1,13 @@
+package notify
+
+import "wsl-notify-send/internal/exitcode"
+
+// Failure kinds returned by this package, check for them with errors.Is
+var (
+	ErrBackendFailed      = exitcode.NewKind("notification backend failed", exitcode.NotificationFailed)
+	ErrIconUnreadable     = exitcode.NewKind("icon unreadable", exitcode.IconError)
+	ErrBackendUnavailable = exitcode.NewKind("notification backend unavailable", exitcode.BackendUnavailable)
+	ErrTimeout            = exitcode.NewKind("timed out waiting for the notification", exitcode.Timeout)
+	ErrDismissed          = exitcode.NewKind("notification dismissed", exitcode.Dismissed)
+	ErrUnsafeText         = exitcode.NewKind("unsafe notification text", exitcode.InvalidArgs)
+)
@@ -0,0 +1,194 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Environment variables that script the fake backend
+const (
+	// FakeResponseEnv sets how waits are answered: "action:KEY", "dismiss"
+	// or "timeout". Waits are dismissed when it is unset.
+	FakeResponseEnv = "WSL_NOTIFY_SEND_FAKE_RESPONSE"
+
+	// FakeLogEnv names a file every notification is appended to as a line
+	// of JSON
+	FakeLogEnv = "WSL_NOTIFY_SEND_FAKE_LOG"
+)
+
+func init() {
+	RegisterBackend(Backend{
+		Name:        "fake",
+		Description: "Shows nothing and answers --wait as scripted, for testing",
+		Available: func() (bool, string) {
+			return true, "always available"
+		},
+		New: func() (Beeper, error) {
+			b := NewFakeBeeper(os.Getenv(FakeResponseEnv))
+			b.logPath = os.Getenv(FakeLogEnv)
+			return b, nil
+		},
+	})
+}
+
+// FakeNotification is a notification recorded by FakeBeeper
+type FakeNotification struct {
+	ID      uint32   `json:"id"`
+	AppName string   `json:"app_name"`
+	Title   string   `json:"title"`
+	Message string   `json:"message"`
+	Sound   bool     `json:"sound"`
+	Urgency string   `json:"urgency,omitempty"`
+	Actions []string `json:"actions,omitempty"`
+	Wait    bool     `json:"wait,omitempty"`
+}
+
+// FakeBeeper implements Beeper without showing anything. It records the
+// notifications it is sent and answers waits with a scripted response, so
+// scripts using --wait can be tested on any machine.
+type FakeBeeper struct {
+	mu       sync.Mutex
+	appName  string
+	response string
+	logPath  string
+	lastID   uint32
+	sent     []FakeNotification
+}
+
+// NewFakeBeeper creates a fake backend that answers waits with response,
+// as described for FakeResponseEnv
+func NewFakeBeeper(response string) *FakeBeeper {
+	return &FakeBeeper{appName: "wsl-notify-send", response: response}
+}
+
+func (b *FakeBeeper) Notify(title, message string, icon interface{}) error {
+	_, err := b.NotifyWithOptions(title, message, icon, Options{ExpireTimeout: ExpireDefault})
+	return err
+}
+
+func (b *FakeBeeper) Alert(title, message string, icon interface{}) error {
+	_, err := b.AlertWithOptions(title, message, icon, Options{ExpireTimeout: ExpireDefault})
+	return err
+}
+
+func (b *FakeBeeper) Beep(freq float64, duration int) error {
+	return nil
+}
+
+func (b *FakeBeeper) SetAppName(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.appName = name
+}
+
+func (b *FakeBeeper) NotifyWithOptions(title, message string, icon interface{}, opts Options) (uint32, error) {
+	n, err := b.record(title, message, opts, false, false)
+	return n.ID, err
+}
+
+func (b *FakeBeeper) AlertWithOptions(title, message string, icon interface{}, opts Options) (uint32, error) {
+	n, err := b.record(title, message, opts, true, false)
+	return n.ID, err
+}
+
+// SendAndWait answers with the scripted response right away. An action
+// response must name one of the notification's actions or DefaultAction.
+func (b *FakeBeeper) SendAndWait(ctx context.Context, title, message string, icon interface{}, opts Options, sound bool) (uint32, string, error) {
+	n, err := b.record(title, message, opts, sound, true)
+	if err != nil {
+		return 0, "", err
+	}
+
+	switch response := b.response; {
+	case response == "" || response == "dismiss":
+		return n.ID, "", ErrDismissed
+	case response == "timeout":
+		return n.ID, "", ErrTimeout
+	case strings.HasPrefix(response, "action:"):
+		key := strings.TrimPrefix(response, "action:")
+		if key != DefaultAction && !hasAction(opts.Actions, key) {
+			return 0, "", fmt.Errorf("fake response names unknown action %q", key)
+		}
+		return n.ID, key, nil
+	default:
+		return 0, "", fmt.Errorf("invalid fake response %q, expected action:KEY, dismiss or timeout", response)
+	}
+}
+
+// CanUpdate reports that notifications sent with the ID of an earlier one
+// keep that ID, as if they replaced it
+func (b *FakeBeeper) CanUpdate() bool {
+	return true
+}
+
+// CanWait reports that waits are answered as scripted
+func (b *FakeBeeper) CanWait() bool {
+	return true
+}
+
+// Sent returns the notifications recorded so far
+func (b *FakeBeeper) Sent() []FakeNotification {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]FakeNotification(nil), b.sent...)
+}
+
+func (b *FakeBeeper) record(title, message string, opts Options, sound, wait bool) (FakeNotification, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := opts.ReplaceID
+	if id == 0 {
+		b.lastID++
+		id = b.lastID
+	}
+
+	n := FakeNotification{
+		ID:      id,
+		AppName: b.appName,
+		Title:   title,
+		Message: message,
+		Sound:   sound,
+		Urgency: opts.Urgency,
+		Wait:    wait,
+	}
+	for _, action := range opts.Actions {
+		n.Actions = append(n.Actions, action.Key)
+	}
+	b.sent = append(b.sent, n)
+
+	if b.logPath == "" {
+		return n, nil
+	}
+	return n, appendJSONLine(b.logPath, n)
+}
+
+func appendJSONLine(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func hasAction(actions []Action, key string) bool {
+	for _, action := range actions {
+		if action.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -1,5 +1,10 @@
 package notify
 
+import (
+	"os"
+	"runtime"
+)
+
 // Beeper interface wraps the beeep library functions for testing
 type Beeper interface {
 	Notify(title, message string, icon interface{}) error
@@ -8,19 +13,63 @@ type Beeper interface {
 	SetAppName(name string)
 }
 
-// DefaultBeeper implements Beeper using the actual beeep library
-type DefaultBeeper struct{}
+// OptionsBeeper is implemented by backends that understand the notify-send
+// compatible Options. They return the ID the notification was given, or 0.
+type OptionsBeeper interface {
+	NotifyWithOptions(title, message string, icon interface{}, opts Options) (uint32, error)
+	AlertWithOptions(title, message string, icon interface{}, opts Options) (uint32, error)
+}
+
+// BusBeeper is implemented by backends that can deliver through the D-Bus
+// notification service. UsesDBus reports whether they do.
+type BusBeeper interface {
+	UsesDBus() bool
+}
+
+// UsesDBus reports whether b delivers through the D-Bus notification
+// service
+func UsesDBus(b Beeper) bool {
+	bb, ok := b.(BusBeeper)
+	return ok && bb.UsesDBus()
+}
+
+// DefaultBeeper implements Beeper using the actual beeep library, or the
+// native D-Bus notification service when the environment calls for it
+type DefaultBeeper struct {
+	dbus *DBusBeeper
+}
 
 func NewDefaultBeeper() *DefaultBeeper {
-	return &DefaultBeeper{}
+	b := &DefaultBeeper{}
+	if dbusSelected(os.Getenv, runtime.GOOS) {
+		b.dbus = NewDBusBeeper(nil)
+	}
+	return b
+}
+
+// UsesDBus reports whether notifications go through the D-Bus service
+func (b *DefaultBeeper) UsesDBus() bool {
+	return b.dbus != nil
+}
+
+// CanUpdate reports whether notifications go through D-Bus, since beeep
+// shows a new one every time
+func (b *DefaultBeeper) CanUpdate() bool {
+	return b.dbus != nil
 }
 
 // These functions will be implemented to wrap the actual beeep calls
 func (b *DefaultBeeper) Notify(title, message string, icon interface{}) error {
+	if b.dbus != nil {
+		return b.dbus.Notify(title, message, icon)
+	}
 	return beepNotify(title, message, icon)
 }
 
 func (b *DefaultBeeper) Alert(title, message string, icon interface{}) error {
+	if b.dbus != nil {
+		return b.dbus.Alert(title, message, icon)
+	}
 	return beepAlert(title, message, icon)
 }
 
@@ -29,5 +78,24 @@ func (b *DefaultBeeper) Beep(freq float64, duration int) error {
 }
 
 func (b *DefaultBeeper) SetAppName(name string) {
+	if b.dbus != nil {
+		b.dbus.SetAppName(name)
+	}
 	beepSetAppName(name)
 }
+
+// NotifyWithOptions uses the options when going through D-Bus, beeep
+// ignores them
+func (b *DefaultBeeper) NotifyWithOptions(title, message string, icon interface{}, opts Options) (uint32, error) {
+	if b.dbus != nil {
+		return b.dbus.NotifyWithOptions(title, message, icon, opts)
+	}
+	return 0, beepNotify(title, message, icon)
+}
+
+func (b *DefaultBeeper) AlertWithOptions(title, message string, icon interface{}, opts Options) (uint32, error) {
+	if b.dbus != nil {
+		return b.dbus.AlertWithOptions(title, message, icon, opts)
+	}
+	return 0, beepAlert(title, message, icon)
+}
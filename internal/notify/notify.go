@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"wsl-notify-send/internal/exitcode"
 
 	"github.com/gen2brain/beeep"
 )
@@ -16,52 +17,117 @@ func SetBeeper(b Beeper) {
 	defaultBeeper = b
 }
 
+// GetBeeper returns the beeper notifications are currently sent through
+func GetBeeper() Beeper {
+	return defaultBeeper
+}
+
 // Notify sends a desktop notification without sound
 func Notify(title, message, icon, appName string) error {
+	_, err := NotifyWithOptions(title, message, icon, appName, Options{ExpireTimeout: ExpireDefault})
+	return err
+}
+
+// Alert sends a desktop notification with sound
+func Alert(title, message, icon, appName string) error {
+	_, err := AlertWithOptions(title, message, icon, appName, Options{ExpireTimeout: ExpireDefault})
+	return err
+}
+
+// NotifyWithOptions sends a desktop notification, passing the notify-send
+// compatible options to backends that support them. The urgency decides how
+// it is delivered: low is silent and transient, normal is a plain
+// notification and critical is sent as an alert that stays until dismissed.
+// It returns the notification ID assigned by the backend, or 0 if it doesn't
+// assign one.
+func NotifyWithOptions(title, message, icon, appName string, opts Options) (uint32, error) {
+	return Send(defaultBeeper, title, message, icon, appName, opts, false)
+}
+
+// AlertWithOptions sends a desktop notification with sound, passing the
+// notify-send compatible options to backends that support them.
+func AlertWithOptions(title, message, icon, appName string, opts Options) (uint32, error) {
+	return Send(defaultBeeper, title, message, icon, appName, opts, true)
+}
+
+// Send is NotifyWithOptions, or AlertWithOptions with sound, through b
+// rather than the current backend
+func Send(b Beeper, title, message, icon, appName string, opts Options, sound bool) (uint32, error) {
 	// Set application name if provided
 	if appName != "" {
-		defaultBeeper.SetAppName(appName)
+		b.SetAppName(appName)
 	}
 
 	// Process icon
 	iconData, err := processIcon(icon)
 	if err != nil {
-		return fmt.Errorf("failed to process icon: %w", err)
+		return 0, exitcode.Mark(ErrIconUnreadable, fmt.Errorf("failed to process icon: %w", err))
 	}
 
-	// Send notification
-	if err := defaultBeeper.Notify(title, message, iconData); err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
+	id, err := Deliver(b, title, message, iconData, opts, sound)
+	if err != nil {
+		what := "notification"
+		if sound {
+			what = "alert"
+		}
+		return 0, exitcode.Mark(ErrBackendFailed, fmt.Errorf("failed to send %s: %w", what, err))
 	}
 
-	return nil
+	return id, nil
 }
 
-// Alert sends a desktop notification with sound
-func Alert(title, message, icon, appName string) error {
-	// Set application name if provided
-	if appName != "" {
-		defaultBeeper.SetAppName(appName)
+// Deliver sends a notification with an already processed icon through b.
+// The urgency is applied first, then the options are handed to b if it
+// implements OptionsBeeper. Rich content is folded into the message unless
+// b shows it itself. Sound forces an alert regardless of urgency.
+func Deliver(b Beeper, title, message string, icon interface{}, opts Options, sound bool) (uint32, error) {
+	message, opts, sound = prepare(b, message, opts, sound)
+
+	if ob, ok := b.(OptionsBeeper); ok {
+		if sound {
+			return ob.AlertWithOptions(title, message, icon, opts)
+		}
+		return ob.NotifyWithOptions(title, message, icon, opts)
 	}
 
-	// Process icon
-	iconData, err := processIcon(icon)
-	if err != nil {
-		return fmt.Errorf("failed to process icon: %w", err)
+	if sound {
+		return 0, b.Alert(title, message, icon)
 	}
+	return 0, b.Notify(title, message, icon)
+}
 
-	// Send alert
-	if err := defaultBeeper.Alert(title, message, iconData); err != nil {
-		return fmt.Errorf("failed to send alert: %w", err)
+// prepare applies the urgency and folds the rich content into the message
+// when b can't show it
+func prepare(b Beeper, message string, opts Options, sound bool) (string, Options, bool) {
+	opts, urgent := applyUrgency(opts)
+	sound = sound || urgent
+
+	if rb, ok := b.(RichBeeper); !ok || !rb.RichContent() {
+		message, opts = FoldRichContent(message, opts)
 	}
 
-	return nil
+	return message, opts, sound
+}
+
+// applyUrgency maps the urgency level onto delivery settings and reports
+// whether the notification should play a sound
+func applyUrgency(opts Options) (Options, bool) {
+	switch opts.Urgency {
+	case UrgencyLow:
+		opts.Transient = true
+		return opts, false
+	case UrgencyCritical:
+		opts.ExpireTimeout = 0
+		return opts, true
+	default:
+		return opts, false
+	}
 }
 
 // Beep plays a beep sound
 func Beep(frequency float64, duration int) error {
 	if err := defaultBeeper.Beep(frequency, duration); err != nil {
-		return fmt.Errorf("failed to beep: %w", err)
+		return exitcode.Mark(ErrBackendFailed, fmt.Errorf("failed to beep: %w", err))
 	}
 
 	return nil
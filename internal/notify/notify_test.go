@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -387,3 +388,101 @@ func TestDefaultBeeper(t *testing.T) {
 	assert.NotNil(t, beeper)
 	assert.IsType(t, &DefaultBeeper{}, beeper)
 }
+
+// MockOptionsBeeper is a MockBeeper that also understands notify-send options
+type MockOptionsBeeper struct {
+	MockBeeper
+}
+
+func (m *MockOptionsBeeper) NotifyWithOptions(title, message string, icon interface{}, opts Options) (uint32, error) {
+	args := m.Called(title, message, icon, opts)
+	return args.Get(0).(uint32), args.Error(1)
+}
+
+func (m *MockOptionsBeeper) AlertWithOptions(title, message string, icon interface{}, opts Options) (uint32, error) {
+	args := m.Called(title, message, icon, opts)
+	return args.Get(0).(uint32), args.Error(1)
+}
+
+func TestNotifyWithOptions_Urgency(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      Options
+		mockSetup func(*MockOptionsBeeper)
+	}{
+		{
+			name: "low urgency is silent and transient",
+			opts: Options{Urgency: UrgencyLow, ExpireTimeout: -1},
+			mockSetup: func(m *MockOptionsBeeper) {
+				m.On("NotifyWithOptions", "Title", "Message", "", Options{Urgency: UrgencyLow, ExpireTimeout: -1, Transient: true}).Return(uint32(1), nil).Once()
+			},
+		},
+		{
+			name: "normal urgency is passed through unchanged",
+			opts: Options{Urgency: UrgencyNormal, ExpireTimeout: 3000},
+			mockSetup: func(m *MockOptionsBeeper) {
+				m.On("NotifyWithOptions", "Title", "Message", "", Options{Urgency: UrgencyNormal, ExpireTimeout: 3000}).Return(uint32(1), nil).Once()
+			},
+		},
+		{
+			name: "critical urgency is an alert that never expires",
+			opts: Options{Urgency: UrgencyCritical, ExpireTimeout: 3000},
+			mockSetup: func(m *MockOptionsBeeper) {
+				m.On("AlertWithOptions", "Title", "Message", "", Options{Urgency: UrgencyCritical, ExpireTimeout: 0}).Return(uint32(1), nil).Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBeeper := new(MockOptionsBeeper)
+			SetBeeper(mockBeeper)
+			t.Cleanup(func() { SetBeeper(NewDefaultBeeper()) })
+			tt.mockSetup(mockBeeper)
+
+			id, err := NotifyWithOptions("Title", "Message", "", "", tt.opts)
+
+			assert.NoError(t, err)
+			assert.Equal(t, uint32(1), id)
+			mockBeeper.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAlertWithOptions_PlainBackend(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+	mockBeeper.On("Alert", "Title", "Message", "").Return(errors.New("boom")).Once()
+
+	id, err := AlertWithOptions("Title", "Message", "", "", Options{Urgency: UrgencyCritical})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to send alert")
+	assert.Equal(t, uint32(0), id)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestNotifyWithOptions_RichContentFolded(t *testing.T) {
+	mockBeeper := new(MockOptionsBeeper)
+	SetBeeper(mockBeeper)
+	t.Cleanup(func() { SetBeeper(NewDefaultBeeper()) })
+
+	mockBeeper.On("NotifyWithOptions", "Title", "Message\nline\nvia CI", "", Options{ExpireTimeout: -1}).Return(uint32(1), nil).Once()
+
+	_, err := NotifyWithOptions("Title", "Message", "", "", Options{
+		ExpireTimeout: -1,
+		Lines:         []string{"line"},
+		Attribution:   "via CI",
+		Timestamp:     time.Now(),
+	})
+
+	assert.NoError(t, err)
+	mockBeeper.AssertExpectations(t)
+}
+
+func TestUsesDBus(t *testing.T) {
+	assert.True(t, UsesDBus(NewDBusBeeper(nil)))
+	assert.True(t, UsesDBus(&DefaultBeeper{dbus: NewDBusBeeper(nil)}))
+	assert.False(t, UsesDBus(&DefaultBeeper{}))
+	assert.False(t, UsesDBus(NewPowerShellBeeper()))
+	assert.False(t, UsesDBus(new(MockBeeper)))
+}
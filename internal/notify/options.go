@@ -0,0 +1,189 @@
+package notify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Urgency levels, as defined by the freedesktop notification spec
+const (
+	UrgencyLow      = "low"
+	UrgencyNormal   = "normal"
+	UrgencyCritical = "critical"
+)
+
+// ExpireDefault leaves the expiration timeout to the notification server
+const ExpireDefault = -1
+
+// Options holds the notify-send compatible settings that go beyond
+// title, message, icon and app name. Backends that don't understand
+// them simply ignore them.
+type Options struct {
+	Urgency       string
+	ExpireTimeout int // milliseconds, -1 for the server default, 0 for never
+	Category      string
+	Transient     bool
+	ReplaceID     uint32
+	Actions       []Action
+	Hints         []Hint
+
+	// Rich content shown by backends that implement RichBeeper: extra text
+	// lines below the message, an attribution line and the time shown on
+	// the notification instead of the time it arrived
+	Lines       []string
+	Attribution string
+	Timestamp   time.Time
+
+	// Progress is a progress bar, shown by backends that implement
+	// RichBeeper, or nil for none
+	Progress *Progress
+
+	// Tag and Group identify a notification, so one sent with the same
+	// identity updates it instead of stacking a new one. Backends that
+	// can't do that ignore them.
+	Tag   string
+	Group string
+}
+
+// Progress is a progress bar on a notification
+type Progress struct {
+	Percent int // 0 to 100
+	Label   string
+	Status  string
+}
+
+// String describes the progress as a line of text, e.g. "Copying: 42% (3/7)"
+func (p Progress) String() string {
+	s := strconv.Itoa(p.Percent) + "%"
+	if p.Label != "" {
+		s = p.Label + ": " + s
+	}
+	if p.Status != "" {
+		s += " (" + p.Status + ")"
+	}
+	return s
+}
+
+// RichBeeper is implemented by backends that show the Lines, Attribution,
+// Timestamp and Progress of Options themselves. Other backends get the
+// lines, attribution and progress folded into the message.
+type RichBeeper interface {
+	OptionsBeeper
+	RichContent() bool
+}
+
+// FoldRichContent appends the extra lines, progress and attribution to the
+// message, for backends that only show a title and a message. The
+// timestamp has nowhere to go and is dropped.
+func FoldRichContent(message string, opts Options) (string, Options) {
+	parts := []string{message}
+	parts = append(parts, opts.Lines...)
+	if opts.Progress != nil {
+		parts = append(parts, opts.Progress.String())
+	}
+	parts = append(parts, opts.Attribution)
+
+	var kept []string
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+
+	opts.Lines = nil
+	opts.Attribution = ""
+	opts.Timestamp = time.Time{}
+	opts.Progress = nil
+	return strings.Join(kept, "\n"), opts
+}
+
+// Hint is a typed notification hint as accepted by notify-send's --hint
+type Hint struct {
+	Name  string
+	Value interface{}
+}
+
+// Action is a notification action button
+type Action struct {
+	Key   string
+	Label string
+}
+
+// ParseHint parses a notify-send style TYPE:NAME:VALUE hint.
+// Valid types are boolean, int, double, string and byte.
+func ParseHint(s string) (Hint, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 || parts[1] == "" {
+		return Hint{}, fmt.Errorf("invalid hint %q, expected TYPE:NAME:VALUE", s)
+	}
+
+	hintType, name, raw := strings.ToLower(parts[0]), parts[1], parts[2]
+
+	var value interface{}
+	switch hintType {
+	case "string":
+		value = raw
+	case "int":
+		v, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil {
+			return Hint{}, fmt.Errorf("invalid int value for hint %s: %s", name, raw)
+		}
+		value = int32(v)
+	case "double":
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return Hint{}, fmt.Errorf("invalid double value for hint %s: %s", name, raw)
+		}
+		value = v
+	case "byte":
+		v, err := strconv.ParseUint(raw, 10, 8)
+		if err != nil {
+			return Hint{}, fmt.Errorf("invalid byte value for hint %s: %s", name, raw)
+		}
+		value = byte(v)
+	case "boolean":
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Hint{}, fmt.Errorf("invalid boolean value for hint %s: %s", name, raw)
+		}
+		value = v
+	default:
+		return Hint{}, fmt.Errorf("invalid hint type %q (supported: boolean, int, double, string, byte)", parts[0])
+	}
+
+	return Hint{Name: name, Value: value}, nil
+}
+
+// ParseActions parses notify-send style [NAME=]Label actions.
+// Actions without a name are keyed by their position, starting at 0.
+func ParseActions(specs []string) ([]Action, error) {
+	actions := make([]Action, 0, len(specs))
+	for i, spec := range specs {
+		key, label, found := strings.Cut(spec, "=")
+		if !found {
+			key, label = strconv.Itoa(i), spec
+		}
+		if key == "" || label == "" {
+			return nil, fmt.Errorf("invalid action %q, expected [NAME=]Label", spec)
+		}
+		actions = append(actions, Action{Key: key, Label: label})
+	}
+	return actions, nil
+}
+
+// Updater is implemented by backends that can update a notification sent
+// again with the same tag, or with the ID it was given as ReplaceID,
+// instead of showing another one. CanUpdate reports whether they can as
+// they are set up.
+type Updater interface {
+	CanUpdate() bool
+}
+
+// CanUpdate reports whether the current backend updates notifications in
+// place
+func CanUpdate() bool {
+	u, ok := defaultBeeper.(Updater)
+	return ok && u.CanUpdate()
+}
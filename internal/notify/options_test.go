@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHint(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		expected    Hint
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:     "string hint",
+			spec:     "string:x-canonical-private-synchronous:volume",
+			expected: Hint{Name: "x-canonical-private-synchronous", Value: "volume"},
+		},
+		{
+			name:     "string value containing colons",
+			spec:     "string:sound-file:C:\\Windows\\Media\\ding.wav",
+			expected: Hint{Name: "sound-file", Value: "C:\\Windows\\Media\\ding.wav"},
+		},
+		{
+			name:     "int hint",
+			spec:     "int:value:42",
+			expected: Hint{Name: "value", Value: int32(42)},
+		},
+		{
+			name:     "double hint",
+			spec:     "double:x:1.5",
+			expected: Hint{Name: "x", Value: 1.5},
+		},
+		{
+			name:     "byte hint",
+			spec:     "byte:urgency:2",
+			expected: Hint{Name: "urgency", Value: byte(2)},
+		},
+		{
+			name:     "boolean hint with uppercase type",
+			spec:     "BOOLEAN:resident:true",
+			expected: Hint{Name: "resident", Value: true},
+		},
+		{
+			name:        "missing value",
+			spec:        "string:name",
+			expectError: true,
+			errorMsg:    "expected TYPE:NAME:VALUE",
+		},
+		{
+			name:        "empty name",
+			spec:        "string::value",
+			expectError: true,
+			errorMsg:    "expected TYPE:NAME:VALUE",
+		},
+		{
+			name:        "unknown type",
+			spec:        "variant:name:value",
+			expectError: true,
+			errorMsg:    "invalid hint type",
+		},
+		{
+			name:        "byte out of range",
+			spec:        "byte:urgency:300",
+			expectError: true,
+			errorMsg:    "invalid byte value",
+		},
+		{
+			name:        "invalid boolean",
+			spec:        "boolean:resident:maybe",
+			expectError: true,
+			errorMsg:    "invalid boolean value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hint, err := ParseHint(tt.spec)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, hint)
+			}
+		})
+	}
+}
+
+func TestParseActions(t *testing.T) {
+	actions, err := ParseActions([]string{"retry=Retry", "Ignore", "open=Open log"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Action{
+		{Key: "retry", Label: "Retry"},
+		{Key: "1", Label: "Ignore"},
+		{Key: "open", Label: "Open log"},
+	}, actions)
+
+	_, err = ParseActions([]string{"retry="})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid action")
+}
+
+func TestFoldRichContent(t *testing.T) {
+	opts := Options{
+		Urgency:     UrgencyNormal,
+		Lines:       []string{"3 passed", "", "1 skipped"},
+		Attribution: "via CI",
+		Timestamp:   time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC),
+	}
+
+	message, folded := FoldRichContent("Tests finished", opts)
+
+	assert.Equal(t, "Tests finished\n3 passed\n1 skipped\nvia CI", message)
+	assert.Equal(t, Options{Urgency: UrgencyNormal}, folded)
+
+	message, _ = FoldRichContent("", Options{Lines: []string{"only line"}})
+	assert.Equal(t, "only line", message)
+}
+
+func TestCanUpdate(t *testing.T) {
+	t.Cleanup(func() { SetBeeper(NewDefaultBeeper()) })
+
+	SetBeeper(NewPowerShellBeeper())
+	assert.True(t, CanUpdate())
+
+	SetBeeper(NewFakeBeeper(""))
+	assert.True(t, CanUpdate())
+
+	SetBeeper(&DefaultBeeper{})
+	assert.False(t, CanUpdate(), "beeep shows a new notification every time")
+
+	SetBeeper(&DefaultBeeper{dbus: NewDBusBeeper(nil)})
+	assert.True(t, CanUpdate())
+
+	SetBeeper(new(MockBeeper))
+	assert.False(t, CanUpdate())
+}
+
+func TestFoldRichContentProgress(t *testing.T) {
+	opts := Options{Progress: &Progress{Percent: 42, Label: "model.bin", Status: "3/7"}, Tag: "download"}
+
+	message, folded := FoldRichContent("Downloading", opts)
+
+	assert.Equal(t, "Downloading\nmodel.bin: 42% (3/7)", message)
+	assert.Equal(t, Options{Tag: "download"}, folded, "the identity is kept")
+	assert.Equal(t, "100%", Progress{Percent: 100}.String())
+}
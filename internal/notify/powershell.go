@@ -0,0 +1,422 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+)
+
+// powershellExe is Windows PowerShell, which can load the WinRT toast APIs.
+// Inside WSL it is reached through interop.
+const powershellExe = "powershell.exe"
+
+// PowerShellEnv names the interpreter the PowerShell backends run instead
+// of powershell.exe
+const PowerShellEnv = "WSL_NOTIFY_SEND_POWERSHELL"
+
+func init() {
+	RegisterBackend(Backend{
+		Name:        "powershell",
+		Description: "Windows toasts through PowerShell, with rich content and actions",
+		Available:   powershellAvailable,
+		New: func() (Beeper, error) {
+			return NewPowerShellBeeper(), nil
+		},
+	})
+}
+
+// PowerShellBeeper implements Beeper by showing toasts through the WinRT
+// API from a PowerShell script, which can render all of the rich content
+type PowerShellBeeper struct {
+	mu      sync.Mutex
+	appName string
+
+	// run executes a PowerShell script and returns its output. runWait, if
+	// set, runs the scripts that wait for the toast instead.
+	run     func(ctx context.Context, script string) (string, error)
+	runWait func(ctx context.Context, script string) (string, error)
+}
+
+// NewPowerShellBeeper creates a PowerShell backend
+func NewPowerShellBeeper() *PowerShellBeeper {
+	return &PowerShellBeeper{appName: "wsl-notify-send", run: runPowerShell}
+}
+
+func (b *PowerShellBeeper) Notify(title, message string, icon interface{}) error {
+	_, err := b.NotifyWithOptions(title, message, icon, Options{ExpireTimeout: ExpireDefault})
+	return err
+}
+
+func (b *PowerShellBeeper) Alert(title, message string, icon interface{}) error {
+	_, err := b.AlertWithOptions(title, message, icon, Options{ExpireTimeout: ExpireDefault})
+	return err
+}
+
+// Beep has no toast equivalent, so it uses beeep directly
+func (b *PowerShellBeeper) Beep(freq float64, duration int) error {
+	return beepBeep(freq, duration)
+}
+
+func (b *PowerShellBeeper) SetAppName(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.appName = name
+}
+
+// NotifyWithOptions shows a silent toast. Toasts have no numeric ID, so it
+// is always 0.
+func (b *PowerShellBeeper) NotifyWithOptions(title, message string, icon interface{}, opts Options) (uint32, error) {
+	return 0, b.send(title, message, icon, opts, false)
+}
+
+// AlertWithOptions shows a toast with the default notification sound
+func (b *PowerShellBeeper) AlertWithOptions(title, message string, icon interface{}, opts Options) (uint32, error) {
+	return 0, b.send(title, message, icon, opts, true)
+}
+
+// RichContent reports that toasts show the extra lines, attribution and
+// timestamp
+func (b *PowerShellBeeper) RichContent() bool {
+	return true
+}
+
+// CanUpdate reports that toasts sent again with the same tag are replaced
+func (b *PowerShellBeeper) CanUpdate() bool {
+	return true
+}
+
+// CanWait reports that the buttons of toasts can be waited for
+func (b *PowerShellBeeper) CanWait() bool {
+	return true
+}
+
+func (b *PowerShellBeeper) send(title, message string, icon interface{}, opts Options, sound bool) error {
+	b.mu.Lock()
+	appName := b.appName
+	b.mu.Unlock()
+
+	if err := CheckToastText(appName, title, message, opts); err != nil {
+		return err
+	}
+
+	image, cleanup := toastImage(icon)
+	defer cleanup()
+
+	_, err := b.run(context.Background(), ToastScript(appName, ToastXML(title, message, image, opts, sound), opts))
+	return err
+}
+
+// SendAndWait keeps PowerShell running until the toast is activated or
+// dismissed. Windows dismisses toasts with a TimedOut reason when they move
+// to the action center, which is reported as ErrTimeout.
+func (b *PowerShellBeeper) SendAndWait(ctx context.Context, title, message string, icon interface{}, opts Options, sound bool) (uint32, string, error) {
+	b.mu.Lock()
+	appName := b.appName
+	b.mu.Unlock()
+
+	if err := CheckToastText(appName, title, message, opts); err != nil {
+		return 0, "", err
+	}
+
+	image, cleanup := toastImage(icon)
+	defer cleanup()
+
+	run := b.run
+	if b.runWait != nil {
+		run = b.runWait
+	}
+
+	script := ToastWaitScript(appName, ToastXML(title, message, image, opts, sound), opts, waitTimeout(opts))
+	out, err := run(ctx, script)
+	if ctx.Err() != nil {
+		// PowerShell was killed, which is all its error says
+		return 0, "", ctx.Err()
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	action, err := toastResponse(out)
+	return 0, action, err
+}
+
+// ToastScript builds the PowerShell script that shows a toast. The app is
+// registered under the current user so Windows shows its name on the toast.
+// A toast with a progress bar and a tag updates the bar of the toast shown
+// with the same tag and group, and is only shown when there is none.
+func ToastScript(appName, toastXML string, opts Options) string {
+	show := "$notifier.Show($toast)"
+	if opts.Tag != "" && opts.Progress != nil {
+		identity := psQuote(opts.Tag)
+		if opts.Group != "" {
+			identity += ", " + psQuote(opts.Group)
+		}
+		show = "if ($notifier.Update($data, " + identity + ") -ne 'Succeeded') { " + show + " }"
+	}
+
+	return strings.Join(append(toastLines(appName, toastXML, opts), show), "\n")
+}
+
+// ToastWaitScript builds the PowerShell script that shows a toast and
+// prints how it ended: "activated:ARGUMENTS", "dismissed:REASON" or
+// "timeout" when nothing happened within timeout. A zero timeout waits for
+// as long as it takes.
+func ToastWaitScript(appName, toastXML string, opts Options, timeout time.Duration) string {
+	wait := "$event = Wait-Event"
+	if timeout > 0 {
+		seconds := int((timeout + time.Second - 1) / time.Second)
+		wait += " -Timeout " + strconv.Itoa(seconds)
+	}
+
+	return strings.Join(append(toastLines(appName, toastXML, opts),
+		"Register-ObjectEvent -InputObject $toast -EventName Activated -SourceIdentifier activated | Out-Null",
+		"Register-ObjectEvent -InputObject $toast -EventName Dismissed -SourceIdentifier dismissed | Out-Null",
+		"Register-ObjectEvent -InputObject $toast -EventName Failed -SourceIdentifier failed | Out-Null",
+		"$notifier.Show($toast)",
+		wait,
+		"if ($null -eq $event) { $notifier.Hide($toast); 'timeout' }",
+		"elseif ($event.SourceIdentifier -eq 'activated') { 'activated:' + ([Windows.UI.Notifications.ToastActivatedEventArgs]$event.SourceArgs[1]).Arguments }",
+		"elseif ($event.SourceIdentifier -eq 'dismissed') { 'dismissed:' + $event.SourceArgs[1].Reason }",
+		"else { throw $event.SourceArgs[1].ErrorCode }",
+	), "\n")
+}
+
+// toastLines registers the app and creates $toast, with its identity and
+// $data, and its $notifier
+func toastLines(appName, toastXML string, opts Options) []string {
+	lines := []string{
+		"$ErrorActionPreference = 'Stop'",
+		"$appId = " + psQuote(appID(appName)),
+		"$key = 'HKCU:\\Software\\Classes\\AppUserModelId\\' + $appId",
+		"New-Item -Path $key -Force | Out-Null",
+		"New-ItemProperty -Path $key -Name DisplayName -Value " + psQuote(appName) + " -PropertyType String -Force | Out-Null",
+		"[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null",
+		"[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom, ContentType = WindowsRuntime] | Out-Null",
+		"$xml = New-Object Windows.Data.Xml.Dom.XmlDocument",
+		"$xml.LoadXml(" + psQuote(toastXML) + ")",
+		"$toast = New-Object Windows.UI.Notifications.ToastNotification $xml",
+	}
+
+	if opts.Tag != "" {
+		lines = append(lines, "$toast.Tag = "+psQuote(opts.Tag))
+	}
+	if opts.Group != "" {
+		lines = append(lines, "$toast.Group = "+psQuote(opts.Group))
+	}
+
+	if data := ToastData(opts); data != nil {
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		lines = append(lines,
+			"[Windows.UI.Notifications.NotificationData, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null",
+			"$data = New-Object Windows.UI.Notifications.NotificationData",
+		)
+		for _, key := range keys {
+			lines = append(lines, "$data.Values["+psQuote(key)+"] = "+psQuote(data[key]))
+		}
+		lines = append(lines, "$toast.Data = $data")
+	}
+
+	return append(lines, "$notifier = [Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($appId)")
+}
+
+// toastResponse interprets the output of the wait script. Clicking the
+// toast itself activates it without arguments.
+func toastResponse(out string) (string, error) {
+	out = strings.TrimSpace(out)
+
+	switch {
+	case out == "timeout", out == "dismissed:TimedOut":
+		return "", ErrTimeout
+	case strings.HasPrefix(out, "dismissed:"):
+		return "", ErrDismissed
+	case strings.HasPrefix(out, "activated:"):
+		if action := strings.TrimPrefix(out, "activated:"); action != "" {
+			return action, nil
+		}
+		return DefaultAction, nil
+	default:
+		return "", fmt.Errorf("unexpected toast response %q", out)
+	}
+}
+
+// psQuote quotes s as a PowerShell single-quoted string. PowerShell also
+// treats the typographic single quotes as quotes, so they are doubled too.
+func psQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\'', '‘', '’', '‚', '‛':
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// appID derives the AppUserModelID toasts are shown under from the app name
+func appID(appName string) string {
+	id := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		case r == ' ':
+			return '-'
+		default:
+			return -1
+		}
+	}, appName)
+
+	if id == "" {
+		return "wsl-notify-send"
+	}
+	return id
+}
+
+// toastImage returns the Windows path of the icon, writing icon data to a
+// temporary file first. Stock icon names have no Windows equivalent and are
+// dropped. The cleanup function removes the temporary file.
+func toastImage(icon interface{}) (string, func()) {
+	noop := func() {}
+
+	switch i := icon.(type) {
+	case string:
+		if i == "" {
+			return "", noop
+		}
+		if _, err := os.Stat(i); err != nil {
+			return "", noop
+		}
+		path, err := windowsPath(i)
+		if err != nil {
+			return "", noop
+		}
+		return path, noop
+	case []byte:
+		f, err := os.CreateTemp("", "wsl-notify-send-*"+imageExt(i))
+		if err != nil {
+			return "", noop
+		}
+		cleanup := func() { os.Remove(f.Name()) }
+
+		_, err = f.Write(i)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			cleanup()
+			return "", noop
+		}
+
+		path, err := windowsPath(f.Name())
+		if err != nil {
+			cleanup()
+			return "", noop
+		}
+		return path, cleanup
+	default:
+		return "", noop
+	}
+}
+
+// imageExt picks a file extension for image data, so Windows can tell its
+// format
+func imageExt(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return ".jpg"
+	case bytes.HasPrefix(data, []byte("BM")):
+		return ".bmp"
+	case bytes.HasPrefix(data, []byte("\x00\x00\x01\x00")):
+		return ".ico"
+	default:
+		return ".png"
+	}
+}
+
+// windowsPath converts a path to the form Windows can open, using wslpath
+// inside WSL
+func windowsPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" {
+		return abs, nil
+	}
+
+	out, err := exec.Command("wslpath", "-w", abs).Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot convert %s to a Windows path: %w", abs, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// runPowerShell runs a script with PowerShell, passed encoded so no quoting
+// is needed on the command line. It returns what the script wrote to
+// stdout; on failure the error includes stderr.
+func runPowerShell(ctx context.Context, script string) (string, error) {
+	cmd := exec.CommandContext(ctx, powerShellPath(), "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass",
+		"-EncodedCommand", encodeCommand(script))
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if msg := strings.TrimSpace(string(exitErr.Stderr)); msg != "" {
+				return "", fmt.Errorf("%w: %s", err, msg)
+			}
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// encodeCommand encodes a script for -EncodedCommand: base64 of its UTF-16LE
+// bytes
+func encodeCommand(script string) string {
+	units := utf16.Encode([]rune(script))
+	data := make([]byte, 0, len(units)*2)
+	for _, u := range units {
+		data = append(data, byte(u), byte(u>>8))
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// powerShellPath returns the interpreter to run, powershell.exe unless
+// PowerShellEnv names another one
+func powerShellPath() string {
+	if path := os.Getenv(PowerShellEnv); path != "" {
+		return path
+	}
+	return powershellExe
+}
+
+// powershellAvailable checks for Windows PowerShell, natively or through WSL
+// interop
+func powershellAvailable() (bool, string) {
+	exe := powerShellPath()
+	path, err := exec.LookPath(exe)
+	if err != nil {
+		return false, exe + " not found in PATH"
+	}
+	return true, "using " + path
+}
@@ -0,0 +1,277 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of PowerShellHost
+const (
+	// hostIdleTimeout is how long PowerShell is kept running without toasts
+	hostIdleTimeout = 5 * time.Minute
+
+	// hostRequestTimeout bounds one script, after which PowerShell is
+	// restarted
+	hostRequestTimeout = 30 * time.Second
+
+	// hostStopGrace is how long PowerShell gets to exit once its input is
+	// closed
+	hostStopGrace = 2 * time.Second
+)
+
+// hostAck starts the line PowerShell answers each script with
+const hostAck = "@@wsl-notify-send"
+
+func init() {
+	RegisterBackend(Backend{
+		Name:        "powershell-host",
+		Description: "Windows toasts through one long-lived PowerShell, faster for repeated toasts",
+		Available:   powershellAvailable,
+		New: func() (Beeper, error) {
+			return NewPowerShellHostBeeper(NewPowerShellHost(powerShellPath())), nil
+		},
+	})
+}
+
+// NewPowerShellHostBeeper creates a PowerShell backend that shows toasts
+// through host. Waiting for a toast holds PowerShell until it is closed,
+// so those scripts still get a PowerShell of their own.
+func NewPowerShellHostBeeper(host *PowerShellHost) *PowerShellBeeper {
+	b := NewPowerShellBeeper()
+	b.run = host.Run
+	b.runWait = runPowerShell
+	return b
+}
+
+// PowerShellHost keeps one PowerShell running and feeds it scripts on
+// stdin, saving the start-up of a Windows process per toast. PowerShell is
+// started on the first script, restarted when it dies or hangs, and
+// stopped after it has been idle for a while.
+type PowerShellHost struct {
+	command []string
+	idle    time.Duration
+	timeout time.Duration
+
+	mu    sync.Mutex
+	proc  *hostProcess
+	seq   uint64
+	timer *time.Timer
+}
+
+// hostProcess is one running PowerShell
+type hostProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Scanner
+}
+
+// NewPowerShellHost creates a host that runs the interpreter at path,
+// reading commands from stdin
+func NewPowerShellHost(path string) *PowerShellHost {
+	return &PowerShellHost{
+		command: []string{path, "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-Command", "-"},
+		idle:    hostIdleTimeout,
+		timeout: hostRequestTimeout,
+	}
+}
+
+// Run runs a script and returns its output, like runPowerShell. Scripts
+// run one at a time.
+func (h *PowerShellHost) Run(ctx context.Context, script string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	h.seq++
+	request := hostRequest(h.seq, script)
+
+	// A PowerShell that died while idle never saw the script, so it is
+	// safe to try once more with a new one
+	err := h.write(request)
+	if err != nil {
+		h.stop(0)
+		err = h.write(request)
+	}
+	if err != nil {
+		h.stop(0)
+		return "", err
+	}
+
+	out, err := h.await(ctx, h.seq)
+	if err != nil {
+		var scriptErr *hostScriptError
+		if !errors.As(err, &scriptErr) {
+			h.stop(0)
+		}
+		return "", err
+	}
+
+	// A timer that fires while the next script waits for the lock must not
+	// stop the PowerShell it is about to use
+	seq := h.seq
+	h.timer = time.AfterFunc(h.idle, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.seq == seq {
+			h.stop(hostStopGrace)
+		}
+	})
+	return out, nil
+}
+
+// Close stops PowerShell if it is running. The next script starts it again.
+func (h *PowerShellHost) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	h.stop(hostStopGrace)
+	return nil
+}
+
+// write sends a request, starting PowerShell if needed
+func (h *PowerShellHost) write(request string) error {
+	if h.proc == nil {
+		proc, err := startHost(h.command)
+		if err != nil {
+			return err
+		}
+		h.proc = proc
+	}
+
+	if _, err := io.WriteString(h.proc.stdin, request); err != nil {
+		return fmt.Errorf("cannot send script to %s: %w", h.command[0], err)
+	}
+	return nil
+}
+
+// await reads the output of PowerShell until the answer to request seq,
+// skipping anything else it prints. PowerShell is killed when ctx is done.
+func (h *PowerShellHost) await(ctx context.Context, seq uint64) (string, error) {
+	proc := h.proc
+	stop := context.AfterFunc(ctx, func() { _ = proc.cmd.Process.Kill() })
+	defer stop()
+
+	for proc.stdout.Scan() {
+		if out, ok, err := parseHostAck(proc.stdout.Text(), seq); ok {
+			return out, err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("%s did not answer: %w", h.command[0], ctx.Err())
+	}
+	if err := proc.stdout.Err(); err != nil {
+		return "", fmt.Errorf("cannot read from %s: %w", h.command[0], err)
+	}
+	return "", fmt.Errorf("%s exited unexpectedly", h.command[0])
+}
+
+// stop ends PowerShell by closing its input, killing it if it hasn't
+// exited within grace
+func (h *PowerShellHost) stop(grace time.Duration) {
+	if h.proc == nil {
+		return
+	}
+	proc := h.proc
+	h.proc = nil
+
+	proc.stdin.Close()
+	done := make(chan struct{})
+	go func() {
+		_ = proc.cmd.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(grace):
+		_ = proc.cmd.Process.Kill()
+		<-done
+	}
+}
+
+// startHost starts PowerShell reading commands from stdin
+func startHost(command []string) (*hostProcess, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("cannot start %s: %w", command[0], err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(nil, 1<<20)
+	return &hostProcess{cmd: cmd, stdin: stdin, stdout: scanner}, nil
+}
+
+// hostScriptError is a script that failed in a PowerShell that is still
+// running fine
+type hostScriptError struct {
+	msg string
+}
+
+func (e *hostScriptError) Error() string {
+	return e.msg
+}
+
+// hostRequest wraps a script into a single line of PowerShell, since
+// -Command - runs its input line by line. It answers with
+// "@@wsl-notify-send SEQ ok OUTPUT" or "@@wsl-notify-send SEQ error
+// MESSAGE", both base64 encoded.
+func hostRequest(seq uint64, script string) string {
+	return "$ack = '" + hostAck + " " + strconv.FormatUint(seq, 10) + " '; " +
+		"try { $out = & ([ScriptBlock]::Create([Text.Encoding]::Unicode.GetString([Convert]::FromBase64String('" + encodeCommand(script) + "')))) | Out-String; " +
+		"[Console]::Out.WriteLine($ack + 'ok ' + [Convert]::ToBase64String([Text.Encoding]::UTF8.GetBytes($out))) } " +
+		"catch { [Console]::Out.WriteLine($ack + 'error ' + [Convert]::ToBase64String([Text.Encoding]::UTF8.GetBytes($_.Exception.Message))) }\n"
+}
+
+// parseHostAck interprets a line of output, reporting whether it is the
+// answer to request seq
+func parseHostAck(line string, seq uint64) (string, bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != hostAck || fields[1] != strconv.FormatUint(seq, 10) {
+		return "", false, nil
+	}
+
+	var payload string
+	if len(fields) > 3 {
+		data, err := base64.StdEncoding.DecodeString(fields[3])
+		if err != nil {
+			return "", true, fmt.Errorf("malformed answer from PowerShell: %w", err)
+		}
+		payload = string(data)
+	}
+
+	switch fields[2] {
+	case "ok":
+		return payload, true, nil
+	case "error":
+		return "", true, &hostScriptError{msg: strings.TrimSpace(payload)}
+	default:
+		return "", true, fmt.Errorf("malformed answer from PowerShell: %q", line)
+	}
+}
@@ -0,0 +1,195 @@
+package notify
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeShell stands in for PowerShell: it logs every start, answers each
+// request with its sequence number or as the mode says, and exits when the
+// crash file exists
+const fakeShell = `#!/bin/sh
+echo started >> "$2"
+while IFS= read -r line; do
+  seq=$(echo "$line" | sed -n "s/^.ack = '@@wsl-notify-send \([0-9]*\) '.*/\1/p")
+  echo "noise before the answer"
+  if [ -e "$2.crash" ]; then
+    rm "$2.crash"
+    exit 3
+  fi
+  case "$1" in
+    error) echo "@@wsl-notify-send $seq error $(printf 'Access denied' | base64)" ;;
+    hang) ;;
+    *) echo "@@wsl-notify-send $seq ok $(printf 'shown %s' "$seq" | base64)" ;;
+  esac
+done
+`
+
+// newFakeHost returns a host running fakeShell in mode, and the file its
+// starts are logged to
+func newFakeHost(t *testing.T, mode string) (*PowerShellHost, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake shell needs sh")
+	}
+
+	dir := t.TempDir()
+	shell := filepath.Join(dir, "powershell.sh")
+	require.NoError(t, os.WriteFile(shell, []byte(fakeShell), 0o755))
+
+	log := filepath.Join(dir, "starts.log")
+	h := NewPowerShellHost(shell)
+	h.command = []string{shell, mode, log}
+	t.Cleanup(func() { h.Close() })
+	return h, log
+}
+
+// starts counts how often the fake shell was started
+func starts(t *testing.T, log string) int {
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	return strings.Count(string(data), "started")
+}
+
+// running reports whether the host has a PowerShell running
+func running(h *PowerShellHost) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.proc != nil
+}
+
+func TestPowerShellHost_Run(t *testing.T) {
+	h, log := newFakeHost(t, "ok")
+
+	for _, want := range []string{"shown 1", "shown 2", "shown 3"} {
+		out, err := h.Run(context.Background(), "$notifier.Show($toast)")
+		require.NoError(t, err)
+		assert.Equal(t, want, out)
+	}
+	assert.Equal(t, 1, starts(t, log))
+}
+
+func TestPowerShellHost_ScriptError(t *testing.T) {
+	h, log := newFakeHost(t, "error")
+
+	_, err := h.Run(context.Background(), "throw 'Access denied'")
+	assert.EqualError(t, err, "Access denied")
+
+	// A failing script leaves PowerShell running
+	_, err = h.Run(context.Background(), "throw 'Access denied'")
+	assert.Error(t, err)
+	assert.Equal(t, 1, starts(t, log))
+}
+
+func TestPowerShellHost_Crash(t *testing.T) {
+	h, log := newFakeHost(t, "ok")
+
+	_, err := h.Run(context.Background(), "$notifier.Show($toast)")
+	require.NoError(t, err)
+
+	// Crashing during a script fails it, as it may have shown the toast
+	require.NoError(t, os.WriteFile(log+".crash", nil, 0o600))
+	_, err = h.Run(context.Background(), "$notifier.Show($toast)")
+	assert.ErrorContains(t, err, "exited unexpectedly")
+	assert.False(t, running(h))
+
+	out, err := h.Run(context.Background(), "$notifier.Show($toast)")
+	require.NoError(t, err)
+	assert.Equal(t, "shown 3", out)
+	assert.Equal(t, 2, starts(t, log))
+}
+
+func TestPowerShellHost_DiedWhileIdle(t *testing.T) {
+	h, log := newFakeHost(t, "ok")
+
+	_, err := h.Run(context.Background(), "$notifier.Show($toast)")
+	require.NoError(t, err)
+
+	h.mu.Lock()
+	require.NoError(t, h.proc.cmd.Process.Kill())
+	_, _ = h.proc.cmd.Process.Wait()
+	h.mu.Unlock()
+
+	// The script never reached the dead PowerShell, so it is sent again
+	out, err := h.Run(context.Background(), "$notifier.Show($toast)")
+	require.NoError(t, err)
+	assert.Equal(t, "shown 2", out)
+	assert.Equal(t, 2, starts(t, log))
+}
+
+func TestPowerShellHost_Timeout(t *testing.T) {
+	h, _ := newFakeHost(t, "hang")
+	h.timeout = 100 * time.Millisecond
+
+	_, err := h.Run(context.Background(), "Start-Sleep 60")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, running(h))
+}
+
+func TestPowerShellHost_Idle(t *testing.T) {
+	h, log := newFakeHost(t, "ok")
+	h.idle = 50 * time.Millisecond
+
+	_, err := h.Run(context.Background(), "$notifier.Show($toast)")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return !running(h) }, 2*time.Second, 10*time.Millisecond)
+
+	_, err = h.Run(context.Background(), "$notifier.Show($toast)")
+	require.NoError(t, err)
+	assert.Equal(t, 2, starts(t, log))
+}
+
+func TestPowerShellHostBeeper(t *testing.T) {
+	h, log := newFakeHost(t, "ok")
+	b := NewPowerShellHostBeeper(h)
+
+	require.NoError(t, b.Notify("Build", "Done", ""))
+	require.NoError(t, b.Alert("Build", "Failed", ""))
+	assert.Equal(t, 1, starts(t, log))
+}
+
+func TestHostRequest(t *testing.T) {
+	request := hostRequest(7, "'héllo'")
+
+	assert.True(t, strings.HasPrefix(request, "$ack = '@@wsl-notify-send 7 '; "))
+	assert.True(t, strings.HasSuffix(request, "\n"))
+	assert.Equal(t, 1, strings.Count(request, "\n"), "-Command - runs its input line by line")
+	assert.Contains(t, request, encodeCommand("'héllo'"))
+}
+
+func TestParseHostAck(t *testing.T) {
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	out, ok, err := parseHostAck("@@wsl-notify-send 3 ok "+encode("activated:retry\r\n")+"\r", 3)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, "activated:retry\r\n", out)
+
+	out, ok, err = parseHostAck("@@wsl-notify-send 3 ok ", 3)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+
+	_, ok, err = parseHostAck("@@wsl-notify-send 3 error "+encode("Access denied\r\n"), 3)
+	assert.True(t, ok)
+	assert.EqualError(t, err, "Access denied")
+
+	_, ok, _ = parseHostAck("@@wsl-notify-send 2 ok", 3)
+	assert.False(t, ok, "answers to earlier scripts are skipped")
+
+	_, ok, _ = parseHostAck("PS C:\\>", 3)
+	assert.False(t, ok)
+
+	_, ok, err = parseHostAck("@@wsl-notify-send 3 ok !!!", 3)
+	assert.True(t, ok)
+	assert.ErrorContains(t, err, "malformed answer")
+}
@@ -0,0 +1,279 @@
+package notify
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPowerShellBeeper(t *testing.T) {
+	var scripts []string
+	b := NewPowerShellBeeper()
+	b.run = func(_ context.Context, script string) (string, error) {
+		scripts = append(scripts, script)
+		return "", nil
+	}
+	b.SetAppName("Build Bot")
+
+	opts := Options{ExpireTimeout: ExpireDefault, Lines: []string{"3 passed"}, Attribution: "via CI"}
+	id, err := Deliver(b, "Build", "Done", "dialog-information", opts, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), id)
+	require.Len(t, scripts, 1)
+
+	// Rich content reaches the toast instead of being folded into the message
+	assert.Contains(t, scripts[0], "<text>Done</text><text>3 passed</text>")
+	assert.Contains(t, scripts[0], `<text placement="attribution">via CI</text>`)
+	assert.Contains(t, scripts[0], toastSound)
+	assert.Contains(t, scripts[0], "$appId = 'Build-Bot'")
+	assert.Contains(t, scripts[0], "-Value 'Build Bot'")
+	assert.NotContains(t, scripts[0], "appLogoOverride", "stock icons have no Windows equivalent")
+}
+
+func TestPowerShellBeeper_Error(t *testing.T) {
+	b := NewPowerShellBeeper()
+	b.run = func(context.Context, string) (string, error) { return "", assert.AnError }
+
+	err := b.Notify("Title", "Message", "")
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestPowerShellBeeper_ControlCharacters(t *testing.T) {
+	b := NewPowerShellBeeper()
+	b.run = func(context.Context, string) (string, error) {
+		t.Fatal("no script should run")
+		return "", nil
+	}
+
+	err := b.Notify("Build", "\x1b]0;pwned\x07", "")
+	assert.ErrorIs(t, err, ErrUnsafeText)
+
+	_, _, err = b.SendAndWait(context.Background(), "Build\x00", "", "", Options{}, false)
+	assert.ErrorIs(t, err, ErrUnsafeText)
+}
+
+func TestPowerShellBeeper_SendAndWait(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		wantAction string
+		wantErr    error
+	}{
+		{"button", "activated:retry\r\n", "retry", nil},
+		{"toast body", "activated:\r\n", DefaultAction, nil},
+		{"dismissed", "dismissed:UserCanceled\r\n", "", ErrDismissed},
+		{"hidden", "dismissed:ApplicationHidden\r\n", "", ErrDismissed},
+		{"moved to action center", "dismissed:TimedOut\r\n", "", ErrTimeout},
+		{"wait timed out", "timeout\r\n", "", ErrTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var script string
+			b := NewPowerShellBeeper()
+			b.run = func(_ context.Context, s string) (string, error) {
+				script = s
+				return tt.output, nil
+			}
+
+			opts := Options{ExpireTimeout: 5000, Actions: []Action{{Key: "retry", Label: "Retry"}}}
+			_, action, err := b.SendAndWait(context.Background(), "Deploy failed", "Retry?", "", opts, false)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantAction, action)
+			assert.Contains(t, script, `<action content="Retry" arguments="retry"/>`)
+			assert.Contains(t, script, "Wait-Event -Timeout 7")
+		})
+	}
+}
+
+func TestPowerShellBeeper_SendAndWaitUnexpectedOutput(t *testing.T) {
+	b := NewPowerShellBeeper()
+	b.run = func(context.Context, string) (string, error) { return "garbage", nil }
+
+	_, _, err := b.SendAndWait(context.Background(), "Title", "Message", "", Options{}, false)
+	assert.ErrorContains(t, err, "unexpected toast response")
+}
+
+func TestToastWaitScript(t *testing.T) {
+	forever := ToastWaitScript("app", "<toast/>", Options{}, 0)
+	assert.Contains(t, forever, "$event = Wait-Event\n")
+	assert.Contains(t, forever, "$notifier.Show($toast)")
+
+	assert.Contains(t, ToastWaitScript("app", "<toast/>", Options{}, 1500*time.Millisecond), "Wait-Event -Timeout 2\n")
+}
+
+func TestToastScriptProgress(t *testing.T) {
+	opts := Options{Tag: "backup", Group: "nightly", Progress: &Progress{Percent: 42, Label: "Copying"}}
+	script := ToastScript("app", "<toast/>", opts)
+
+	assert.Contains(t, script, "$toast.Tag = 'backup'\n$toast.Group = 'nightly'\n")
+	assert.Contains(t, script, "$data.Values['progressStatus'] = ''\n"+
+		"$data.Values['progressTitle'] = 'Copying'\n"+
+		"$data.Values['progressValue'] = '0.42'\n"+
+		"$toast.Data = $data\n")
+	assert.True(t, strings.HasSuffix(script,
+		"if ($notifier.Update($data, 'backup', 'nightly') -ne 'Succeeded') { $notifier.Show($toast) }"))
+
+	// Without a tag there is nothing to update
+	script = ToastScript("app", "<toast/>", Options{Progress: opts.Progress})
+	assert.Contains(t, script, "$toast.Data = $data")
+	assert.NotContains(t, script, "$toast.Tag")
+	assert.True(t, strings.HasSuffix(script, "\n$notifier.Show($toast)"))
+
+	// A tag alone replaces the toast
+	script = ToastScript("app", "<toast/>", Options{Tag: "backup"})
+	assert.Contains(t, script, "$toast.Tag = 'backup'")
+	assert.NotContains(t, script, "$data")
+	assert.True(t, strings.HasSuffix(script, "\n$notifier.Show($toast)"))
+}
+
+func TestPSQuote(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"plain", "'plain'"},
+		{"it's", "'it''s'"},
+		{"$(Remove-Item C:\\)", "'$(Remove-Item C:\\)'"},
+		{"smart ‘quotes’ ‚low‛", "'smart ‘‘quotes’’ ‚‚low‛‛'"},
+		{"", "''"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, psQuote(tt.input))
+		})
+	}
+}
+
+func TestAppID(t *testing.T) {
+	assert.Equal(t, "wsl-notify-send", appID("wsl-notify-send"))
+	assert.Equal(t, "Claude-Code", appID("Claude Code"))
+	assert.Equal(t, "Build-api", appID("Build: api"))
+	assert.Equal(t, "wsl-notify-send", appID("✓"))
+}
+
+func TestEncodeCommand(t *testing.T) {
+	script := "Write-Output 'héllo ✓'"
+
+	data, err := base64.StdEncoding.DecodeString(encodeCommand(script))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(data)%2)
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+	}
+	assert.Equal(t, script, string(utf16.Decode(units)))
+}
+
+func TestImageExt(t *testing.T) {
+	assert.Equal(t, ".png", imageExt([]byte("\x89PNG\r\n\x1a\n")))
+	assert.Equal(t, ".jpg", imageExt([]byte("\xff\xd8\xff\xe0")))
+	assert.Equal(t, ".bmp", imageExt([]byte("BM6")))
+	assert.Equal(t, ".ico", imageExt([]byte("\x00\x00\x01\x00\x01")))
+}
+
+// psSplit reads a script the way the PowerShell tokenizer reads single-quoted
+// strings: any of the quote characters opens and closes one, and two of
+// them in a row inside stand for the second. It returns the code with every
+// string replaced by an empty one, and the values of the strings.
+func psSplit(script string) (string, []string, error) {
+	isQuote := func(r rune) bool { return strings.ContainsRune("'‘’‚‛", r) }
+
+	var code strings.Builder
+	var literals []string
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		if !isQuote(runes[i]) {
+			code.WriteRune(runes[i])
+			continue
+		}
+
+		var literal strings.Builder
+		closed := false
+		for i++; i < len(runes); i++ {
+			if !isQuote(runes[i]) {
+				literal.WriteRune(runes[i])
+				continue
+			}
+			if i+1 < len(runes) && isQuote(runes[i+1]) {
+				i++
+				literal.WriteRune(runes[i])
+				continue
+			}
+			closed = true
+			break
+		}
+		if !closed {
+			return "", nil, errors.New("unterminated string")
+		}
+		code.WriteString("''")
+		literals = append(literals, literal.String())
+	}
+	return code.String(), literals, nil
+}
+
+// FuzzToastScript checks that no app name, title or message can break out
+// of the strings they are quoted in: the code of every script stays the
+// same as for harmless content, and the strings hold the content unchanged
+func FuzzToastScript(f *testing.F) {
+	f.Add("wsl-notify-send", "Build", "Done")
+	f.Add("it's", "'; Remove-Item C:\\ -Recurse; '", "$(calc.exe)")
+	f.Add("‘smart’", "‚low‛", "`\"double`\" @' here '@")
+	f.Add("\xff", "</text>", "\r\n\t")
+	f.Add("", "", "")
+
+	harmlessXML := ToastXML("t", "m", "", Options{ExpireTimeout: ExpireDefault}, false)
+	scripts := map[string]func(appName, toastXML string) string{
+		"show": func(appName, toastXML string) string { return ToastScript(appName, toastXML, Options{}) },
+		"wait": func(appName, toastXML string) string {
+			return ToastWaitScript(appName, toastXML, Options{}, 5*time.Second)
+		},
+	}
+
+	f.Fuzz(func(t *testing.T, appName, title, message string) {
+		quoted, literals, err := psSplit(psQuote(appName))
+		require.NoError(t, err)
+		assert.Equal(t, "''", quoted)
+		assert.Equal(t, []string{string([]rune(appName))}, literals)
+
+		if CheckToastText(appName, title, message, Options{}) != nil {
+			return
+		}
+		toastXML := ToastXML(title, message, "", Options{ExpireTimeout: ExpireDefault}, false)
+
+		for name, build := range scripts {
+			script := build(appName, toastXML)
+			wantCode, _, err := psSplit(build("app", harmlessXML))
+			require.NoError(t, err)
+
+			code, literals, err := psSplit(script)
+			require.NoError(t, err, name)
+			assert.Equal(t, wantCode, code, name)
+			assert.Contains(t, literals, string([]rune(appName)), name)
+			assert.Contains(t, literals, toastXML, name)
+
+			// The persistent host wraps the script once more
+			wantCode, _, err = psSplit(hostRequest(1, build("app", harmlessXML)))
+			require.NoError(t, err)
+			code, literals, err = psSplit(hostRequest(1, script))
+			require.NoError(t, err, name)
+			assert.Equal(t, wantCode, code, name)
+			assert.Contains(t, literals, encodeCommand(script), name)
+		}
+	})
+}
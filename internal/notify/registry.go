@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"wsl-notify-send/internal/exitcode"
+)
+
+// Backend describes a named notification backend that can be selected
+// with --backend
+type Backend struct {
+	Name        string
+	Description string
+
+	// Available reports whether the backend can be used on this machine,
+	// with a short reason either way
+	Available func() (bool, string)
+
+	// New creates the Beeper that delivers the notifications
+	New func() (Beeper, error)
+}
+
+var (
+	backendsMu sync.Mutex
+	backends   = make(map[string]Backend)
+)
+
+// AutoBackend is the backend that picks D-Bus or beeep from the environment
+const AutoBackend = "auto"
+
+func init() {
+	RegisterBackend(Backend{
+		Name:        AutoBackend,
+		Description: "Picks dbus or beeep from the environment",
+		Available: func() (bool, string) {
+			if NewDefaultBeeper().UsesDBus() {
+				return true, "currently uses dbus"
+			}
+			return true, "currently uses beeep"
+		},
+		New: func() (Beeper, error) {
+			return NewDefaultBeeper(), nil
+		},
+	})
+
+	RegisterBackend(Backend{
+		Name:        "beeep",
+		Description: "Windows toasts through the beeep library",
+		Available: func() (bool, string) {
+			switch runtime.GOOS {
+			case "windows", "darwin", "linux", "freebsd", "netbsd", "openbsd", "illumos":
+				return true, "supported on " + runtime.GOOS
+			default:
+				return false, "not supported on " + runtime.GOOS
+			}
+		},
+		New: func() (Beeper, error) {
+			return &DefaultBeeper{}, nil
+		},
+	})
+}
+
+// RegisterBackend makes a backend available by name. Registering the same
+// name twice replaces the earlier backend.
+func RegisterBackend(b Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backends[b.Name] = b
+}
+
+// LookupBackend returns the backend registered under name
+func LookupBackend(name string) (Backend, bool) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	b, ok := backends[name]
+	return b, ok
+}
+
+// Backends returns all registered backends sorted by name
+func Backends() []Backend {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	list := make([]Backend, 0, len(backends))
+	for _, b := range backends {
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// BackendNames returns the names of all registered backends
+func BackendNames() []string {
+	var names []string
+	for _, b := range Backends() {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+// UseBackend makes the named backend the one notifications are sent through
+func UseBackend(name string) error {
+	b, ok := LookupBackend(name)
+	if !ok {
+		return fmt.Errorf("unknown backend: %s", name)
+	}
+
+	if ok, reason := b.Available(); !ok {
+		return exitcode.Mark(ErrBackendUnavailable, fmt.Errorf("backend %s is not available: %s", name, reason))
+	}
+
+	beeper, err := b.New()
+	if err != nil {
+		return exitcode.Mark(ErrBackendUnavailable, fmt.Errorf("cannot create backend %s: %w", name, err))
+	}
+
+	SetBeeper(beeper)
+	return nil
+}
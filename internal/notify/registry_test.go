@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinBackends(t *testing.T) {
+	names := BackendNames()
+	assert.Contains(t, names, AutoBackend)
+	assert.Contains(t, names, "beeep")
+	assert.Contains(t, names, "dbus")
+	assert.Contains(t, names, "fake")
+	assert.Contains(t, names, "powershell-host")
+
+	for _, b := range Backends() {
+		ok, reason := b.Available()
+		assert.NotEmpty(t, reason, "backend %s should explain its availability", b.Name)
+		if b.Name == AutoBackend {
+			assert.True(t, ok)
+		}
+	}
+}
+
+func TestBackendsSorted(t *testing.T) {
+	names := BackendNames()
+	for i := 1; i < len(names); i++ {
+		assert.Less(t, names[i-1], names[i])
+	}
+}
+
+func TestUseBackend(t *testing.T) {
+	originalBeeper := defaultBeeper
+	t.Cleanup(func() { SetBeeper(originalBeeper) })
+
+	mockBeeper := new(MockBeeper)
+	RegisterBackend(Backend{
+		Name:      "test-available",
+		Available: func() (bool, string) { return true, "always" },
+		New:       func() (Beeper, error) { return mockBeeper, nil },
+	})
+	RegisterBackend(Backend{
+		Name:      "test-unavailable",
+		Available: func() (bool, string) { return false, "never on this machine" },
+		New:       func() (Beeper, error) { return mockBeeper, nil },
+	})
+	RegisterBackend(Backend{
+		Name:      "test-broken",
+		Available: func() (bool, string) { return true, "always" },
+		New:       func() (Beeper, error) { return nil, errors.New("boom") },
+	})
+
+	require.NoError(t, UseBackend("test-available"))
+	assert.Equal(t, mockBeeper, GetBeeper())
+
+	err := UseBackend("test-unavailable")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "backend test-unavailable is not available: never on this machine")
+
+	err = UseBackend("test-broken")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot create backend test-broken")
+
+	err = UseBackend("missing")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown backend: missing")
+}
+
+func TestBeeepBackendNeverUsesDBus(t *testing.T) {
+	b, ok := LookupBackend("beeep")
+	require.True(t, ok)
+
+	beeper, err := b.New()
+	require.NoError(t, err)
+	assert.False(t, beeper.(*DefaultBeeper).UsesDBus())
+}
@@ -0,0 +1,186 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"wsl-notify-send/internal/exitcode"
+)
+
+// maxToastTexts is the number of text elements a toast shows, the title
+// included
+const maxToastTexts = 3
+
+// maxToastActions is the number of buttons a toast shows
+const maxToastActions = 5
+
+// toastProgress is the progress bar of a toast. Its values are bound from
+// the toast data, so they can be updated without showing the toast again.
+const toastProgress = `<progress title="{progressTitle}" value="{progressValue}" status="{progressStatus}"/>`
+
+// toastDismiss is the system dismiss button, labelled by Windows. Reminder
+// toasts only stay on screen when they have a button.
+const toastDismiss = `<action activationType="system" arguments="dismiss" content=""/>`
+
+// Toast audio for silent notifications and alerts
+const (
+	toastSilent = `<audio silent="true"/>`
+	toastSound  = `<audio src="ms-winsoundevent:Notification.Default"/>`
+)
+
+// ToastXML builds the XML of a Windows toast. The message and extra lines
+// fill the text elements after the title; when there are more than fit, the
+// remaining lines share the last one. Actions become buttons whose
+// arguments are the action keys, up to five of them. The progress bar takes
+// its values from ToastData. image is the path or URI of the app logo, or ""
+// for none. Critical toasts are reminders, which stay on screen until
+// dismissed.
+func ToastXML(title, message, image string, opts Options, sound bool) string {
+	var b strings.Builder
+	reminder := opts.Urgency == UrgencyCritical
+
+	b.WriteString("<toast")
+	if reminder {
+		writeAttr(&b, "scenario", "reminder")
+	}
+	if !opts.Timestamp.IsZero() {
+		writeAttr(&b, "displayTimestamp", opts.Timestamp.UTC().Format(time.RFC3339))
+	}
+	if opts.ExpireTimeout == 0 || opts.ExpireTimeout > 10000 {
+		writeAttr(&b, "duration", "long")
+	}
+	b.WriteString(`><visual><binding template="ToastGeneric">`)
+
+	if image != "" {
+		b.WriteString(`<image placement="appLogoOverride"`)
+		writeAttr(&b, "src", image)
+		b.WriteString("/>")
+	}
+
+	for _, text := range toastTexts(title, message, opts.Lines) {
+		writeElement(&b, "<text>", text, "</text>")
+	}
+	if opts.Attribution != "" {
+		writeElement(&b, `<text placement="attribution">`, opts.Attribution, "</text>")
+	}
+	if opts.Progress != nil {
+		b.WriteString(toastProgress)
+	}
+
+	b.WriteString("</binding></visual>")
+	writeActions(&b, opts.Actions, reminder)
+	if sound {
+		b.WriteString(toastSound)
+	} else {
+		b.WriteString(toastSilent)
+	}
+	b.WriteString("</toast>")
+
+	return b.String()
+}
+
+// ToastData returns the values bound into the progress bar of the toast
+// built for opts, or nil when it has none
+func ToastData(opts Options) map[string]string {
+	if opts.Progress == nil {
+		return nil
+	}
+	return map[string]string{
+		"progressValue":  strconv.FormatFloat(float64(opts.Progress.Percent)/100, 'f', -1, 64),
+		"progressTitle":  opts.Progress.Label,
+		"progressStatus": opts.Progress.Status,
+	}
+}
+
+// CheckToastText rejects toast content with control characters other than
+// tabs and line breaks. Titles and messages come from commit messages, logs
+// and tool output; control characters have no place on a toast and most of
+// them can't be represented in its XML at all. The error is ErrUnsafeText.
+func CheckToastText(appName, title, message string, opts Options) error {
+	type field struct{ name, text string }
+	fields := []field{
+		{"app name", appName}, {"title", title}, {"message", message}, {"attribution", opts.Attribution},
+		{"tag", opts.Tag}, {"group", opts.Group},
+	}
+	for _, line := range opts.Lines {
+		fields = append(fields, field{"line", line})
+	}
+	for _, action := range opts.Actions {
+		fields = append(fields, field{"action", action.Key}, field{"action", action.Label})
+	}
+	if opts.Progress != nil {
+		fields = append(fields, field{"progress label", opts.Progress.Label}, field{"progress status", opts.Progress.Status})
+	}
+
+	for _, f := range fields {
+		for _, r := range f.text {
+			if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+				return exitcode.Mark(ErrUnsafeText, fmt.Errorf("%s contains control character %U", f.name, r))
+			}
+		}
+	}
+	return nil
+}
+
+// toastTexts lays out the title, message and lines over the text elements
+func toastTexts(title, message string, lines []string) []string {
+	var body []string
+	if message != "" {
+		body = append(body, message)
+	}
+	body = append(body, lines...)
+
+	if len(body) > maxToastTexts-1 {
+		last := strings.Join(body[maxToastTexts-2:], "\n")
+		body = append(body[:maxToastTexts-2], last)
+	}
+
+	return append([]string{title}, body...)
+}
+
+// writeActions adds a button for each action, or the dismiss button when
+// there are none and dismiss is set
+func writeActions(b *strings.Builder, actions []Action, dismiss bool) {
+	if len(actions) == 0 {
+		if dismiss {
+			b.WriteString("<actions>" + toastDismiss + "</actions>")
+		}
+		return
+	}
+	if len(actions) > maxToastActions {
+		actions = actions[:maxToastActions]
+	}
+
+	b.WriteString("<actions>")
+	for _, action := range actions {
+		b.WriteString("<action")
+		writeAttr(b, "content", action.Label)
+		writeAttr(b, "arguments", action.Key)
+		b.WriteString("/>")
+	}
+	b.WriteString("</actions>")
+}
+
+func writeAttr(b *strings.Builder, name, value string) {
+	b.WriteString(" " + name + `="`)
+	b.WriteString(escapeXML(value))
+	b.WriteString(`"`)
+}
+
+func writeElement(b *strings.Builder, open, text, close string) {
+	b.WriteString(open)
+	b.WriteString(escapeXML(text))
+	b.WriteString(close)
+}
+
+// escapeXML escapes text for use in XML content and attribute values.
+// Characters XML can't represent are replaced.
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
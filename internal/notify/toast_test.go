@@ -0,0 +1,273 @@
+package notify
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToastXML(t *testing.T) {
+	opts := Options{
+		ExpireTimeout: ExpireDefault,
+		Lines:         []string{"3 passed"},
+		Attribution:   "via CI",
+		Timestamp:     time.Date(2026, 10, 16, 11, 30, 0, 0, time.FixedZone("CEST", 2*60*60)),
+	}
+
+	got := ToastXML("Build", "Tests finished", `C:\icons\ok.png`, opts, false)
+
+	want := `<toast displayTimestamp="2026-10-16T09:30:00Z"><visual><binding template="ToastGeneric">` +
+		`<image placement="appLogoOverride" src="C:\icons\ok.png"/>` +
+		`<text>Build</text><text>Tests finished</text><text>3 passed</text>` +
+		`<text placement="attribution">via CI</text>` +
+		`</binding></visual><audio silent="true"/></toast>`
+	assert.Equal(t, want, got)
+}
+
+func TestToastXMLTextLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		lines   []string
+		want    string
+	}{
+		{"title only", "", nil, "<text>T</text>"},
+		{"message", "M", nil, "<text>T</text><text>M</text>"},
+		{"lines without message", "", []string{"a", "b"}, "<text>T</text><text>a</text><text>b</text>"},
+		{"overflow shares the last text", "M", []string{"a", "b", "c"}, "<text>T</text><text>M</text><text>a&#xA;b&#xA;c</text>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ToastXML("T", tt.message, "", Options{ExpireTimeout: ExpireDefault, Lines: tt.lines}, false)
+			assert.Contains(t, got, `<binding template="ToastGeneric">`+tt.want+"</binding>")
+		})
+	}
+}
+
+func TestToastXMLEscaping(t *testing.T) {
+	got := ToastXML(`<b>"Deploy"</b> & co`, "it's </text><text>injected", `C:\a "b".png`, Options{ExpireTimeout: ExpireDefault}, false)
+
+	assert.Contains(t, got, "<text>&lt;b&gt;&#34;Deploy&#34;&lt;/b&gt; &amp; co</text>")
+	assert.Contains(t, got, "<text>it&#39;s &lt;/text&gt;&lt;text&gt;injected</text>")
+	assert.Contains(t, got, `src="C:\a &#34;b&#34;.png"`)
+	assert.Equal(t, 2, strings.Count(got, "<text>"))
+}
+
+func TestToastXMLSoundAndDuration(t *testing.T) {
+	silent := ToastXML("T", "", "", Options{ExpireTimeout: ExpireDefault}, false)
+	assert.Contains(t, silent, toastSilent)
+	assert.NotContains(t, silent, "duration")
+
+	alert := ToastXML("T", "", "", Options{ExpireTimeout: 0}, true)
+	assert.Contains(t, alert, toastSound)
+	assert.Contains(t, alert, `<toast duration="long">`)
+}
+
+func TestToastXMLCritical(t *testing.T) {
+	got := ToastXML("T", "", "", Options{Urgency: UrgencyCritical, ExpireTimeout: 0}, true)
+
+	// Reminders need a button to stay on screen
+	assert.Contains(t, got, `<toast scenario="reminder" duration="long">`)
+	assert.Contains(t, got, "</visual><actions>"+toastDismiss+"</actions>")
+
+	withActions := ToastXML("T", "", "", Options{Urgency: UrgencyCritical, ExpireTimeout: ExpireDefault, Actions: []Action{{Key: "retry", Label: "Retry"}}}, true)
+	assert.Contains(t, withActions, `<toast scenario="reminder">`)
+	assert.Contains(t, withActions, `<actions><action content="Retry" arguments="retry"/></actions>`)
+	assert.NotContains(t, withActions, toastDismiss)
+
+	normal := ToastXML("T", "", "", Options{Urgency: UrgencyNormal, ExpireTimeout: 0}, true)
+	assert.NotContains(t, normal, "scenario")
+	assert.NotContains(t, normal, "<actions>")
+}
+
+func TestToastXMLActions(t *testing.T) {
+	var actions []Action
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		actions = append(actions, Action{Key: key, Label: `"` + strings.ToUpper(key) + `"`})
+	}
+
+	got := ToastXML("Title", "", "", Options{ExpireTimeout: ExpireDefault, Actions: actions}, false)
+
+	assert.Contains(t, got, `</visual><actions><action content="&#34;A&#34;" arguments="a"/>`)
+	assert.Contains(t, got, `arguments="e"/></actions><audio`)
+	assert.NotContains(t, got, `arguments="f"`, "toasts show at most five buttons")
+	assert.NotContains(t, ToastXML("Title", "", "", Options{}, false), "<actions>")
+}
+
+func TestToastXMLProgress(t *testing.T) {
+	opts := Options{
+		ExpireTimeout: ExpireDefault,
+		Attribution:   "via CI",
+		Progress:      &Progress{Percent: 42, Label: "Copying", Status: "3/7"},
+	}
+
+	got := ToastXML("Backup", "", "", opts, false)
+	assert.Contains(t, got, `<text placement="attribution">via CI</text>`+toastProgress+"</binding>")
+	assert.NotContains(t, ToastXML("Backup", "", "", Options{}, false), "<progress")
+
+	assert.Equal(t, map[string]string{
+		"progressValue":  "0.42",
+		"progressTitle":  "Copying",
+		"progressStatus": "3/7",
+	}, ToastData(opts))
+	assert.Equal(t, "1", ToastData(Options{Progress: &Progress{Percent: 100}})["progressValue"])
+	assert.Nil(t, ToastData(Options{}))
+}
+
+func TestCheckToastText(t *testing.T) {
+	assert.NoError(t, CheckToastText("CI", "Build", "line 1\r\n\tline 2", Options{}))
+
+	tests := []struct {
+		name    string
+		appName string
+		title   string
+		message string
+		opts    Options
+		errMsg  string
+	}{
+		{"escape in title", "CI", "\x1b[31mFailed", "", Options{}, "title contains control character U+001B"},
+		{"nul in message", "CI", "Build", "a\x00b", Options{}, "message contains control character U+0000"},
+		{"delete in app name", "C\x7fI", "Build", "", Options{}, "app name contains control character U+007F"},
+		{"c1 in line", "CI", "Build", "", Options{Lines: []string{"\u0085"}}, "line contains control character U+0085"},
+		{"backspace in attribution", "CI", "Build", "", Options{Attribution: "\b"}, "attribution contains"},
+		{"bell in action", "CI", "Build", "", Options{Actions: []Action{{Key: "ok", Label: "OK\a"}}}, "action contains"},
+		{"escape in tag", "CI", "Build", "", Options{Tag: "\x1b"}, "tag contains"},
+		{"vertical tab in progress status", "CI", "Build", "", Options{Progress: &Progress{Status: "\v"}}, "progress status contains"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckToastText(tt.appName, tt.title, tt.message, tt.opts)
+			assert.ErrorContains(t, err, tt.errMsg)
+			assert.ErrorIs(t, err, ErrUnsafeText)
+		})
+	}
+}
+
+// toastElements parses toast XML, returning the path of every element, the
+// text of the text elements and the attributes of all elements
+func toastElements(t *testing.T, toast string) ([]string, []string, []xml.Attr) {
+	t.Helper()
+
+	var elements, texts []string
+	var attrs []xml.Attr
+	var path []string
+	var text *strings.Builder
+
+	d := xml.NewDecoder(strings.NewReader(toast))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err, "toast XML must stay well-formed: %s", toast)
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			path = append(path, tok.Name.Local)
+			elements = append(elements, strings.Join(path, "/"))
+			attrs = append(attrs, tok.Attr...)
+			if tok.Name.Local == "text" {
+				text = new(strings.Builder)
+			}
+		case xml.EndElement:
+			path = path[:len(path)-1]
+			if tok.Name.Local == "text" {
+				texts = append(texts, text.String())
+				text = nil
+			}
+		case xml.CharData:
+			require.NotNil(t, text, "text outside of a text element: %q", tok)
+			text.Write(tok)
+		default:
+			t.Fatalf("unexpected token %T in toast XML", tok)
+		}
+	}
+	return elements, texts, attrs
+}
+
+// asXMLText is s as it reads back from XML: bytes that aren't UTF-8 and
+// characters XML can't hold become U+FFFD
+func asXMLText(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		switch {
+		case r == utf8.RuneError,
+			r < 0x20 && r != '\t' && r != '\n' && r != '\r',
+			r == 0xFFFE, r == 0xFFFF:
+			b.WriteRune(utf8.RuneError)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// placeholder stands in for s with content that needs no escaping
+func placeholder(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "x"
+}
+
+// FuzzToastXML checks that no content changes the structure of a toast:
+// it stays well-formed, has the same elements as harmless content of the
+// same shape, and every text reads back as it was given
+func FuzzToastXML(f *testing.F) {
+	f.Add("Build", "Done", "3 passed", "retry", "Retry")
+	f.Add(`<b>"Deploy"</b> & co`, "it's </text><text>injected", "]]><!--", `x" arguments="y`, "&amp;")
+	f.Add("\xff\xfe", "\ufffe\uffff", "\r\n\t", "<action/>", "</actions>")
+	f.Add("", "", "", "", "")
+
+	f.Fuzz(func(t *testing.T, title, message, line, key, label string) {
+		opts := Options{
+			Lines:       []string{line},
+			Attribution: label,
+			Actions:     []Action{{Key: key, Label: label}},
+		}
+		if CheckToastText("", title, message, opts) != nil {
+			return
+		}
+
+		elements, texts, attrs := toastElements(t, ToastXML(title, message, "", opts, false))
+
+		harmless := Options{
+			Lines:       []string{placeholder(line)},
+			Attribution: placeholder(label),
+			Actions:     []Action{{Key: placeholder(key), Label: placeholder(label)}},
+		}
+		want, _, wantAttrs := toastElements(t, ToastXML(placeholder(title), placeholder(message), "", harmless, false))
+		assert.Equal(t, want, elements)
+		require.Len(t, attrs, len(wantAttrs))
+		for i, attr := range attrs {
+			assert.Equal(t, wantAttrs[i].Name, attr.Name)
+			switch attr.Name.Local {
+			case "content":
+				assert.Equal(t, asXMLText(label), attr.Value)
+			case "arguments":
+				assert.Equal(t, asXMLText(key), attr.Value)
+			default:
+				assert.Equal(t, wantAttrs[i].Value, attr.Value)
+			}
+		}
+
+		var expected []string
+		for _, text := range toastTexts(title, message, opts.Lines) {
+			expected = append(expected, asXMLText(text))
+		}
+		if label != "" {
+			expected = append(expected, asXMLText(label))
+		}
+		assert.Equal(t, expected, texts)
+	})
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"wsl-notify-send/internal/exitcode"
+)
+
+// DefaultAction is the action key reported when the notification itself is
+// clicked rather than one of its buttons, as with libnotify
+const DefaultAction = "default"
+
+// waitGrace is how long past its expire time a notification is waited for,
+// so the server gets to report the expiry itself
+const waitGrace = 2 * time.Second
+
+// Waiter is implemented by backends that report how the user responded to
+// a notification. SendAndWait sends it and blocks until the user clicks it
+// or one of its actions, closes it, or it expires. It returns the ID the
+// notification was given and the key of the chosen action; a notification
+// that was closed or expired fails with ErrDismissed or ErrTimeout.
+// CanWait reports whether the backend can do that as it is set up.
+type Waiter interface {
+	SendAndWait(ctx context.Context, title, message string, icon interface{}, opts Options, sound bool) (uint32, string, error)
+	CanWait() bool
+}
+
+// errCannotWait is returned when waiting with a backend that can't report
+// responses
+var errCannotWait = exitcode.Mark(ErrBackendUnavailable, errors.New("cannot wait for a response: the backend doesn't report them, pick one that does with --backend"))
+
+// NotifyAndWait sends a notification like NotifyWithOptions, or like
+// AlertWithOptions when sound is set, and waits for the user to respond. It
+// returns the notification ID and the key of the chosen action. A deadline
+// on ctx times the wait out like the notification expiring. Backends
+// that can't report responses fail with ErrBackendUnavailable before
+// anything is shown, since an empty action would read as an answer.
+func NotifyAndWait(ctx context.Context, title, message, icon, appName string, opts Options, sound bool) (uint32, string, error) {
+	w, ok := defaultBeeper.(Waiter)
+	if !ok || !w.CanWait() {
+		return 0, "", errCannotWait
+	}
+
+	if appName != "" {
+		defaultBeeper.SetAppName(appName)
+	}
+
+	iconData, err := processIcon(icon)
+	if err != nil {
+		return 0, "", exitcode.Mark(ErrIconUnreadable, fmt.Errorf("failed to process icon: %w", err))
+	}
+
+	message, opts, sound = prepare(defaultBeeper, message, opts, sound)
+
+	id, action, err := w.SendAndWait(ctx, title, message, iconData, opts, sound)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = ErrTimeout
+	}
+	if errors.Is(err, ErrDismissed) || errors.Is(err, ErrTimeout) {
+		return id, "", err
+	}
+	if err != nil {
+		return 0, "", exitcode.Mark(ErrBackendFailed, fmt.Errorf("failed to send notification: %w", err))
+	}
+
+	return id, action, nil
+}
+
+// CanWait reports whether the current backend reports responses to
+// notifications, so NotifyAndWait can wait for them
+func CanWait() bool {
+	w, ok := defaultBeeper.(Waiter)
+	return ok && w.CanWait()
+}
+
+// waitTimeout returns how long to wait for a response before giving up, or
+// 0 to wait until the user responds
+func waitTimeout(opts Options) time.Duration {
+	if opts.ExpireTimeout <= 0 {
+		return 0
+	}
+	return time.Duration(opts.ExpireTimeout)*time.Millisecond + waitGrace
+}
+
+// SendAndWait waits through D-Bus. beeep can't report responses, so
+// without D-Bus nothing is shown.
+func (b *DefaultBeeper) SendAndWait(ctx context.Context, title, message string, icon interface{}, opts Options, sound bool) (uint32, string, error) {
+	if b.dbus == nil {
+		return 0, "", errCannotWait
+	}
+	return b.dbus.SendAndWait(ctx, title, message, icon, opts, sound)
+}
+
+// CanWait reports whether notifications go through D-Bus
+func (b *DefaultBeeper) CanWait() bool {
+	return b.dbus != nil
+}
@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"wsl-notify-send/internal/exitcode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyAndWait(t *testing.T) {
+	retry := []Action{{Key: "retry", Label: "Retry"}}
+
+	tests := []struct {
+		name       string
+		response   string
+		wantAction string
+		wantCode   int
+	}{
+		{"action", "action:retry", "retry", exitcode.Success},
+		{"default action", "action:default", DefaultAction, exitcode.Success},
+		{"dismissed", "dismiss", "", exitcode.Dismissed},
+		{"unscripted", "", "", exitcode.Dismissed},
+		{"timeout", "timeout", "", exitcode.Timeout},
+		{"unknown action", "action:deploy", "", exitcode.NotificationFailed},
+		{"invalid response", "yes", "", exitcode.NotificationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeBeeper(tt.response)
+			SetBeeper(fake)
+			t.Cleanup(func() { SetBeeper(NewDefaultBeeper()) })
+
+			_, action, err := NotifyAndWait(context.Background(), "Deploy failed", "Retry?", "", "CI", Options{ExpireTimeout: ExpireDefault, Actions: retry}, false)
+
+			assert.Equal(t, tt.wantCode, exitcode.Of(err))
+			assert.Equal(t, tt.wantAction, action)
+
+			sent := fake.Sent()
+			require.Len(t, sent, 1)
+			assert.Equal(t, "CI", sent[0].AppName)
+			assert.Equal(t, []string{"retry"}, sent[0].Actions)
+			assert.True(t, sent[0].Wait)
+		})
+	}
+}
+
+func TestNotifyAndWait_Urgency(t *testing.T) {
+	fake := NewFakeBeeper("dismiss")
+	SetBeeper(fake)
+	t.Cleanup(func() { SetBeeper(NewDefaultBeeper()) })
+
+	_, _, err := NotifyAndWait(context.Background(), "Title", "Message", "", "", Options{Urgency: UrgencyCritical, Lines: []string{"line"}}, false)
+
+	assert.ErrorIs(t, err, ErrDismissed)
+	sent := fake.Sent()
+	require.Len(t, sent, 1)
+	assert.True(t, sent[0].Sound, "critical notifications are alerts")
+	assert.Equal(t, "Message\nline", sent[0].Message, "the fake backend shows no rich content")
+}
+
+func TestNotifyAndWait_Unsupported(t *testing.T) {
+	mockBeeper := setupMockBeeper(t)
+
+	_, _, err := NotifyAndWait(context.Background(), "Title", "Message", "", "", Options{}, true)
+
+	assert.ErrorIs(t, err, ErrBackendUnavailable)
+	mockBeeper.AssertExpectations(t)
+
+	// beeep shows nothing either, rather than a notification whose answer
+	// can't be told
+	SetBeeper(&DefaultBeeper{})
+	_, _, err = NotifyAndWait(context.Background(), "Title", "Message", "", "", Options{}, false)
+	assert.ErrorIs(t, err, ErrBackendUnavailable)
+}
+
+func TestWaitTimeout(t *testing.T) {
+	assert.Equal(t, time.Duration(0), waitTimeout(Options{ExpireTimeout: ExpireDefault}))
+	assert.Equal(t, time.Duration(0), waitTimeout(Options{ExpireTimeout: 0}))
+	assert.Equal(t, 5*time.Second+waitGrace, waitTimeout(Options{ExpireTimeout: 5000}))
+}
+
+func TestFakeBeeper_Log(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sent.jsonl")
+	b := NewFakeBeeper("")
+	b.logPath = path
+
+	_, err := b.NotifyWithOptions("One", "First", "", Options{Urgency: UrgencyLow})
+	require.NoError(t, err)
+	id, err := b.AlertWithOptions("Two", "Second", "", Options{ReplaceID: 7})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(7), id)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var second FakeNotification
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, FakeNotification{ID: 7, AppName: "wsl-notify-send", Title: "Two", Message: "Second", Sound: true}, second)
+	assert.Contains(t, lines[0], `"urgency":"low"`)
+}
+
+func TestNotifyAndWait_Deadline(t *testing.T) {
+	b := NewPowerShellBeeper()
+	b.run = func(ctx context.Context, _ string) (string, error) {
+		<-ctx.Done()
+		return "", errors.New("signal: killed")
+	}
+	SetBeeper(b)
+	t.Cleanup(func() { SetBeeper(NewDefaultBeeper()) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, _, err := NotifyAndWait(ctx, "Title", "Message", "", "", Options{}, false)
+
+	assert.ErrorIs(t, err, ErrTimeout)
+}
+
+func TestCanWait(t *testing.T) {
+	t.Cleanup(func() { SetBeeper(NewDefaultBeeper()) })
+
+	SetBeeper(NewFakeBeeper(""))
+	assert.True(t, CanWait())
+
+	SetBeeper(&DefaultBeeper{})
+	assert.False(t, CanWait(), "beeep can't report responses")
+
+	SetBeeper(&DefaultBeeper{dbus: NewDBusBeeper(nil)})
+	assert.True(t, CanWait())
+
+	SetBeeper(new(MockBeeper))
+	assert.False(t, CanWait())
+}
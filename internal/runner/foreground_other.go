@@ -0,0 +1,9 @@
+//go:build !unix
+
+package runner
+
+// foreground reports true, since the console sends Ctrl-C to every process
+// attached to it, and it can't be forwarded anyway
+func foreground() bool {
+	return true
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package runner
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// foreground reports whether our process group, which the child shares, is
+// the foreground process group of the controlling terminal
+func foreground() bool {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return false
+	}
+	defer tty.Close()
+
+	pgrp, err := unix.IoctlGetInt(int(tty.Fd()), unix.TIOCGPGRP)
+	if err != nil {
+		return false
+	}
+	return pgrp == unix.Getpgrp()
+}
@@ -0,0 +1,146 @@
+// Package runner runs a child command on behalf of the run subcommand and
+// reports how it went.
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"wsl-notify-send/internal/exitcode"
+)
+
+// Failure kinds returned by this package, check for them with errors.Is
+var (
+	ErrNotFound      = exitcode.NewKind("command not found", exitcode.CommandNotFound)
+	ErrCannotExecute = exitcode.NewKind("command cannot be executed", exitcode.CannotExecute)
+)
+
+// forwardedSignals are passed on to the child while it runs. The terminal
+// sends Ctrl-C and Ctrl-\ to the whole foreground process group, which the
+// child is part of, so terminalSignals are only passed on when we aren't in
+// the foreground, as when killed by another process or run without a
+// terminal.
+var (
+	forwardedSignals = []os.Signal{syscall.SIGTERM, syscall.SIGHUP}
+	terminalSignals  = []os.Signal{os.Interrupt, syscall.SIGQUIT}
+)
+
+// inForeground reports whether the terminal also sends its signals to the
+// child, replaced in tests
+var inForeground = foreground
+
+// Result describes a finished child command
+type Result struct {
+	Args     []string
+	ExitCode int
+	Elapsed  time.Duration
+}
+
+// Success reports whether the command exited with status 0
+func (r Result) Success() bool {
+	return r.ExitCode == 0
+}
+
+// CommandLine returns the command for display
+func (r Result) CommandLine() string {
+	return CommandLine(r.Args)
+}
+
+// Run executes args[0] with the remaining arguments, connected to the given
+// stdio, and waits for it. Signals received meanwhile are forwarded to the
+// child, except for Ctrl-C and Ctrl-\ when the terminal sends them to the
+// child itself. Errors are only returned when the command could not be
+// started; a command that fails is reported through Result.ExitCode.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) (Result, error) {
+	result := Result{Args: args}
+	if len(args) == 0 {
+		return result, errors.New("no command given")
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// Catch signals before starting so none are missed. Terminal signals
+	// are caught even when they aren't forwarded, since the child would
+	// inherit them being ignored.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, forwardedSignals...)
+	defer signal.Stop(sigs)
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, terminalSignals...)
+	defer signal.Stop(interrupts)
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			result.ExitCode = exitcode.CommandNotFound
+			return result, exitcode.Mark(ErrNotFound, fmt.Errorf("cannot run %s: %w", args[0], err))
+		}
+		result.ExitCode = exitcode.CannotExecute
+		return result, exitcode.Mark(ErrCannotExecute, fmt.Errorf("cannot run %s: %w", args[0], err))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				_ = cmd.Process.Signal(sig)
+			case sig := <-interrupts:
+				if !inForeground() {
+					_ = cmd.Process.Signal(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	close(done)
+	result.Elapsed = time.Since(start)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		result.ExitCode = 0
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitCode(exitErr.ProcessState)
+	default:
+		return result, err
+	}
+
+	return result, nil
+}
+
+// exitCode returns the exit status of a process, or 128 plus the signal
+// number when it was killed by a signal, as shells report it
+func exitCode(state *os.ProcessState) int {
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return state.ExitCode()
+}
+
+// CommandLine joins args for display, quoting the ones that contain spaces
+// or shell characters
+func CommandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`|&;<>()*?[]{}~#!") {
+			quoted[i] = strconv.Quote(arg)
+		} else {
+			quoted[i] = arg
+		}
+	}
+	return strings.Join(quoted, " ")
+}
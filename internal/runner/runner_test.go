@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"wsl-notify-send/internal/exitcode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// helperCommand returns a command line that runs TestHelperProcess with args
+func helperCommand(args ...string) []string {
+	return append([]string{os.Args[0], "-test.run=TestHelperProcess", "--"}, args...)
+}
+
+// TestHelperProcess isn't a real test, it's the child process for the
+// other tests: it echoes its arguments and exits with the last one
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	args = args[1:]
+
+	fmt.Fprintln(os.Stdout, "out:", strings.Join(args, " "))
+	fmt.Fprintln(os.Stderr, "err:", strings.Join(args, " "))
+
+	code, _ := strconv.Atoi(args[len(args)-1])
+	os.Exit(code)
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+
+	tests := []struct {
+		name     string
+		exitCode string
+		expected int
+	}{
+		{"success", "0", 0},
+		{"failure", "3", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := helperCommand("hello", tt.exitCode)
+
+			result, err := Run(args, nil, &stdout, &stderr)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.ExitCode)
+			assert.Equal(t, tt.expected == 0, result.Success())
+			assert.Equal(t, args, result.Args)
+			assert.Greater(t, result.Elapsed, time.Duration(0))
+			assert.Equal(t, "out: hello "+tt.exitCode+"\n", stdout.String())
+			assert.Equal(t, "err: hello "+tt.exitCode+"\n", stderr.String())
+		})
+	}
+}
+
+func TestRunNotFound(t *testing.T) {
+	result, err := Run([]string{"wsl-notify-send-no-such-command"}, nil, nil, nil)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, exitcode.CommandNotFound, exitcode.Of(err))
+	assert.Equal(t, exitcode.CommandNotFound, result.ExitCode)
+}
+
+func TestRunNoCommand(t *testing.T) {
+	_, err := Run(nil, nil, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestCommandLine(t *testing.T) {
+	assert.Equal(t, "go test ./...", CommandLine([]string{"go", "test", "./..."}))
+	assert.Equal(t, `git commit -m "fix it"`, CommandLine([]string{"git", "commit", "-m", "fix it"}))
+	assert.Equal(t, `echo ""`, CommandLine([]string{"echo", ""}))
+	assert.Equal(t, `sh -c "exit 1 | true"`, CommandLine([]string{"sh", "-c", "exit 1 | true"}))
+}
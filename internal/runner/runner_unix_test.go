@@ -0,0 +1,101 @@
+//go:build unix
+
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readyWriter closes ready on the first write
+type readyWriter struct {
+	ready chan struct{}
+	once  sync.Once
+}
+
+func (w *readyWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.ready) })
+	return len(p), nil
+}
+
+func TestRunSignals(t *testing.T) {
+	tests := []struct {
+		name       string
+		foreground bool
+		expected   int
+	}{
+		// The terminal sends Ctrl-C to the child itself, so it isn't sent
+		// twice
+		{"foreground", true, 9},
+		// Without a terminal sending it, Ctrl-C is forwarded like the rest
+		{"background", false, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inForeground = func() bool { return tt.foreground }
+			t.Cleanup(func() { inForeground = foreground })
+
+			stdout := &readyWriter{ready: make(chan struct{})}
+			script := `trap 'exit 7' INT; trap 'exit 9' TERM; echo ready; while :; do sleep 0.05; done`
+			results := make(chan Result, 1)
+			go func() {
+				result, err := Run([]string{"sh", "-c", script}, nil, stdout, nil)
+				assert.NoError(t, err)
+				results <- result
+			}()
+
+			select {
+			case <-stdout.ready:
+			case <-time.After(10 * time.Second):
+				t.Fatal("command didn't start")
+			}
+
+			// SIGTERM is only sent while the child still runs, since nothing
+			// catches it afterwards
+			require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGINT))
+			var result Result
+			select {
+			case result = <-results:
+			case <-time.After(200 * time.Millisecond):
+				require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+				select {
+				case result = <-results:
+				case <-time.After(10 * time.Second):
+					t.Fatal("command didn't exit")
+				}
+			}
+			assert.Equal(t, tt.expected, result.ExitCode)
+		})
+	}
+}
+
+func TestForegroundWithoutTerminal(t *testing.T) {
+	setsid, err := exec.LookPath("setsid")
+	if err != nil {
+		t.Skip("setsid not installed")
+	}
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+
+	// setsid leaves the helper without a controlling terminal
+	out, err := exec.Command(setsid, os.Args[0], "-test.run=TestForegroundHelper").CombinedOutput()
+	require.NoError(t, err, string(out))
+	assert.Contains(t, string(out), "foreground: false")
+}
+
+// TestForegroundHelper isn't a real test, it reports foreground() for
+// TestForegroundWithoutTerminal
+func TestForegroundHelper(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	fmt.Printf("foreground: %v\n", foreground())
+}
@@ -0,0 +1,159 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+	"wsl-notify-send/internal/ansi"
+)
+
+// MaxLineLength is the length tail lines are truncated to, in characters
+const MaxLineLength = 120
+
+// maxPartialBytes bounds the unterminated line a writer buffers, so output
+// without newlines can't grow without limit
+const maxPartialBytes = 4096
+
+// Tail keeps the last meaningful lines written to it, with terminal escapes
+// stripped and long lines truncated. Each stream writes through its own
+// Writer so partial lines from stdout and stderr don't mix.
+type Tail struct {
+	mu      sync.Mutex
+	lines   []string
+	next    int
+	full    bool
+	writers []*tailWriter
+}
+
+// NewTail creates a tail that keeps the last n lines
+func NewTail(n int) *Tail {
+	return &Tail{lines: make([]string, n)}
+}
+
+// Writer returns a writer for one output stream
+func (t *Tail) Writer() io.Writer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w := &tailWriter{tail: t}
+	t.writers = append(t.writers, w)
+	return w
+}
+
+// Lines returns the kept lines, oldest first, followed by any unterminated
+// last lines
+func (t *Tail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var lines []string
+	if t.full {
+		lines = append(lines, t.lines[t.next:]...)
+	}
+	lines = append(lines, t.lines[:t.next]...)
+
+	for _, w := range t.writers {
+		if line, ok := cleanLine(string(w.partial)); ok {
+			lines = append(lines, line)
+		}
+	}
+
+	// Unterminated lines can push the total over the limit
+	if len(lines) > len(t.lines) {
+		lines = lines[len(lines)-len(t.lines):]
+	}
+	return lines
+}
+
+// add keeps line if it has any content, dropping the oldest one when full
+func (t *Tail) add(raw string) {
+	line, ok := cleanLine(raw)
+	if !ok || len(t.lines) == 0 {
+		return
+	}
+
+	t.lines[t.next] = line
+	t.next++
+	if t.next == len(t.lines) {
+		t.next = 0
+		t.full = true
+	}
+}
+
+type tailWriter struct {
+	tail    *Tail
+	partial []byte
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.tail.mu.Lock()
+	defer w.tail.mu.Unlock()
+
+	rest := p
+	for len(rest) > 0 {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			w.buffer(rest)
+			break
+		}
+
+		w.buffer(rest[:i])
+		w.tail.add(string(w.partial))
+		w.partial = w.partial[:0]
+		rest = rest[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// buffer appends to the unterminated line up to maxPartialBytes
+func (w *tailWriter) buffer(p []byte) {
+	if room := maxPartialBytes - len(w.partial); room < len(p) {
+		p = p[:max(room, 0)]
+	}
+	w.partial = append(w.partial, p...)
+}
+
+// cleanLine strips escapes and control characters, keeps what a terminal
+// would show after carriage returns and truncates the result. Blank lines
+// are not meaningful.
+func cleanLine(raw string) (string, bool) {
+	line := ansi.Strip(raw)
+
+	// Progress output redraws the line after a carriage return
+	line = strings.TrimRight(line, "\r")
+	if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+
+	line = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case r < ' ' || r == 0x7f:
+			return -1
+		case r == utf8.RuneError:
+			return -1
+		default:
+			return r
+		}
+	}, line)
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", false
+	}
+
+	return truncate(line, MaxLineLength), true
+}
+
+// truncate shortens s to n characters, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
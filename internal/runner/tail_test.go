@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTail(t *testing.T) {
+	tail := NewTail(3)
+	w := tail.Writer()
+
+	for i := 1; i <= 5; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+
+	assert.Equal(t, []string{"line 3", "line 4", "line 5"}, tail.Lines())
+}
+
+func TestTailNotFull(t *testing.T) {
+	tail := NewTail(5)
+	io.WriteString(tail.Writer(), "one\ntwo\n")
+
+	assert.Equal(t, []string{"one", "two"}, tail.Lines())
+	assert.Empty(t, NewTail(5).Lines())
+}
+
+func TestTailSkipsBlankLines(t *testing.T) {
+	tail := NewTail(2)
+	io.WriteString(tail.Writer(), "FAIL\n\n   \n\x1b[0m\n\t\n")
+
+	assert.Equal(t, []string{"FAIL"}, tail.Lines())
+}
+
+func TestTailPartialWrites(t *testing.T) {
+	tail := NewTail(3)
+	w := tail.Writer()
+
+	io.WriteString(w, "hel")
+	io.WriteString(w, "lo\nwor")
+	io.WriteString(w, "ld")
+
+	// The unterminated last line is included
+	assert.Equal(t, []string{"hello", "world"}, tail.Lines())
+
+	io.WriteString(w, "!\n")
+	assert.Equal(t, []string{"hello", "world!"}, tail.Lines())
+}
+
+func TestTailStreamsDontMix(t *testing.T) {
+	tail := NewTail(4)
+	stdout := tail.Writer()
+	stderr := tail.Writer()
+
+	io.WriteString(stdout, "compil")
+	io.WriteString(stderr, "warning: x\n")
+	io.WriteString(stdout, "ing\n")
+
+	assert.Equal(t, []string{"warning: x", "compiling"}, tail.Lines())
+}
+
+func TestTailUnterminatedLinesRespectLimit(t *testing.T) {
+	tail := NewTail(2)
+	stdout := tail.Writer()
+	stderr := tail.Writer()
+
+	io.WriteString(stdout, "a\nb\nc")
+	io.WriteString(stderr, "d")
+
+	assert.Equal(t, []string{"c", "d"}, tail.Lines())
+}
+
+func TestTailBoundedPartialLine(t *testing.T) {
+	tail := NewTail(1)
+	w := tail.Writer().(*tailWriter)
+
+	for i := 0; i < 100; i++ {
+		io.WriteString(w, strings.Repeat("x", 1000))
+	}
+
+	assert.Len(t, w.partial, maxPartialBytes)
+	assert.Len(t, []rune(tail.Lines()[0]), MaxLineLength)
+}
+
+func TestCleanLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+		ok       bool
+	}{
+		{"plain", "ok  \tpkg", "ok   pkg", true},
+		{"colors", "\x1b[31;1mFAIL\x1b[0m: TestX", "FAIL: TestX", true},
+		{"cursor movement", "\x1b[2K\x1b[1Gdone", "done", true},
+		{"osc hyperlink", "\x1b]8;;http://x\x07link\x1b]8;;\x07", "link", true},
+		{"carriage return progress", "10%\r50%\r100%", "100%", true},
+		{"windows line ending", "error\r", "error", true},
+		{"control characters", "bell\a here", "bell here", true},
+		{"blank", "   ", "", false},
+		{"only escapes", "\x1b[0m\x1b[K", "", false},
+		{"long", strings.Repeat("é", 200), strings.Repeat("é", MaxLineLength-1) + "…", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, ok := cleanLine(tt.raw)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, line)
+		})
+	}
+}
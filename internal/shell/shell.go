@@ -0,0 +1,305 @@
+// Package shell generates the shell integration snippets printed by
+// shell-init and decides which finished commands are worth a notification.
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Shells lists the shells a snippet can be generated for
+var Shells = []string{"bash", "zsh", "fish"}
+
+// DefaultIgnore lists the interactive programs that are slow because someone
+// was using them: editors, pagers, REPLs, remote shells and monitors. The
+// interpreters, shells and remote shells in sessions are only ignored when
+// nothing is given for them to run.
+var DefaultIgnore = []string{
+	// Editors
+	"vi", "vim", "nvim", "view", "nano", "pico", "emacs", "micro", "hx", "kak", "code", "ed", "joe",
+	// Pagers and manuals
+	"less", "more", "most", "man", "info",
+	// REPLs and database shells
+	"python", "python3", "ipython", "node", "deno", "irb", "pry", "ghci", "R", "lua", "julia",
+	"psql", "mysql", "sqlite3", "redis-cli", "mongosh",
+	// Shells, terminals and remote sessions
+	"bash", "zsh", "fish", "sh", "ssh", "mosh", "tmux", "screen",
+	// Monitors and followers
+	"top", "htop", "btop", "watch", "tail", "journalctl",
+}
+
+// session is an interpreter or shell that is interactive unless it is
+// given a script or code to run, or a remote shell unless it is given a
+// command
+type session struct {
+	// Options that take an argument, which is skipped with them
+	wrapper
+
+	// run and longRun are the options that run code from the command line,
+	// like bash -c
+	run     string
+	longRun []string
+
+	// hosts counts the arguments that still leave a session, like the
+	// host of ssh
+	hosts int
+}
+
+// sessions are the programs of DefaultIgnore that also run scripts
+var sessions = map[string]session{
+	"python":  {wrapper: wrapper{short: "WX"}, run: "cm"},
+	"python3": {wrapper: wrapper{short: "WX"}, run: "cm"},
+	"node": {
+		wrapper: wrapper{short: "r", long: []string{"import", "require", "title"}},
+		run:     "ep",
+		longRun: []string{"eval", "print"},
+	},
+	"deno":  {},
+	"lua":   {wrapper: wrapper{short: "l"}, run: "e"},
+	"julia": {run: "eE", longRun: []string{"eval", "print"}},
+	"bash":  {wrapper: wrapper{short: "oO", long: []string{"init-file", "rcfile"}}, run: "c"},
+	"sh":    {wrapper: wrapper{short: "oO"}, run: "c"},
+	"zsh":   {wrapper: wrapper{short: "o"}, run: "c"},
+	"fish":  {wrapper: wrapper{short: "C", long: []string{"init-command"}}, run: "c", longRun: []string{"command"}},
+	"ssh":   {wrapper: wrapper{short: "BbcDEeFIiJLlmOopQRSWw"}, hosts: 1},
+	"mosh":  {wrapper: wrapper{long: []string{"bind-server", "family", "port", "predict", "server", "ssh"}}, hosts: 1},
+}
+
+// interactive reports whether args leave the session interactive
+func (s session) interactive(args []string) bool {
+	positional := 0
+	argument := false
+	options := true
+
+	for _, arg := range args {
+		switch {
+		case argument:
+			argument = false
+		case options && arg == "--":
+			options = false
+		case options && strings.HasPrefix(arg, "--"):
+			name, _, _ := strings.Cut(arg[2:], "=")
+			for _, long := range s.longRun {
+				if name == long {
+					return false
+				}
+			}
+			argument = s.takesArgument(arg)
+		case options && strings.HasPrefix(arg, "-") && arg != "-":
+			if strings.ContainsAny(arg[1:], s.run) {
+				return false
+			}
+			argument = s.takesArgument(arg)
+		default:
+			positional++
+		}
+	}
+	return positional <= s.hosts
+}
+
+// wrapper is a program that runs the command named after it
+type wrapper struct {
+	// short and long are the options that take an argument, which is
+	// skipped with them unless given as -uroot or --user=root
+	short string
+	long  []string
+
+	// positional counts the arguments before the command, like the
+	// duration of timeout
+	positional int
+}
+
+// wrappers are skipped to find the program a command line runs
+var wrappers = map[string]wrapper{
+	"sudo": {
+		short: "CDghpRrTtUu",
+		long:  []string{"chdir", "chroot", "close-from", "command-timeout", "group", "host", "other-user", "prompt", "role", "type", "user"},
+	},
+	"doas":    {short: "Cu"},
+	"command": {},
+	"builtin": {},
+	"exec":    {short: "a"},
+	"nohup":   {},
+	"time":    {short: "fo", long: []string{"format", "output"}},
+	"env":     {short: "CSu", long: []string{"chdir", "split-string", "unset"}},
+	"nice":    {short: "n", long: []string{"adjustment"}},
+	"ionice":  {short: "cnp", long: []string{"class", "classdata", "pid"}},
+	"timeout": {short: "ks", long: []string{"kill-after", "signal"}, positional: 1},
+}
+
+// takesArgument reports whether the next word is the argument of option
+func (w wrapper) takesArgument(option string) bool {
+	if strings.HasPrefix(option, "--") {
+		name, _, hasValue := strings.Cut(option[2:], "=")
+		if hasValue {
+			return false
+		}
+		for _, long := range w.long {
+			if name == long {
+				return true
+			}
+		}
+		return false
+	}
+
+	// In -Eu, only the last option can take the next word
+	for i := 1; i < len(option); i++ {
+		if strings.IndexByte(w.short, option[i]) >= 0 {
+			return i == len(option)-1
+		}
+	}
+	return false
+}
+
+// Options configures a snippet
+type Options struct {
+	// Executable is the wsl-notify-send binary the snippet calls back
+	Executable string
+
+	// Threshold is how long a command must run before it is reported
+	Threshold time.Duration
+
+	// Ignore lists programs to never report, on top of DefaultIgnore
+	Ignore []string
+}
+
+// Snippet returns the integration snippet for the named shell
+func Snippet(shell string, opts Options) (string, error) {
+	tmpl, ok := templates[shell]
+	if !ok {
+		return "", fmt.Errorf("unsupported shell: %s (supported: %s)", shell, strings.Join(Shells, ", "))
+	}
+
+	// Shells count whole seconds, round up so short commands stay quiet
+	threshold := int(math.Ceil(opts.Threshold.Seconds()))
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	callback := []string{Quote(opts.Executable), "shell-done"}
+	for _, name := range opts.Ignore {
+		callback = append(callback, "--ignore", Quote(name))
+	}
+
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, struct {
+		Threshold int
+		Callback  string
+	}{threshold, strings.Join(callback, " ")})
+	return buf.String(), err
+}
+
+// Quote single-quotes s for bash, zsh and fish
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Ignored reports whether a command line runs one of the programs in
+// DefaultIgnore or ignore. Environment assignments and wrappers such as sudo
+// are skipped to find the program, and interpreters, shells and ssh are only
+// ignored when they run interactively.
+func Ignored(command string, ignore []string) bool {
+	program, args := split(command)
+	if program == "" {
+		return true
+	}
+
+	for _, name := range ignore {
+		if program == name {
+			return true
+		}
+	}
+	for _, name := range DefaultIgnore {
+		if program == name {
+			s, ok := sessions[name]
+			return !ok || s.interactive(args)
+		}
+	}
+	return false
+}
+
+// Program returns the name of the program a command line runs
+func Program(command string) string {
+	program, _ := split(command)
+	return program
+}
+
+// fields splits a command line into words the way the shell does for
+// quotes and backslashes, without expanding anything
+func fields(command string) []string {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == '\'':
+			word.WriteRune(r)
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// split returns the name of the program a command line runs and the
+// arguments that follow it
+func split(command string) (string, []string) {
+	var current wrapper
+	positional := 0
+	argument := false
+
+	words := fields(command)
+	for i, word := range words {
+		switch {
+		// Skip wrapper options with their arguments, VAR=value
+		// assignments and the wrapper's own arguments
+		case argument:
+			argument = false
+		case strings.HasPrefix(word, "-"):
+			argument = current.takesArgument(word)
+		case strings.Contains(word, "=") && !strings.HasPrefix(word, "="):
+		case positional > 0:
+			positional--
+
+		default:
+			name := filepath.Base(word)
+			w, ok := wrappers[name]
+			if !ok {
+				return name, words[i+1:]
+			}
+			current = w
+			positional = w.positional
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,233 @@
+package shell
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnippet(t *testing.T) {
+	opts := Options{
+		Executable: "/opt/bin/wsl-notify-send",
+		Threshold:  30 * time.Second,
+		Ignore:     []string{"k9s", "it's"},
+	}
+
+	for _, shell := range Shells {
+		t.Run(shell, func(t *testing.T) {
+			snippet, err := Snippet(shell, opts)
+			require.NoError(t, err)
+
+			assert.Contains(t, snippet, "30s or more")
+			assert.Contains(t, snippet, `'/opt/bin/wsl-notify-send' shell-done --ignore 'k9s' --ignore 'it'\''s'`)
+		})
+	}
+}
+
+func TestSnippetSyntax(t *testing.T) {
+	opts := Options{Executable: "wsl-notify-send", Threshold: 10 * time.Second}
+
+	for _, shell := range Shells {
+		t.Run(shell, func(t *testing.T) {
+			path, err := exec.LookPath(shell)
+			if err != nil {
+				t.Skipf("%s not installed", shell)
+			}
+
+			snippet, err := Snippet(shell, opts)
+			require.NoError(t, err)
+
+			// Parse without running
+			out, err := exec.Command(path, "-n", "-c", snippet).CombinedOutput()
+			assert.NoError(t, err, string(out))
+		})
+	}
+}
+
+func TestSnippetThreshold(t *testing.T) {
+	tests := []struct {
+		threshold time.Duration
+		expected  string
+	}{
+		{10 * time.Second, "-ge 10 ]"},
+		{1500 * time.Millisecond, "-ge 2 ]"},
+		{0, "-ge 1 ]"},
+	}
+
+	for _, tt := range tests {
+		snippet, err := Snippet("bash", Options{Executable: "x", Threshold: tt.threshold})
+		require.NoError(t, err)
+		assert.Contains(t, snippet, tt.expected)
+	}
+}
+
+func TestSnippetBashHooks(t *testing.T) {
+	path, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+
+	snippet, err := Snippet("bash", Options{Executable: "wsl-notify-send", Threshold: 10 * time.Second})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		setup    string
+		debug    string
+		expected string
+	}{
+		{
+			name:     "unset",
+			debug:    `trap -- '__wsl_notify_send_preexec' DEBUG`,
+			expected: `declare -- PROMPT_COMMAND="__wsl_notify_send_precmd;__wsl_notify_send_ready=1"`,
+		},
+		{
+			name:     "string",
+			setup:    `trap 'echo mine' DEBUG; PROMPT_COMMAND='history -a'`,
+			debug:    "trap -- 'echo mine\n__wsl_notify_send_preexec' DEBUG",
+			expected: `declare -- PROMPT_COMMAND="__wsl_notify_send_precmd;history -a;__wsl_notify_send_ready=1"`,
+		},
+		{
+			name:     "array",
+			setup:    `PROMPT_COMMAND=('history -a' mine)`,
+			debug:    `trap -- '__wsl_notify_send_preexec' DEBUG`,
+			expected: `declare -a PROMPT_COMMAND=([0]="__wsl_notify_send_precmd" [1]="history -a" [2]="mine" [3]="__wsl_notify_send_ready=1")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := tt.setup + "\n" + snippet + "\ntrap -p DEBUG\ndeclare -p PROMPT_COMMAND\n"
+			out, err := exec.Command(path, "--norc", "-c", script).CombinedOutput()
+			require.NoError(t, err, string(out))
+
+			assert.Contains(t, string(out), tt.debug)
+			assert.Contains(t, string(out), tt.expected)
+		})
+	}
+}
+
+func TestSnippetUnsupportedShell(t *testing.T) {
+	_, err := Snippet("tcsh", Options{})
+	assert.EqualError(t, err, "unsupported shell: tcsh (supported: bash, zsh, fish)")
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, `'plain'`, Quote("plain"))
+	assert.Equal(t, `'with space'`, Quote("with space"))
+	assert.Equal(t, `'it'\''s'`, Quote("it's"))
+	assert.Equal(t, `'$HOME'`, Quote("$HOME"))
+}
+
+func TestProgram(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"make build", "make"},
+		{"/usr/bin/vim main.go", "vim"},
+		{"GOOS=windows go build", "go"},
+		{"sudo apt upgrade", "apt"},
+		{"time cargo build", "cargo"},
+		{"env FOO=1 less log.txt", "less"},
+		{"sudo -u root vim /etc/hosts", "vim"},
+		{"sudo -Eu root vim", "vim"},
+		{"sudo -uroot vim", "vim"},
+		{"sudo --user root --preserve-env vim", "vim"},
+		{"sudo --user=root vim", "vim"},
+		{"env -u HOME -C /tmp less log.txt", "less"},
+		{"nice -n 10 make", "make"},
+		{"timeout 10 make", "make"},
+		{"timeout -s KILL -k 5 1m tail -f log", "tail"},
+		{"sudo nice -n 5 timeout 1h journalctl -f", "journalctl"},
+		{"sudo -u", ""},
+		{`env "GREETING=hello world" less log.txt`, "less"},
+		{"   ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Program(tt.command))
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		{"go test ./...", []string{"go", "test", "./..."}},
+		{"  git   commit\t-m 'fix it' ", []string{"git", "commit", "-m", "fix it"}},
+		{`echo "a 'b'" it\'s`, []string{"echo", "a 'b'", "it's"}},
+		{`printf '' x`, []string{"printf", "", "x"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, fields(tt.command), tt.command)
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	assert.True(t, Ignored("vim README.md", nil))
+	assert.True(t, Ignored("sudo nano /etc/hosts", nil))
+	assert.True(t, Ignored("sudo -u root vim /etc/hosts", nil))
+	assert.True(t, Ignored("man bash", nil))
+	assert.True(t, Ignored("", nil))
+
+	assert.False(t, Ignored("go test ./...", nil))
+	assert.False(t, Ignored("k9s", nil))
+	assert.True(t, Ignored("k9s --context prod", []string{"k9s"}))
+	assert.True(t, Ignored("python train.py", []string{"python"}))
+}
+
+func TestIgnoredSessions(t *testing.T) {
+	tests := []struct {
+		command string
+		ignored bool
+	}{
+		// REPLs, shells and remote sessions
+		{"python", true},
+		{"python3 -i", true},
+		{"python3 -W ignore", true},
+		{"node", true},
+		{"node --require ts-node/register", true},
+		{"deno", true},
+		{"bash", true},
+		{"bash -l", true},
+		{"zsh -o vi", true},
+		{"fish --init-command 'set x 1'", true},
+		{"sudo -i bash", true},
+		{"ssh build-box", true},
+		{"ssh -p 2222 -i ~/.ssh/key user@build-box", true},
+		{"mosh --ssh=\"ssh -p 2222\" build-box", true},
+
+		// Scripts, code and remote commands
+		{"python train.py", false},
+		{"python3 -m pytest", false},
+		{"python -c 'import time; time.sleep(60)'", false},
+		{"python3 -W ignore train.py --epochs 10", false},
+		{"node build.js", false},
+		{"node -e 'setTimeout(() => {}, 60000)'", false},
+		{"node --eval=1", false},
+		{"deno run main.ts", false},
+		{"bash ./deploy.sh", false},
+		{"bash -ec 'make all'", false},
+		{"sh -- deploy.sh", false},
+		{"fish --command 'make'", false},
+		{"sudo bash ./deploy.sh", false},
+		{"ssh host make", false},
+		{"ssh -p 2222 host make test", false},
+		{"mosh host -- make", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			assert.Equal(t, tt.ignored, Ignored(tt.command, nil))
+		})
+	}
+}
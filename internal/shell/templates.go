@@ -0,0 +1,84 @@
+package shell
+
+import "text/template"
+
+// The snippets time each interactive command in the shell itself and only
+// call back into wsl-notify-send, in the background, for slow ones
+
+const bashSnippet = `# wsl-notify-send: notify when an interactive command takes {{.Threshold}}s or more
+__wsl_notify_send_ready=1
+__wsl_notify_send_preexec() {
+    # Record only the first command run from each prompt
+    [ -n "$COMP_LINE" ] && return
+    [ "$__wsl_notify_send_ready" = 1 ] || return
+    __wsl_notify_send_ready=0
+    __wsl_notify_send_cmd=$(HISTTIMEFORMAT= history 1 | sed 's/^ *[0-9]* *//')
+    __wsl_notify_send_start=$SECONDS
+}
+__wsl_notify_send_precmd() {
+    local exit_status=$?
+    if [ -n "$__wsl_notify_send_cmd" ]; then
+        local elapsed=$((SECONDS - __wsl_notify_send_start))
+        if [ "$elapsed" -ge {{.Threshold}} ]; then
+            ({{.Callback}} --status "$exit_status" --duration "${elapsed}s" -- "$__wsl_notify_send_cmd" >/dev/null 2>&1 &)
+        fi
+    fi
+    __wsl_notify_send_cmd=
+    return $exit_status
+}
+# Run after any DEBUG trap already set, like bash-preexec's
+__wsl_notify_send_trap() { __wsl_notify_send_debug=$3; }
+eval "__wsl_notify_send_trap $(trap -p DEBUG)"
+unset -f __wsl_notify_send_trap
+case $__wsl_notify_send_debug in
+    *__wsl_notify_send_preexec*) ;;
+    '') trap '__wsl_notify_send_preexec' DEBUG ;;
+    *) trap "$__wsl_notify_send_debug"$'\n''__wsl_notify_send_preexec' DEBUG ;;
+esac
+unset __wsl_notify_send_debug
+# The precmd hook goes first so it sees the command's exit status, and bash
+# 5.1 and later can keep PROMPT_COMMAND as an array
+if [[ $(declare -p PROMPT_COMMAND 2>/dev/null) == "declare -a"* ]]; then
+    PROMPT_COMMAND=(__wsl_notify_send_precmd "${PROMPT_COMMAND[@]}" __wsl_notify_send_ready=1)
+else
+    PROMPT_COMMAND="__wsl_notify_send_precmd${PROMPT_COMMAND:+;$PROMPT_COMMAND};__wsl_notify_send_ready=1"
+fi
+`
+
+const zshSnippet = `# wsl-notify-send: notify when an interactive command takes {{.Threshold}}s or more
+autoload -Uz add-zsh-hook
+__wsl_notify_send_preexec() {
+    __wsl_notify_send_cmd=$1
+    __wsl_notify_send_start=$SECONDS
+}
+__wsl_notify_send_precmd() {
+    local exit_status=$?
+    if [[ -n $__wsl_notify_send_cmd ]]; then
+        local elapsed=$(( SECONDS - __wsl_notify_send_start ))
+        if (( elapsed >= {{.Threshold}} )); then
+            {{.Callback}} --status "$exit_status" --duration "${elapsed}s" -- "$__wsl_notify_send_cmd" >/dev/null 2>&1 &!
+        fi
+    fi
+    __wsl_notify_send_cmd=
+}
+add-zsh-hook preexec __wsl_notify_send_preexec
+add-zsh-hook precmd __wsl_notify_send_precmd
+`
+
+const fishSnippet = `# wsl-notify-send: notify when an interactive command takes {{.Threshold}}s or more
+function __wsl_notify_send_postexec --on-event fish_postexec
+    set -l exit_status $status
+    test -n "$argv[1]"; or return
+    set -l elapsed (math --scale=0 "$CMD_DURATION / 1000")
+    if test $elapsed -ge {{.Threshold}}
+        {{.Callback}} --status $exit_status --duration {$elapsed}s -- $argv[1] >/dev/null 2>&1 &
+        disown 2>/dev/null
+    end
+end
+`
+
+var templates = map[string]*template.Template{
+	"bash": template.Must(template.New("bash").Parse(bashSnippet)),
+	"zsh":  template.Must(template.New("zsh").Parse(zshSnippet)),
+	"fish": template.Must(template.New("fish").Parse(fishSnippet)),
+}
@@ -0,0 +1,221 @@
+// Package tmpl renders notification text from Go templates, with a data
+// context describing where and when the notification is sent.
+package tmpl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/humanize"
+)
+
+// ErrTemplate is returned for templates that can't be parsed or rendered
+var ErrTemplate = exitcode.NewKind("template error", exitcode.TemplateError)
+
+// Funcs are available in every template
+var Funcs = template.FuncMap{
+	"truncate": truncate,
+	"upper":    func(v interface{}) string { return strings.ToUpper(text(v)) },
+	"lower":    func(v interface{}) string { return strings.ToLower(text(v)) },
+	"duration": duration,
+	"default":  defaultValue,
+	"base":     filepath.Base,
+}
+
+// Data is what title and message templates are executed with
+type Data struct {
+	// Title and Message are the notification's own title and message
+	Title   string
+	Message string
+
+	Env       map[string]string
+	Hostname  string
+	Cwd       string
+	GitBranch string
+	Time      time.Time
+
+	// Input holds the fields of the JSON object read with --json or --jsonl
+	Input map[string]interface{}
+}
+
+// NewData describes the current environment for a notification with the
+// given title, message and JSON input fields
+func NewData(title, message string, input map[string]interface{}) Data {
+	data := Data{
+		Title:   title,
+		Message: message,
+		Env:     make(map[string]string),
+		Time:    time.Now(),
+		Input:   input,
+	}
+
+	for _, kv := range os.Environ() {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			data.Env[name] = value
+		}
+	}
+
+	data.Hostname, _ = os.Hostname()
+	if cwd, err := os.Getwd(); err == nil {
+		data.Cwd = cwd
+		data.GitBranch = GitBranch(cwd)
+	}
+
+	return data
+}
+
+// Parse parses a template with Funcs. Missing map keys render as the zero
+// value, so an unset environment variable is "". Its errors are ErrTemplate.
+func Parse(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(Funcs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, exitcode.Mark(ErrTemplate, fmt.Errorf("invalid %s template: %w", name, err))
+	}
+	return t, nil
+}
+
+// Execute renders a parsed template. Its errors are ErrTemplate.
+func Execute(t *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", exitcode.Mark(ErrTemplate, fmt.Errorf("cannot render %s template: %w", t.Name(), err))
+	}
+	return buf.String(), nil
+}
+
+// Render parses and renders a template
+func Render(name, text string, data interface{}) (string, error) {
+	t, err := Parse(name, text)
+	if err != nil {
+		return "", err
+	}
+	return Execute(t, data)
+}
+
+// GitBranch returns the branch checked out in the repository containing dir,
+// the short commit hash when the HEAD is detached, or "" outside a repository
+func GitBranch(dir string) string {
+	gitDir := findGitDir(dir)
+	if gitDir == "" {
+		return ""
+	}
+
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+
+	ref := strings.TrimSpace(string(head))
+	if branch, ok := strings.CutPrefix(ref, "ref: refs/heads/"); ok {
+		return branch
+	}
+	if len(ref) >= 7 && !strings.HasPrefix(ref, "ref:") {
+		return ref[:7]
+	}
+	return ""
+}
+
+// findGitDir walks up from dir to the nearest .git directory, following the
+// .git files of worktrees and submodules
+func findGitDir(dir string) string {
+	for {
+		path := filepath.Join(dir, ".git")
+		if info, err := os.Stat(path); err == nil {
+			if info.IsDir() {
+				return path
+			}
+			if data, err := os.ReadFile(path); err == nil {
+				if gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: "); ok {
+					if !filepath.IsAbs(gitDir) {
+						gitDir = filepath.Join(dir, gitDir)
+					}
+					return gitDir
+				}
+			}
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// text formats a template value, with missing values as ""
+func text(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// truncate shortens a value to n characters, marking the cut with an ellipsis
+func truncate(n int, v interface{}) string {
+	runes := []rune(text(v))
+	if len(runes) <= n || n < 1 {
+		return string(runes)
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// duration formats a duration given as a time.Duration, a number of seconds
+// or a string like "1m30s"
+func duration(v interface{}) (string, error) {
+	var d time.Duration
+	switch v := v.(type) {
+	case time.Duration:
+		d = v
+	case int:
+		d = time.Duration(v) * time.Second
+	case int64:
+		d = time.Duration(v) * time.Second
+	case float64:
+		d = time.Duration(v * float64(time.Second))
+	case json.Number:
+		seconds, err := v.Float64()
+		if err != nil {
+			return "", fmt.Errorf("invalid duration: %s", v)
+		}
+		d = time.Duration(seconds * float64(time.Second))
+	case string:
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			d = time.Duration(seconds * float64(time.Second))
+			break
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid duration: %s", v)
+		}
+		d = parsed
+	default:
+		return "", fmt.Errorf("invalid duration: %v", v)
+	}
+	return humanize.Duration(d), nil
+}
+
+// defaultValue returns value, or def when value is missing or empty. Zero
+// numbers and false are values, not empty.
+func defaultValue(def, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		if rv.Len() == 0 {
+			return def
+		}
+	}
+	return value
+}
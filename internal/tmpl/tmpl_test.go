@@ -0,0 +1,124 @@
+package tmpl
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"wsl-notify-send/internal/exitcode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	data := Data{
+		Title:     "Build",
+		Message:   "Done",
+		Env:       map[string]string{"WSL_DISTRO_NAME": "Ubuntu"},
+		Hostname:  "devbox",
+		GitBranch: "main",
+		Time:      time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC),
+		Input:     map[string]interface{}{"service": "api", "took": json.Number("93.5"), "failed": false},
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"env", "{{.Env.WSL_DISTRO_NAME}}: {{.Hostname}}", "Ubuntu: devbox"},
+		{"missing env", "[{{.Env.NO_SUCH_VAR}}]", "[]"},
+		{"title and branch", "{{.Title}} on {{.GitBranch}}", "Build on main"},
+		{"time", `{{.Time.Format "15:04"}}`, "15:04"},
+		{"input", "{{.Input.service}} took {{duration .Input.took}}", "api took 1m34s"},
+		{"upper and lower", "{{upper .Input.service}} {{lower .Title}}", "API build"},
+		{"truncate", "{{truncate 4 .Message}} {{truncate 3 .Input.service}}", "Done api"},
+		{"truncate long", `{{truncate 5 "deployment"}}`, "depl…"},
+		{"default missing", `{{default "unknown" .Input.region}}`, "unknown"},
+		{"default empty", `{{default "none" .Env.NO_SUCH_VAR}}`, "none"},
+		{"default keeps false", `{{default true .Input.failed}}`, "false"},
+		{"duration string", `{{duration "90s"}} {{duration 2}} {{duration "0.25"}}`, "1m30s 2s 250ms"},
+		{"base", `{{base "/src/api/main.go"}}`, "main.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render("title", tt.text, data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		errorMsg string
+	}{
+		{"parse", "{{.Title", "invalid message template"},
+		{"unknown function", "{{shout .Title}}", "invalid message template"},
+		{"unknown field", "{{.NoSuchField}}", "cannot render message template"},
+		{"bad duration", `{{duration "soon"}}`, "invalid duration: soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Render("message", tt.text, Data{})
+			assert.ErrorContains(t, err, tt.errorMsg)
+			assert.ErrorIs(t, err, ErrTemplate)
+			assert.Equal(t, exitcode.TemplateError, exitcode.Of(err))
+		})
+	}
+}
+
+func TestNewData(t *testing.T) {
+	t.Setenv("WSL_DISTRO_NAME", "Debian")
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	data := NewData("Title", "Message", nil)
+
+	assert.Equal(t, "Title", data.Title)
+	assert.Equal(t, "Message", data.Message)
+	assert.Equal(t, "Debian", data.Env["WSL_DISTRO_NAME"])
+	assert.NotEmpty(t, data.Hostname)
+	assert.Equal(t, dir, data.Cwd)
+	assert.WithinDuration(t, time.Now(), data.Time, time.Minute)
+}
+
+func writeGitFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestGitBranch(t *testing.T) {
+	repo := t.TempDir()
+	writeGitFile(t, filepath.Join(repo, ".git", "HEAD"), "ref: refs/heads/feature/templates\n")
+	sub := filepath.Join(repo, "cmd", "tool")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+
+	assert.Equal(t, "feature/templates", GitBranch(repo))
+	assert.Equal(t, "feature/templates", GitBranch(sub))
+}
+
+func TestGitBranchDetached(t *testing.T) {
+	repo := t.TempDir()
+	writeGitFile(t, filepath.Join(repo, ".git", "HEAD"), "5dc2b76a1e0f3c9d8b7a6e5f4d3c2b1a09f8e7d6\n")
+
+	assert.Equal(t, "5dc2b76", GitBranch(repo))
+}
+
+func TestGitBranchWorktree(t *testing.T) {
+	root := t.TempDir()
+	writeGitFile(t, filepath.Join(root, "repo", ".git", "worktrees", "wt", "HEAD"), "ref: refs/heads/wip\n")
+	writeGitFile(t, filepath.Join(root, "wt", ".git"), "gitdir: ../repo/.git/worktrees/wt\n")
+
+	assert.Equal(t, "wip", GitBranch(filepath.Join(root, "wt")))
+}
+
+func TestGitBranchOutsideRepository(t *testing.T) {
+	assert.Equal(t, "", GitBranch(t.TempDir()))
+}
@@ -1,18 +1,21 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"strings"
 	"wsl-notify-send/cmd"
+	"wsl-notify-send/internal/exitcode"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
 		exitCode := getExitCode(err)
 
-		// Only print error if not in quiet mode
-		if !cmd.IsQuietMode() {
+		// Only print error if not in quiet mode, and not for a bare exit
+		// status that has already been reported
+		var status exitcode.Status
+		if !cmd.IsQuietMode() && !errors.As(err, &status) {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		}
 
@@ -20,20 +23,8 @@ func main() {
 	}
 }
 
+// getExitCode maps an error to the exit code of the kind it was marked with,
+// or to the exit status it carries
 func getExitCode(err error) int {
-	if err == nil {
-		return 0
-	}
-
-	errStr := err.Error()
-
-	// Check for different error types
-	switch {
-	case strings.Contains(errStr, "invalid configuration") || strings.Contains(errStr, "too many arguments") || strings.Contains(errStr, "requires at least"):
-		return 2 // Invalid arguments
-	case strings.Contains(errStr, "failed to send") || strings.Contains(errStr, "failed to beep"):
-		return 3 // Notification failed
-	default:
-		return 1 // General error
-	}
+	return exitcode.Of(err)
 }
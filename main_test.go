@@ -2,7 +2,12 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"testing"
+	"wsl-notify-send/internal/config"
+	"wsl-notify-send/internal/exitcode"
+	"wsl-notify-send/internal/notify"
+	"wsl-notify-send/internal/tmpl"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -20,28 +25,48 @@ func TestGetExitCode(t *testing.T) {
 		},
 		{
 			name:       "invalid configuration error",
-			err:        errors.New("invalid configuration: frequency must be positive"),
+			err:        exitcode.Mark(config.ErrInvalidConfig, errors.New("invalid configuration: frequency must be positive")),
 			expectCode: 2,
 		},
 		{
-			name:       "too many arguments error",
-			err:        errors.New("too many arguments, expected: <title> [message]"),
+			name:       "invalid arguments error",
+			err:        exitcode.Mark(config.ErrInvalidArgs, errors.New("requires at least a title argument")),
 			expectCode: 2,
 		},
 		{
-			name:       "requires at least error",
-			err:        errors.New("requires at least a title argument"),
-			expectCode: 2,
+			name:       "backend failed",
+			err:        exitcode.Mark(notify.ErrBackendFailed, errors.New("failed to send notification: something went wrong")),
+			expectCode: 3,
 		},
 		{
-			name:       "failed to send notification",
-			err:        errors.New("failed to send notification: something went wrong"),
-			expectCode: 3,
+			name:       "icon unreadable",
+			err:        exitcode.Mark(notify.ErrIconUnreadable, errors.New("failed to process icon: permission denied")),
+			expectCode: 4,
 		},
 		{
-			name:       "failed to beep",
-			err:        errors.New("failed to beep: beep error"),
-			expectCode: 3,
+			name:       "backend unavailable",
+			err:        exitcode.Mark(notify.ErrBackendUnavailable, errors.New("backend dbus is not available: no session bus")),
+			expectCode: 5,
+		},
+		{
+			name:       "timeout",
+			err:        notify.ErrTimeout,
+			expectCode: 6,
+		},
+		{
+			name:       "dismissed",
+			err:        notify.ErrDismissed,
+			expectCode: 7,
+		},
+		{
+			name:       "template error",
+			err:        exitcode.Mark(tmpl.ErrTemplate, errors.New("cannot render title template: bad")),
+			expectCode: 8,
+		},
+		{
+			name:       "child exit status",
+			err:        exitcode.Status(42),
+			expectCode: 42,
 		},
 		{
 			name:       "general error",
@@ -63,43 +88,28 @@ func TestGetExitCode(t *testing.T) {
 	}
 }
 
-func TestGetExitCodeEdgeCases(t *testing.T) {
-	// Test that error message matching is case-sensitive and exact
+func TestGetExitCodeIgnoresMessageText(t *testing.T) {
+	// Rewording an error must never change its exit code
 	tests := []struct {
 		name       string
 		err        error
 		expectCode int
 	}{
 		{
-			name:       "invalid configuration in middle",
-			err:        errors.New("something invalid configuration something"),
-			expectCode: 2,
+			name:       "config message without kind",
+			err:        errors.New("invalid configuration: frequency must be positive"),
+			expectCode: 1,
 		},
 		{
-			name:       "failed to send in middle",
-			err:        errors.New("error failed to send more text"),
-			expectCode: 3,
+			name:       "send message without kind",
+			err:        errors.New("failed to send notification: something went wrong"),
+			expectCode: 1,
 		},
 		{
-			name:       "failed to beep in middle",
-			err:        errors.New("error failed to beep more text"),
+			name:       "reworded backend failure",
+			err:        exitcode.Mark(notify.ErrBackendFailed, errors.New("toast could not be shown")),
 			expectCode: 3,
 		},
-		{
-			name:       "requires at least in middle",
-			err:        errors.New("error requires at least more text"),
-			expectCode: 2,
-		},
-		{
-			name:       "too many arguments in middle",
-			err:        errors.New("error too many arguments more text"),
-			expectCode: 2,
-		},
-		{
-			name:       "case sensitivity test",
-			err:        errors.New("Invalid Configuration"),
-			expectCode: 1, // Should not match due to case sensitivity
-		},
 	}
 
 	for _, tt := range tests {
@@ -110,31 +120,19 @@ func TestGetExitCodeEdgeCases(t *testing.T) {
 	}
 }
 
-func TestGetExitCodeMultipleMatches(t *testing.T) {
-	// Test that the first matching case is used
-	tests := []struct {
-		name       string
-		err        error
-		expectCode int
-	}{
-		{
-			name:       "invalid configuration and failed to send",
-			err:        errors.New("invalid configuration: failed to send notification"),
-			expectCode: 2, // Should match first case (invalid configuration)
-		},
-		{
-			name:       "too many arguments and failed to beep",
-			err:        errors.New("too many arguments, also failed to beep"),
-			expectCode: 2, // Should match first case (too many arguments)
-		},
-	}
+func TestGetExitCodeWrapped(t *testing.T) {
+	// Kinds survive further wrapping
+	err := fmt.Errorf("run failed: %w", exitcode.Mark(notify.ErrBackendFailed, errors.New("failed to beep: beep error")))
+	assert.Equal(t, 3, getExitCode(err))
+}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			code := getExitCode(tt.err)
-			assert.Equal(t, tt.expectCode, code)
-		})
-	}
+func TestGetExitCodeMostSpecificKind(t *testing.T) {
+	// An icon error reported as invalid configuration keeps the icon code
+	iconErr := exitcode.Mark(notify.ErrIconUnreadable, errors.New("icon file does not exist: test.png"))
+	err := exitcode.Mark(config.ErrInvalidConfig, fmt.Errorf("invalid configuration: %w", iconErr))
+
+	assert.Equal(t, 4, getExitCode(err))
+	assert.ErrorIs(t, err, notify.ErrIconUnreadable)
 }
 
 func TestGetExitCodeEmptyError(t *testing.T) {
@@ -144,98 +142,34 @@ func TestGetExitCodeEmptyError(t *testing.T) {
 	assert.Equal(t, 1, code) // Should default to general error
 }
 
-func TestGetExitCodeSpecificErrorMessages(t *testing.T) {
-	// Test specific error messages that might be returned by the application
+func TestGetExitCodeFromValidation(t *testing.T) {
+	// Test errors as actually returned by config validation
 	tests := []struct {
 		name       string
-		errorMsg   string
+		config     config.Config
 		expectCode int
 	}{
 		{
-			name:       "config validation error",
-			errorMsg:   "invalid configuration: cannot use both --alert and --beep modes",
-			expectCode: 2,
-		},
-		{
-			name:       "icon file error",
-			errorMsg:   "invalid configuration: icon file does not exist: test.png",
-			expectCode: 2,
-		},
-		{
-			name:       "frequency validation error",
-			errorMsg:   "invalid configuration: frequency must be positive",
-			expectCode: 2,
-		},
-		{
-			name:       "duration validation error",
-			errorMsg:   "invalid configuration: duration must be positive",
+			name:       "both modes",
+			config:     config.Config{AlertMode: true, BeepMode: true, Frequency: 587, Duration: 500},
 			expectCode: 2,
 		},
 		{
-			name:       "beeep notify error",
-			errorMsg:   "failed to send notification: notification system error",
-			expectCode: 3,
-		},
-		{
-			name:       "beeep alert error",
-			errorMsg:   "failed to send alert: alert system error",
-			expectCode: 3,
-		},
-		{
-			name:       "beeep beep error",
-			errorMsg:   "failed to beep: beep system error",
-			expectCode: 3,
-		},
-		{
-			name:       "argument parsing error",
-			errorMsg:   "requires at least a title argument",
+			name:       "invalid frequency",
+			config:     config.Config{Frequency: -1, Duration: 500},
 			expectCode: 2,
 		},
 		{
-			name:       "too many args error",
-			errorMsg:   "too many arguments, expected: <title> [message]",
-			expectCode: 2,
+			name:       "missing icon file",
+			config:     config.Config{Icon: "/nonexistent/icon.png", Frequency: 587, Duration: 500},
+			expectCode: 4,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := errors.New(tt.errorMsg)
-			code := getExitCode(err)
-			assert.Equal(t, tt.expectCode, code)
-		})
-	}
-}
-
-// Test the behavior of the main function error handling patterns
-func TestMainErrorHandling(t *testing.T) {
-	// These tests verify the error handling logic without actually calling main()
-	// since main() calls os.Exit() which would terminate the test
-
-	// Test that we properly categorize different error types
-	errorTypes := map[string]int{
-		"invalid configuration errors": 2,
-		"argument parsing errors":      2,
-		"notification system errors":   3,
-		"general errors":               1,
-	}
-
-	for errorType, expectedCode := range errorTypes {
-		t.Run(errorType, func(t *testing.T) {
-			var testErr error
-			switch errorType {
-			case "invalid configuration errors":
-				testErr = errors.New("invalid configuration: test error")
-			case "argument parsing errors":
-				testErr = errors.New("requires at least a title argument")
-			case "notification system errors":
-				testErr = errors.New("failed to send notification: test error")
-			case "general errors":
-				testErr = errors.New("some general error")
-			}
-
-			code := getExitCode(testErr)
-			assert.Equal(t, expectedCode, code)
+			err := tt.config.Validate()
+			assert.Equal(t, tt.expectCode, getExitCode(fmt.Errorf("invalid configuration: %w", err)))
 		})
 	}
 }
@@ -244,11 +178,10 @@ func TestMainErrorHandling(t *testing.T) {
 func BenchmarkGetExitCode(b *testing.B) {
 	testErrors := []error{
 		nil,
-		errors.New("invalid configuration: test"),
-		errors.New("failed to send notification: test"),
-		errors.New("failed to beep: test"),
-		errors.New("requires at least a title argument"),
-		errors.New("too many arguments, expected: <title> [message]"),
+		exitcode.Mark(config.ErrInvalidConfig, errors.New("invalid configuration: test")),
+		exitcode.Mark(notify.ErrBackendFailed, errors.New("failed to send notification: test")),
+		exitcode.Mark(notify.ErrIconUnreadable, errors.New("failed to process icon: test")),
+		exitcode.Mark(config.ErrInvalidArgs, errors.New("requires at least a title argument")),
 		errors.New("general error"),
 	}
 
@@ -258,49 +191,3 @@ func BenchmarkGetExitCode(b *testing.B) {
 		getExitCode(err)
 	}
 }
-
-// Test string comparison behavior
-func TestStringContainsLogic(t *testing.T) {
-	// Test the strings.Contains behavior used in getExitCode
-	tests := []struct {
-		str      string
-		substr   string
-		expected bool
-	}{
-		{"invalid configuration: test", "invalid configuration", true},
-		{"failed to send notification", "failed to send", true},
-		{"failed to beep", "failed to beep", true},
-		{"requires at least a title", "requires at least", true},
-		{"too many arguments", "too many arguments", true},
-		{"Invalid Configuration", "invalid configuration", false}, // case sensitive
-		{"", "test", false},
-		{"test", "", true}, // empty substring always matches
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.str+"_contains_"+tt.substr, func(t *testing.T) {
-			// This tests the actual logic used in getExitCode
-			result := len(tt.str) >= len(tt.substr) &&
-				(tt.str[:len(tt.substr)] == tt.substr ||
-					(len(tt.str) > len(tt.substr) && tt.str[len(tt.str)-len(tt.substr):] == tt.substr) ||
-					stringContains(tt.str, tt.substr))
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
-// Helper function to test string contains logic
-func stringContains(str, substr string) bool {
-	if len(substr) == 0 {
-		return true
-	}
-	if len(str) < len(substr) {
-		return false
-	}
-	for i := 0; i <= len(str)-len(substr); i++ {
-		if str[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
-}
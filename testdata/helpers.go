@@ -112,5 +112,12 @@ func ExpectedExitCodes() map[string]int {
 		"general_error":        1,
 		"invalid_args":         2,
 		"notification_failed":  3,
+		"icon_error":           4,
+		"backend_unavailable":  5,
+		"timeout":              6,
+		"dismissed":            7,
+		"template_error":       8,
+		"cannot_execute":       126,
+		"command_not_found":    127,
 	}
 }
\ No newline at end of file